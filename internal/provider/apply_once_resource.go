@@ -0,0 +1,234 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &ApplyOnceResource{}
+var _ resource.ResourceWithConfigValidators = &ApplyOnceResource{}
+
+func NewApplyOnceResource() resource.Resource {
+	return &ApplyOnceResource{}
+}
+
+// ApplyOnceResource defines the resource implementation.
+type ApplyOnceResource struct {
+	minionConnection
+}
+
+// ApplyOnceResourceModel describes the resource data model.
+type ApplyOnceResourceModel struct {
+	Id                    types.String  `tfsdk:"id"`
+	Server                types.String  `tfsdk:"server"`
+	UyuniSystemName       types.String  `tfsdk:"uyuni_system_name"`
+	Triggers              types.Map     `tfsdk:"triggers"`
+	SyncBeforeApply       types.Bool    `tfsdk:"sync_before_apply"`
+	Saltenv               types.String  `tfsdk:"saltenv"`
+	Pillar                types.Map     `tfsdk:"pillar"`
+	ApplyStateFailureMode types.String  `tfsdk:"apply_state_failure_mode"`
+	StatesChanged         types.Int64   `tfsdk:"states_changed"`
+	StatesFailed          types.Int64   `tfsdk:"states_failed"`
+	DurationSeconds       types.Float64 `tfsdk:"duration_seconds"`
+}
+
+func (r *ApplyOnceResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_apply_once"
+}
+
+func (r *ApplyOnceResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Single highstate of a Salt Minion, run once all grain resources it `depends_on` have converged. Use it instead of `apply_state = true` on every grain of the server.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks"),
+			"triggers": schema.MapAttribute{
+				MarkdownDescription: "Arbitrary values, e.g. the ids or values of the grain resources of the server. The highstate runs again whenever any of them changes.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"sync_before_apply": schema.BoolAttribute{
+				MarkdownDescription: "Run `saltutil.sync_all` before `state.apply` so freshly pushed custom modules and states are used.",
+				Optional:            true,
+			},
+			"saltenv": schema.StringAttribute{
+				MarkdownDescription: "Salt environment of the `state.apply` run. Defaults to the provider's `default_saltenv`.",
+				Optional:            true,
+			},
+			"pillar": schema.MapAttribute{
+				MarkdownDescription: "Pillar data passed to the `state.apply` run as `pillar='{...}'`, overriding the pillar of the minion for this run only.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"apply_state_failure_mode": schema.StringAttribute{
+				MarkdownDescription: "What failed states in the `state.apply` run do: `error` fails the operation, `warn` only reports them as a warning and `ignore` just logs the result, e.g. while a new state is still being worked on. Defaults to `error`.",
+				Optional:            true,
+			},
+			"states_changed": schema.Int64Attribute{
+				MarkdownDescription: "Number of states with changes in the `state.apply` run of the last create or update.",
+				Computed:            true,
+			},
+			"states_failed": schema.Int64Attribute{
+				MarkdownDescription: "Number of failed states in the `state.apply` run of the last create or update.",
+				Computed:            true,
+			},
+			"duration_seconds": schema.Float64Attribute{
+				MarkdownDescription: "Sum of the state durations of the `state.apply` run of the last create or update, in seconds.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *ApplyOnceResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		applyStateFailureModeValidator{},
+	}
+}
+
+func (r *ApplyOnceResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+}
+
+func (r *ApplyOnceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data ApplyOnceResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.highstate(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue(data.Server.ValueString())
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *ApplyOnceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data ApplyOnceResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// a highstate run leaves nothing behind to read back
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *ApplyOnceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data, state ApplyOnceResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Triggers.Equal(state.Triggers) {
+		data.StatesChanged, data.StatesFailed, data.DurationSeconds = state.StatesChanged, state.StatesFailed, state.DurationSeconds
+	} else {
+		r.highstate(ctx, &data, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *ApplyOnceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// the applied states stay on the minion, there is nothing to undo
+}
+
+// highstate runs state.apply on the server of data and records its summary.
+func (r *ApplyOnceResource) highstate(ctx context.Context, data *ApplyOnceResourceModel, diags *diag.Diagnostics) {
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	err := r.waitMinionIsUp(ctx, nil, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		diags.AddError(
+			"failed to wait for the minion to be up",
+			fmt.Sprintf("failed to wait for the minion %s to be up: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	summary, err := r.applyState(ctx, stateApply{
+		Server:          data.Server.ValueString(),
+		SystemName:      uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName),
+		SyncBeforeApply: data.SyncBeforeApply.ValueBool(),
+		Saltenv:         data.Saltenv,
+		Pillar:          data.Pillar,
+		FailureMode:     data.ApplyStateFailureMode,
+	})
+	if err != nil {
+		diags.AddError(
+			"Cannot apply state",
+			err.Error(),
+		)
+		return
+	}
+
+	reportHighstate(ctx, data.Server.ValueString(), data.ApplyStateFailureMode, summary, diags)
+	data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+}
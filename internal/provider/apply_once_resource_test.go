@@ -0,0 +1,48 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"testing"
+)
+
+func TestAccApplyOnceResource(t *testing.T) {
+	testAccFixture(t, "apply_once_resource")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccProviderConfig + testAccApplyOnceResourceConfig("1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("salty_apply_once.test", "id", testAccServer),
+					resource.TestCheckResourceAttr("salty_apply_once.test", "states_changed", "1"),
+					resource.TestCheckResourceAttr("salty_apply_once.test", "states_failed", "0"),
+				),
+			},
+			// Update and Read testing, a new trigger runs the highstate again
+			{
+				Config: testAccProviderConfig + testAccApplyOnceResourceConfig("2"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("salty_apply_once.test", "triggers.revision", "2"),
+					resource.TestCheckResourceAttr("salty_apply_once.test", "states_changed", "0"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func testAccApplyOnceResourceConfig(revision string) string {
+	return `
+resource "salty_apply_once" "test" {
+  server = "` + testAccServer + `"
+  triggers = {
+    revision = "` + revision + `"
+  }
+}
+`
+}
@@ -0,0 +1,109 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Supported values of apply_state_failure_mode.
+const (
+	applyStateFailureError  = "error"
+	applyStateFailureWarn   = "warn"
+	applyStateFailureIgnore = "ignore"
+)
+
+// statesFailed reports whether err is a state.apply run which finished with failed states, as
+// opposed to one which could not run or compile at all.
+func statesFailed(err error) bool {
+	var exitErr interface{ ExitStatus() int }
+	return errors.As(err, &exitErr) && exitErr.ExitStatus() == saltRetcodeStateFailure
+}
+
+// tolerateStatesFailed returns the summary of a state.apply run which finished with failed states
+// when failureMode lets the operation carry on, nil otherwise.
+func tolerateStatesFailed(failureMode types.String, output string, err error) *highstateSummary {
+	if !statesFailed(err) || failureMode.ValueString() == "" || failureMode.ValueString() == applyStateFailureError {
+		return nil
+	}
+
+	summary := parseHighstate(output)
+	if summary == nil {
+		summary = &highstateSummary{}
+	}
+	// the retcode tells the run failed even when its output could not be summarized
+	summary.Failed = max(summary.Failed, 1)
+	return summary
+}
+
+// reportHighstate surfaces the summary of a state.apply run on server as failureMode asks, an
+// error for failed states, a warning or only a log line. It reports whether the operation may
+// carry on.
+func reportHighstate(ctx context.Context, server string, failureMode types.String, summary *highstateSummary, diags *diag.Diagnostics) bool {
+	failed := summary != nil && summary.Failed > 0
+
+	switch failureMode.ValueString() {
+	case applyStateFailureIgnore:
+		tflog.Info(ctx, "state.apply finished", map[string]any{logFieldServer: server, "summary": summary.String()})
+		return true
+	case applyStateFailureWarn:
+		if failed {
+			diags.AddWarning(
+				"apply state failed",
+				fmt.Sprintf("state.apply on Salt Minion %s finished with failed states, continuing as apply_state_failure_mode is %s: %s", server, applyStateFailureWarn, summary),
+			)
+			return true
+		}
+	default:
+		if failed {
+			diags.AddError(
+				"Cannot apply state",
+				fmt.Sprintf("state.apply on Salt Minion %s finished with failed states, %s; see /var/log/state.apply.tf.log on the minion for which states failed and why", server, summary),
+			)
+			return false
+		}
+	}
+
+	diags.AddWarning("apply state result", summary.String())
+	return true
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ resource.ConfigValidator = applyStateFailureModeValidator{}
+
+// applyStateFailureModeValidator checks apply_state_failure_mode at plan time.
+type applyStateFailureModeValidator struct{}
+
+func (v applyStateFailureModeValidator) Description(ctx context.Context) string {
+	return "apply_state_failure_mode must be error, warn or ignore"
+}
+
+func (v applyStateFailureModeValidator) MarkdownDescription(ctx context.Context) string {
+	return "`apply_state_failure_mode` must be `error`, `warn` or `ignore`"
+}
+
+func (v applyStateFailureModeValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var failureMode types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("apply_state_failure_mode"), &failureMode)...)
+	if resp.Diagnostics.HasError() || failureMode.IsNull() || failureMode.IsUnknown() {
+		return
+	}
+
+	switch failureMode.ValueString() {
+	case applyStateFailureError, applyStateFailureWarn, applyStateFailureIgnore:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("apply_state_failure_mode"),
+			"Unsupported apply_state_failure_mode",
+			fmt.Sprintf("apply_state_failure_mode must be %s, %s or %s, got %q", applyStateFailureError, applyStateFailureWarn, applyStateFailureIgnore, failureMode.ValueString()),
+		)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// setStrings returns the elements of a set of strings in the order Terraform holds them,
+// nil when the set is null or unknown.
+func setStrings(values types.Set) []string {
+	var result []string
+	for _, value := range values.Elements() {
+		if str, ok := value.(types.String); ok {
+			result = append(result, str.ValueString())
+		}
+	}
+	return result
+}
+
+// nonNil returns values, or an empty slice so it is encoded as [] rather than null.
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
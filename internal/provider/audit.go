@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditTransport records every command run through next in the audit_log file.
+type auditTransport struct {
+	next Transport
+	path string
+
+	mu sync.Mutex
+}
+
+// auditRecord is a line of the audit log.
+type auditRecord struct {
+	Time    string `json:"time"`
+	Server  string `json:"server"`
+	Command string `json:"command"`
+	// ExitCode is -1 when the command did not finish, e.g. the connection failed or it timed out.
+	ExitCode int    `json:"exit_code"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (t *auditTransport) Run(ctx context.Context, server string, runCommand string, timeout time.Duration) (string, error) {
+	started := time.Now()
+	output, err := t.next.Run(ctx, server, runCommand, timeout)
+
+	record := auditRecord{
+		Time:     started.UTC().Format(time.RFC3339),
+		Server:   server,
+		Command:  runCommand,
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+	}
+	if err != nil {
+		record.ExitCode = -1
+		record.Error = err.Error()
+		var exitErr interface{ ExitStatus() int }
+		if errors.As(err, &exitErr) {
+			record.ExitCode = exitErr.ExitStatus()
+		}
+	}
+
+	// the command ran either way, a lost record must not fail the operation
+	if auditErr := t.write(record); auditErr != nil {
+		tflog.Error(ctx, fmt.Sprintf("cannot record the command in the audit log %s: %s", t.path, auditErr))
+	}
+
+	return output, err
+}
+
+// write appends record to the audit log as a JSON line.
+func (t *auditTransport) write(record auditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	file, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"strings"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &CmdOutputDataSource{}
+
+func NewCmdOutputDataSource() datasource.DataSource {
+	return &CmdOutputDataSource{}
+}
+
+// defaultCmdOutputFunctions are the read-only execution module functions salty_cmd_output may
+// call without being allowed in the provider configuration.
+var defaultCmdOutputFunctions = []string{
+	"disk.usage",
+	"grains.get",
+	"grains.item",
+	"network.interfaces",
+	"network.ip_addrs",
+	"pkg.list_pkgs",
+	"pkg.version",
+	"service.enabled",
+	"service.status",
+	"status.uptime",
+	"test.version",
+}
+
+// CmdOutputDataSource defines the data source implementation.
+type CmdOutputDataSource struct {
+	transport        Transport
+	uyuni            *UyuniClient
+	minionInstalls   *minionInstallCache
+	allowedFunctions []string
+}
+
+// CmdOutputDataSourceModel describes the data source data model.
+type CmdOutputDataSourceModel struct {
+	Id       types.String `tfsdk:"id"`
+	Server   types.String `tfsdk:"server"`
+	Function types.String `tfsdk:"function"`
+	Args     types.List   `tfsdk:"args"`
+	Result   types.String `tfsdk:"result"`
+}
+
+type SaltCmdOutputModel struct {
+	Result json.RawMessage `json:"local"`
+}
+
+func (d *CmdOutputDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cmd_output"
+}
+
+func (d *CmdOutputDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Result of a read-only Salt execution module function run on a Salt Minion",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+			},
+			"function": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("Execution module function to call, one of `%s` or a function listed in the provider's `allowed_functions`.", strings.Join(defaultCmdOutputFunctions, "`, `")),
+				Required:            true,
+			},
+			"args": schema.ListAttribute{
+				MarkdownDescription: "Arguments passed to the function, e.g. `[\"kernelrelease\"]` for `grains.get`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"result": schema.StringAttribute{
+				MarkdownDescription: "Return value of the function as JSON, to be read with `jsondecode`.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *CmdOutputDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.transport = data.Transport
+	d.uyuni = data.Uyuni
+	d.minionInstalls = data.MinionInstalls
+	d.allowedFunctions = append(slices.Clone(defaultCmdOutputFunctions), data.AllowedFunctions...)
+}
+
+func (d *CmdOutputDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data CmdOutputDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	function := data.Function.ValueString()
+	if !slices.Contains(d.allowedFunctions, function) {
+		resp.Diagnostics.AddError(
+			"Function not allowed",
+			fmt.Sprintf("The function %s is not allowed in salty_cmd_output, add it to the provider's allowed_functions if it does not change the minion.", function),
+		)
+		return
+	}
+
+	var args []string
+	resp.Diagnostics.Append(data.Args.ElementsAs(ctx, &args, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.Server.ValueString()
+	minion, err := d.minionInstalls.detect(ctx, d.transport, d.uyuni, server, minionID(server))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", server, err),
+		)
+		return
+	}
+
+	runCommand := minion.Command(function, args...)
+
+	output, err := d.transport.Run(ctx, server, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot run the function",
+			fmt.Sprintf("cannot run %s on the Salt Minion %s: %s", function, server, err),
+		)
+		return
+	}
+
+	cmdOutput := SaltCmdOutputModel{}
+	err = json.Unmarshal(saltJSON(output), &cmdOutput)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot parse the function result",
+			fmt.Sprintf("cannot parse the result of %s on the Salt Minion %s: %s", function, server, err),
+		)
+		return
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("%s on %s returned %s", function, server, cmdOutput.Result))
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", server, function))
+	data.Result = types.StringValue(string(cmdOutput.Result))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,351 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &CronResource{}
+
+func NewCronResource() resource.Resource {
+	return &CronResource{}
+}
+
+// CronResource defines the resource implementation.
+type CronResource struct {
+	minionConnection
+}
+
+// CronResourceModel describes the resource data model.
+type CronResourceModel struct {
+	Id              types.String `tfsdk:"id"`
+	Server          types.String `tfsdk:"server"`
+	UyuniSystemName types.String `tfsdk:"uyuni_system_name"`
+	User            types.String `tfsdk:"user"`
+	Identifier      types.String `tfsdk:"identifier"`
+	Command         types.String `tfsdk:"command"`
+	Minute          types.String `tfsdk:"minute"`
+	Hour            types.String `tfsdk:"hour"`
+	DayMonth        types.String `tfsdk:"daymonth"`
+	Month           types.String `tfsdk:"month"`
+	DayWeek         types.String `tfsdk:"dayweek"`
+	Comment         types.String `tfsdk:"comment"`
+}
+
+// saltCronTab is the cron.list_tab output.
+type saltCronTab struct {
+	Local struct {
+		Crons []saltCronJob `json:"crons"`
+	} `json:"local"`
+}
+
+// saltCronJob is an entry of a crontab as listed by cron.list_tab.
+type saltCronJob struct {
+	Identifier string `json:"identifier"`
+	Cmd        string `json:"cmd"`
+	Minute     string `json:"minute"`
+	Hour       string `json:"hour"`
+	DayMonth   string `json:"daymonth"`
+	Month      string `json:"month"`
+	DayWeek    string `json:"dayweek"`
+	Comment    string `json:"comment"`
+}
+
+func (r *CronResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cron"
+}
+
+func (r *CronResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	schedule := func(field string) schema.StringAttribute {
+		return schema.StringAttribute{
+			MarkdownDescription: fmt.Sprintf("The %s field of the schedule, in crontab syntax. Defaults to `*`.", field),
+			Optional:            true,
+			Computed:            true,
+			Default:             stringdefault.StaticString("*"),
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Cron job of a user on a minion, set with the `cron` execution module. Suits host-specific jobs no formula covers, the job is found again by its `identifier` even when someone edited it.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks"),
+			"user": schema.StringAttribute{
+				MarkdownDescription: "User whose crontab holds the job. Defaults to `root`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("root"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"identifier": schema.StringAttribute{
+				MarkdownDescription: "Unique name of the job in the crontab, kept by Salt in a `# SALT_CRON_IDENTIFIER` comment.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"command": schema.StringAttribute{
+				MarkdownDescription: "Command run by the job.",
+				Required:            true,
+			},
+			"minute":   schedule("minute"),
+			"hour":     schedule("hour"),
+			"daymonth": schedule("day of month"),
+			"month":    schedule("month"),
+			"dayweek":  schedule("day of week"),
+			"comment": schema.StringAttribute{
+				MarkdownDescription: "Comment written above the job.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *CronResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+}
+
+func (r *CronResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data CronResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	err := r.setJob(ctx, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot set the cron job",
+			fmt.Sprintf("cannot set the cron job %s of %s on the Salt Minion %s: %s", data.Identifier.ValueString(), data.User.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s-%s", data.Server.ValueString(), data.User.ValueString(), data.Identifier.ValueString()))
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *CronResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data CronResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	output, err := r.transport.Run(ctx, data.Server.ValueString(), minion.Command("cron.list_tab", data.User.ValueString()), 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the crontab",
+			fmt.Sprintf("cannot read the crontab of %s on the Salt Minion %s: %s", data.User.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	tab := saltCronTab{}
+	if err := json.Unmarshal(saltJSON(output), &tab); err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the crontab",
+			fmt.Sprintf("cannot parse the crontab of %s on the Salt Minion %s: %s", data.User.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	index := slices.IndexFunc(tab.Local.Crons, func(job saltCronJob) bool { return job.Identifier == data.Identifier.ValueString() })
+	if index < 0 {
+		tflog.Info(ctx, fmt.Sprintf("cron job %s of %s is gone, removing it from state", data.Identifier.ValueString(), data.User.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	job := tab.Local.Crons[index]
+	data.Command = types.StringValue(job.Cmd)
+	data.Minute = types.StringValue(job.Minute)
+	data.Hour = types.StringValue(job.Hour)
+	data.DayMonth = types.StringValue(job.DayMonth)
+	data.Month = types.StringValue(job.Month)
+	data.DayWeek = types.StringValue(job.DayWeek)
+	if job.Comment != "" || !data.Comment.IsNull() {
+		data.Comment = types.StringValue(job.Comment)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *CronResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data CronResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// cron.set_job finds the job by its identifier and replaces it
+	err := r.setJob(ctx, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot set the cron job",
+			fmt.Sprintf("cannot set the cron job %s of %s on the Salt Minion %s: %s", data.Identifier.ValueString(), data.User.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *CronResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data CronResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	runCommand := minion.Command("cron.rm_job", saltclient.StringArg(data.User.ValueString()), saltclient.StringArg(data.Command.ValueString()), "identifier="+saltclient.StringArg(data.Identifier.ValueString()))
+	output, err := r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+	if err == nil {
+		err = cronResult(output, "removed", "absent")
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the cron job",
+			fmt.Sprintf("cannot delete the cron job %s of %s on the Salt Minion %s: %s", data.Identifier.ValueString(), data.User.ValueString(), data.Server.ValueString(), err),
+		)
+	}
+}
+
+// setJob adds the cron job of data or replaces the one with the same identifier.
+func (r *CronResource) setJob(ctx context.Context, data CronResourceModel) error {
+	err := r.waitMinionIsUp(ctx, nil, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return fmt.Errorf("failed to wait for the minion to be up: %s", err)
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		saltclient.StringArg(data.User.ValueString()),
+		saltclient.StringArg(data.Minute.ValueString()),
+		saltclient.StringArg(data.Hour.ValueString()),
+		saltclient.StringArg(data.DayMonth.ValueString()),
+		saltclient.StringArg(data.Month.ValueString()),
+		saltclient.StringArg(data.DayWeek.ValueString()),
+		saltclient.StringArg(data.Command.ValueString()),
+		"identifier=" + saltclient.StringArg(data.Identifier.ValueString()),
+	}
+	if !data.Comment.IsNull() {
+		args = append(args, "comment="+saltclient.StringArg(data.Comment.ValueString()))
+	}
+
+	output, err := r.transport.Run(mutating(ctx), data.Server.ValueString(), minion.Command("cron.set_job", args...), 0)
+	if err != nil {
+		return err
+	}
+	return cronResult(output, "new", "updated", "present")
+}
+
+// cronResult returns an error unless the cron function in output returned one of the expected
+// results. The functions report a failure as the error message instead of their result.
+func cronResult(output string, expected ...string) error {
+	var result struct {
+		Local string `json:"local"`
+	}
+	if err := json.Unmarshal(saltJSON(output), &result); err != nil {
+		return fmt.Errorf("cannot parse the cron output: %s", err)
+	}
+	if !slices.Contains(expected, result.Local) {
+		return fmt.Errorf("%s", result.Local)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"sync"
+	"time"
+)
+
+// dryRunTransport runs the read-only commands through next and only records the mutating ones.
+type dryRunTransport struct {
+	next Transport
+}
+
+func (t *dryRunTransport) Run(ctx context.Context, server string, runCommand string, timeout time.Duration) (string, error) {
+	if isMutating(ctx) {
+		tflog.Warn(ctx, "dry run, not running the command", map[string]any{logFieldServer: server, logFieldCommand: runCommand})
+		if skipped, ok := ctx.Value(dryRunSkippedKey{}).(*dryRunSkipped); ok {
+			skipped.add(server, redactedCommand(ctx, runCommand))
+		}
+		return "", nil
+	}
+	return t.next.Run(ctx, server, runCommand, timeout)
+}
+
+type dryRunSkippedKey struct{}
+
+// dryRunSkipped collects the commands a dry run skipped during one resource operation.
+type dryRunSkipped struct {
+	mu       sync.Mutex
+	commands []skippedCommand
+}
+
+type skippedCommand struct {
+	server  string
+	command string
+}
+
+// recordDryRun returns ctx collecting the commands the dry run skips while it is used.
+func recordDryRun(ctx context.Context) (context.Context, *dryRunSkipped) {
+	skipped := &dryRunSkipped{}
+	return context.WithValue(ctx, dryRunSkippedKey{}, skipped), skipped
+}
+
+func (s *dryRunSkipped) add(server string, runCommand string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, skippedCommand{server: server, command: runCommand})
+}
+
+// report adds a warning for each skipped command. As the servers did not change, the operation
+// then fails with state set back to prior, nil for a create, instead of recording the planned
+// values as applied.
+func (s *dryRunSkipped) report(ctx context.Context, diags *diag.Diagnostics, state *tfsdk.State, prior *tfsdk.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.commands) == 0 {
+		return
+	}
+
+	for _, skipped := range s.commands {
+		diags.AddWarning(
+			"Dry run skipped a command",
+			fmt.Sprintf("dry_run is enabled, not running on %s: %s", skipped.server, skipped.command),
+		)
+	}
+	diags.AddError(
+		"Dry run",
+		fmt.Sprintf("dry_run is enabled and %d commands changing the servers were skipped, the state is left as it was. Disable dry_run to apply the changes.", len(s.commands)),
+	)
+
+	if prior == nil {
+		state.RemoveResource(ctx)
+		return
+	}
+	*state = *prior
+}
@@ -0,0 +1,142 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"math/big"
+)
+
+// dynamicToNative converts a Terraform value of any type into the strings, numbers, booleans,
+// slices and maps it is written to the minions as. Integral numbers become int64, so they are
+// not rendered with a fraction.
+func dynamicToNative(value attr.Value) (any, error) {
+	if value == nil || value.IsNull() {
+		return nil, nil
+	}
+	if value.IsUnknown() {
+		return nil, fmt.Errorf("the value is not known yet")
+	}
+
+	switch v := value.(type) {
+	case types.Dynamic:
+		return dynamicToNative(v.UnderlyingValue())
+	case types.String:
+		return v.ValueString(), nil
+	case types.Bool:
+		return v.ValueBool(), nil
+	case types.Int64:
+		return v.ValueInt64(), nil
+	case types.Float64:
+		return v.ValueFloat64(), nil
+	case types.Number:
+		number := v.ValueBigFloat()
+		if integer, accuracy := number.Int64(); accuracy == big.Exact {
+			return integer, nil
+		}
+		float, _ := number.Float64()
+		return float, nil
+	case types.List:
+		return dynamicSliceToNative(v.Elements())
+	case types.Set:
+		return dynamicSliceToNative(v.Elements())
+	case types.Tuple:
+		return dynamicSliceToNative(v.Elements())
+	case types.Map:
+		return dynamicMapToNative(v.Elements())
+	case types.Object:
+		return dynamicMapToNative(v.Attributes())
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", value)
+}
+
+func dynamicSliceToNative(elements []attr.Value) (any, error) {
+	native := make([]any, 0, len(elements))
+	for _, element := range elements {
+		value, err := dynamicToNative(element)
+		if err != nil {
+			return nil, err
+		}
+		native = append(native, value)
+	}
+	return native, nil
+}
+
+func dynamicMapToNative(elements map[string]attr.Value) (any, error) {
+	native := make(map[string]any, len(elements))
+	for key, element := range elements {
+		value, err := dynamicToNative(element)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		native[key] = value
+	}
+	return native, nil
+}
+
+// nativeToDynamic converts JSON decoded with json.Decoder.UseNumber into a Terraform value.
+// Lists become tuples and dictionaries objects, as they are written in HCL.
+func nativeToDynamic(value any) (attr.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return types.StringNull(), nil
+	case string:
+		return types.StringValue(v), nil
+	case bool:
+		return types.BoolValue(v), nil
+	case json.Number:
+		number, _, err := big.ParseFloat(v.String(), 10, 512, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("malformed number %s: %w", v, err)
+		}
+		return types.NumberValue(number), nil
+	case float64:
+		return types.NumberValue(big.NewFloat(v)), nil
+	case []any:
+		elementTypes := make([]attr.Type, 0, len(v))
+		elements := make([]attr.Value, 0, len(v))
+		for _, item := range v {
+			element, err := nativeToDynamic(item)
+			if err != nil {
+				return nil, err
+			}
+			elementTypes = append(elementTypes, element.Type(context.Background()))
+			elements = append(elements, element)
+		}
+		tuple, diags := types.TupleValue(elementTypes, elements)
+		if diags.HasError() {
+			return nil, fmt.Errorf("cannot build a tuple: %v", diags)
+		}
+		return tuple, nil
+	case map[string]any:
+		attributeTypes := make(map[string]attr.Type, len(v))
+		attributes := make(map[string]attr.Value, len(v))
+		for key, item := range v {
+			attribute, err := nativeToDynamic(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			attributeTypes[key] = attribute.Type(context.Background())
+			attributes[key] = attribute
+		}
+		object, diags := types.ObjectValue(attributeTypes, attributes)
+		if diags.HasError() {
+			return nil, fmt.Errorf("cannot build an object: %v", diags)
+		}
+		return object, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", value)
+}
+
+// sameNativeValues reports whether two values hold the same data, ignoring whether numbers are
+// integers or floats and how lists and dictionaries are typed in Terraform.
+func sameNativeValues(a any, b any) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strings"
+	"sync"
+)
+
+// grainItemsCache keeps the grains.items output per server. The provider process only lives for
+// a single Terraform operation, so all Reads of one refresh share a single SSH round trip.
+type grainItemsCache struct {
+	mu      sync.Mutex
+	servers map[string]*grainItems
+}
+
+// grainItems is the result of one grains.items call, fetched at most once.
+type grainItems struct {
+	once  sync.Once
+	items map[string]json.RawMessage
+	err   error
+}
+
+func newGrainItemsCache() *grainItemsCache {
+	return &grainItemsCache{servers: map[string]*grainItems{}}
+}
+
+// get returns the grain in the grains.get output format. Nested keys, split at delimiter, and
+// grains missing from the cached grains.items are read with a targeted grains.get instead.
+func (c *grainItemsCache) get(ctx context.Context, transport Transport, minion saltMinionInstall, server string, grainKey string, delimiter string) (string, error) {
+	if delimiter == "" {
+		delimiter = defaultGrainDelimiter
+	}
+	if !strings.Contains(grainKey, delimiter) {
+		c.mu.Lock()
+		cached, ok := c.servers[server]
+		if !ok {
+			cached = &grainItems{}
+			c.servers[server] = cached
+		}
+		c.mu.Unlock()
+
+		cached.once.Do(func() {
+			runCommand := minion.Command("grains.items")
+			output, err := transport.Run(ctx, server, runCommand, 0)
+			if err != nil {
+				cached.err = err
+				return
+			}
+
+			items := struct {
+				Local map[string]json.RawMessage `json:"local"`
+			}{}
+			cached.err = json.Unmarshal(saltJSON(output), &items)
+			cached.items = items.Local
+		})
+
+		if cached.err == nil {
+			if value, ok := cached.items[grainKey]; ok {
+				return fmt.Sprintf(`{"local": %s}`, value), nil
+			}
+		}
+		tflog.Debug(ctx, fmt.Sprintf("grain %s of %s not in the grains.items cache, reading it directly", grainKey, server))
+	}
+
+	runCommand := minion.Command("grains.get", grainArgs(delimiter, grainKey)...)
+	return transport.Run(ctx, server, runCommand, 0)
+}
+
+// saltMinionID returns the id grain of server, the name the Salt master knows it by.
+func (c *grainItemsCache) saltMinionID(ctx context.Context, transport Transport, minion saltMinionInstall, server string) (string, error) {
+	output, err := c.get(ctx, transport, minion, server, "id", "")
+	if err != nil {
+		return "", err
+	}
+
+	id := SaltGrainStringModel{}
+	if err := json.Unmarshal(saltJSON(output), &id); err != nil {
+		return "", err
+	}
+	if id.Value == "" {
+		return "", fmt.Errorf("the minion reports no id grain")
+	}
+	return id.Value, nil
+}
+
+// invalidate drops the cached grains of server, to be called once its grains were changed.
+func (c *grainItemsCache) invalidate(server string) {
+	c.mu.Lock()
+	delete(c.servers, server)
+	c.mu.Unlock()
+}
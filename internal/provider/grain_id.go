@@ -0,0 +1,60 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"strings"
+)
+
+// grainIDSeparator separates the server from the grain key in the ID of the grain resources.
+// Hostnames and SSH addresses cannot contain it, unlike the dash of the former
+// `server-grain_key` IDs.
+const grainIDSeparator = "|"
+
+// grainID returns the ID of the grain grainKey on server.
+func grainID(server string, grainKey string) string {
+	return server + grainIDSeparator + grainKey
+}
+
+// parseGrainID splits an ID built by grainID. The grain key is everything after the first
+// separator, so it may contain the separator itself.
+func parseGrainID(id string) (string, string, error) {
+	server, grainKey, ok := strings.Cut(id, grainIDSeparator)
+	if !ok || server == "" || grainKey == "" {
+		return "", "", fmt.Errorf("expected an ID of the form server%sgrain_key, got %q", grainIDSeparator, id)
+	}
+	return server, grainKey, nil
+}
+
+// importGrainState sets the ID, server and grain_key of an imported grain resource, the
+// following read fills in the value.
+func importGrainState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	server, grainKey, err := parseGrainID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid import ID",
+			fmt.Sprintf("cannot import the grain: %s", err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), grainID(server, grainKey))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("server"), server)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("grain_key"), grainKey)...)
+}
+
+// structuredGrainIDState rebuilds the `server-grain_key` ID of a state as grainID does.
+func structuredGrainIDState(attributes map[string]any) error {
+	server, serverOk := attributes["server"].(string)
+	grainKey, grainKeyOk := attributes["grain_key"].(string)
+	if !serverOk || !grainKeyOk {
+		return fmt.Errorf("the state holds no server and grain_key to build the ID from")
+	}
+	attributes["id"] = grainID(server, grainKey)
+	return nil
+}
@@ -0,0 +1,350 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &GrainJSONResource{}
+var _ resource.ResourceWithImportState = &GrainJSONResource{}
+var _ resource.ResourceWithUpgradeState = &GrainJSONResource{}
+var _ resource.ResourceWithConfigValidators = &GrainJSONResource{}
+
+func NewGrainJSONResource() resource.Resource {
+	return &GrainJSONResource{}
+}
+
+// GrainJSONResource defines the resource implementation.
+type GrainJSONResource struct {
+	minionConnection
+	grainItems       *grainItemsCache
+	unreachable      *unreachableServers
+	defaultDelimiter string
+}
+
+// GrainJSONResourceModel describes the resource data model.
+type GrainJSONResourceModel struct {
+	Id              types.String         `tfsdk:"id"`
+	Server          types.String         `tfsdk:"server"`
+	UyuniSystemName types.String         `tfsdk:"uyuni_system_name"`
+	Uyuni           *uyuniEndpointModel  `tfsdk:"uyuni"`
+	GrainKey        types.String         `tfsdk:"grain_key"`
+	GrainValueJSON  jsontypes.Normalized `tfsdk:"grain_value_json"`
+	KeepOnDestroy   types.Bool           `tfsdk:"keep_on_destroy"`
+	Delimiter       types.String         `tfsdk:"delimiter"`
+	OnUnreachable   types.String         `tfsdk:"on_unreachable"`
+}
+
+// SaltGrainJSONModel is the grains.get output of a grain of any structure.
+type SaltGrainJSONModel struct {
+	Value json.RawMessage `json:"local"`
+}
+
+func (r *GrainJSONResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_grain_json"
+}
+
+func (r *GrainJSONResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt Minion grain of any structure, e.g. a list of maps or nested dictionaries, set from JSON",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "`server|grain_key`, also the ID to import the grain with, e.g. `web01.example.com|roles`.",
+				Computed:            true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks"),
+			"uyuni":             uyuniEndpointAttribute(),
+			"grain_key": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"grain_value_json": schema.StringAttribute{
+				MarkdownDescription: "Value of the grain as JSON, e.g. `jsonencode([{ name = \"web\", port = 80 }])`. Formatting and key order do not cause a diff.",
+				CustomType:          jsontypes.NormalizedType{},
+				Required:            true,
+			},
+			"delimiter": schema.StringAttribute{
+				MarkdownDescription: "Delimiter of the levels of a nested `grain_key`, used to read, write and remove the grain. Defaults to the provider's `default_delimiter`, or `:` as in Salt.",
+				Optional:            true,
+			},
+			"on_unreachable": onUnreachableAttribute(),
+			"keep_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Leave the grain on the minion when the resource is destroyed, e.g. when handing its management over to another system.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+// UpgradeState migrates states written with earlier schema versions.
+func (r *GrainJSONResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return stateUpgraders(
+		// 0 -> 1: the ID separates the server from the grain key with a pipe
+		structuredGrainIDState,
+	)
+}
+
+func (r *GrainJSONResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+	r.grainItems = data.GrainItems
+	r.unreachable = data.Unreachable
+	r.defaultDelimiter = data.DefaultDelimiter
+}
+
+func (r *GrainJSONResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data GrainJSONResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = redactValues(ctx, data.GrainValueJSON.ValueString())
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	err := r.setGrain(ctx, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot create the grain value on the Salt Minion",
+			fmt.Sprintf("cannot create the grain value on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(grainID(data.Server.ValueString(), data.GrainKey.ValueString()))
+
+	tflog.Info(ctx, "created the grain", map[string]any{logFieldServer: data.Server.ValueString(), logFieldGrainKey: data.GrainKey.ValueString()})
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainJSONResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data GrainJSONResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if unreachableRead(ctx, r.transport, r.unreachable, data.Server.ValueString(), data.OnUnreachable, resp) {
+		return
+	}
+
+	ctx = redactValues(ctx, data.GrainValueJSON.ValueString())
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	readGrain, err := r.grainItems.get(ctx, r.transport, minion, data.Server.ValueString(), data.GrainKey.ValueString(), inherited(data.Delimiter, r.defaultDelimiter))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot get the grain value on the Salt Minion",
+			fmt.Sprintf("cannot get the grain value on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	liveGrain := SaltGrainJSONModel{}
+	err = json.Unmarshal(saltJSON(readGrain), &liveGrain)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot parse the grain value",
+			fmt.Sprintf("cannot parse the grain value on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	// grains.get answers an empty string for missing grains
+	if len(liveGrain.Value) == 0 || string(liveGrain.Value) == `""` {
+		tflog.Info(ctx, fmt.Sprintf("grain %s is gone from %s, removing it from state", data.GrainKey.ValueString(), data.Server.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// the normalized type keeps the configured formatting unless the value really changed
+	data.GrainValueJSON = jsontypes.NewNormalizedValue(string(liveGrain.Value))
+	data.Id = types.StringValue(grainID(data.Server.ValueString(), data.GrainKey.ValueString()))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainJSONResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data GrainJSONResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = redactValues(ctx, data.GrainValueJSON.ValueString())
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	err := r.setGrain(ctx, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot set the grain value on the Salt Minion",
+			fmt.Sprintf("cannot set the grain value on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainJSONResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data GrainJSONResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	if data.KeepOnDestroy.ValueBool() {
+		tflog.Info(ctx, fmt.Sprintf("keep_on_destroy is set, leaving grain %s on %s", data.GrainKey.ValueString(), data.Server.ValueString()))
+		return
+	}
+
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to wait for the minion to be up",
+			fmt.Sprintf("failed to wait for the minion %s to be up: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	runCommand := minion.Command("grains.set", deleteGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	_, err = r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the grain on the Salt Minion",
+			fmt.Sprintf("cannot delete the grain %s on the Salt Minion %s: %s", data.GrainKey.ValueString(), data.Server.ValueString(), err),
+		)
+	}
+}
+
+func (r *GrainJSONResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		grainKeyValidator{},
+		serverAddressValidator{},
+		onUnreachableValidator{},
+	}
+}
+
+func (r *GrainJSONResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importGrainState(ctx, req, resp)
+}
+
+// setGrain sets the grain to the configured JSON. salt-call loads its arguments as YAML, of
+// which JSON is a subset, so the grain gets the structure of the JSON rather than its text.
+func (r *GrainJSONResource) setGrain(ctx context.Context, data GrainJSONResourceModel) error {
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return fmt.Errorf("failed to wait for the minion to be up: %s", err)
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return err
+	}
+
+	// compacted, so the command line does not carry the configured indentation
+	var value any
+	if err := json.Unmarshal([]byte(data.GrainValueJSON.ValueString()), &value); err != nil {
+		return fmt.Errorf("grain_value_json is not valid JSON: %s", err)
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	runCommand := minion.Command("grains.set", setGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), string(content))...)
+	_, err = r.transport.Run(mutating(redactValues(ctx, string(content))), data.Server.ValueString(), runCommand, 0)
+	return err
+}
@@ -0,0 +1,209 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"strings"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithMoveState = &GrainResource{}
+var _ resource.ResourceWithMoveState = &GrainStringResource{}
+
+// isSaltyResource reports whether a moved state comes from resourceType of this provider.
+func isSaltyResource(req resource.MoveStateRequest, resourceType string) bool {
+	return req.SourceTypeName == resourceType && strings.HasSuffix(req.SourceProviderAddress, "/salty")
+}
+
+// grainConversionKey marks in the private state a moved grain which still has the shape of its
+// source resource on the minion. The next apply converts the grain and drops the mark.
+const grainConversionKey = "grain_conversion"
+
+// privateState is the private state of a request or response.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+// movedGrain reports whether the grain was moved from another resource type and not converted yet.
+func movedGrain(ctx context.Context, private privateState) bool {
+	value, _ := private.GetKey(ctx, grainConversionKey)
+	return len(value) > 0
+}
+
+// stringGrainAsList turns the grains.get output of a string grain into the one of a list
+// holding it, so a grain moved from salty_grain_string reads as a salty_grain until converted.
+func stringGrainAsList(output string) string {
+	var grain struct {
+		Local any `json:"local"`
+	}
+	if err := json.Unmarshal(saltJSON(output), &grain); err != nil {
+		return output
+	}
+	value, ok := grain.Local.(string)
+	if !ok {
+		return output
+	}
+
+	values := []string{}
+	if value != "" {
+		values = append(values, value)
+	}
+	converted, _ := json.Marshal(map[string][]string{"local": values})
+	return string(converted)
+}
+
+// listGrainAsString turns the grains.get output of a list grain with a single value into the
+// one of a string grain, so a grain moved from salty_grain reads as a salty_grain_string until
+// converted. A list with other values is not taken over, converting it would drop them.
+func listGrainAsString(output string) (string, error) {
+	var grain struct {
+		Local any `json:"local"`
+	}
+	if err := json.Unmarshal(saltJSON(output), &grain); err != nil {
+		return output, nil
+	}
+	values, ok := grain.Local.([]any)
+	if !ok {
+		return output, nil
+	}
+
+	value, ok := "", len(values) == 1
+	if ok {
+		value, ok = values[0].(string)
+	}
+	if !ok {
+		return "", fmt.Errorf("the grain holds %d values, a salty_grain_string only takes over a list with a single string, remove the other values first", len(values))
+	}
+	converted, _ := json.Marshal(map[string]string{"local": value})
+	return string(converted), nil
+}
+
+// MoveState lets `moved` blocks turn a salty_grain_string into a salty_grain with a single
+// value. The string grain on the minion is rewritten as a list by the next apply.
+func (r *GrainResource) MoveState(ctx context.Context) []resource.StateMover {
+	sourceSchema := resource.SchemaResponse{}
+	(&GrainStringResource{}).Schema(ctx, resource.SchemaRequest{}, &sourceSchema)
+
+	return []resource.StateMover{
+		{
+			SourceSchema: &sourceSchema.Schema,
+			StateMover: func(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+				if !isSaltyResource(req, "salty_grain_string") || req.SourceState == nil {
+					return
+				}
+
+				var source GrainStringResourceModel
+				resp.Diagnostics.Append(req.SourceState.Get(ctx, &source)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				grainValue, diags := types.ListValue(types.StringType, []attr.Value{source.GrainValue})
+				resp.Diagnostics.Append(diags...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				target := GrainResourceModel{
+					Id:                    source.Id,
+					Server:                source.Server,
+					MinionId:              source.MinionId,
+					UyuniSystemName:       source.UyuniSystemName,
+					Uyuni:                 source.Uyuni,
+					GrainKey:              source.GrainKey,
+					GrainValue:            grainValue,
+					ApplyState:            source.ApplyState,
+					SyncBeforeApply:       source.SyncBeforeApply,
+					KeepOnDestroy:         source.KeepOnDestroy,
+					PreCommand:            source.PreCommand,
+					PostCommand:           source.PostCommand,
+					HookOnFailure:         source.HookOnFailure,
+					Delimiter:             source.Delimiter,
+					OnUnreachable:         source.OnUnreachable,
+					Saltenv:               source.Saltenv,
+					Pillar:                source.Pillar,
+					ApplyStateFailureMode: source.ApplyStateFailureMode,
+					StatesChanged:         source.StatesChanged,
+					StatesFailed:          source.StatesFailed,
+					DurationSeconds:       source.DurationSeconds,
+					Protect:               source.Protect,
+					AllowedValues:         source.AllowedValues,
+					ValueRegex:            source.ValueRegex,
+					ActualValues:          types.ListValueMust(types.StringType, []attr.Value{}),
+				}
+				resp.Diagnostics.Append(resp.TargetState.Set(ctx, &target)...)
+				resp.Diagnostics.Append(resp.TargetPrivate.SetKey(ctx, grainConversionKey, []byte(`"string"`))...)
+			},
+		},
+	}
+}
+
+// MoveState lets `moved` blocks turn a salty_grain holding a single value into a
+// salty_grain_string. The list grain on the minion is rewritten as a string by the next apply.
+func (r *GrainStringResource) MoveState(ctx context.Context) []resource.StateMover {
+	sourceSchema := resource.SchemaResponse{}
+	(&GrainResource{}).Schema(ctx, resource.SchemaRequest{}, &sourceSchema)
+
+	return []resource.StateMover{
+		{
+			SourceSchema: &sourceSchema.Schema,
+			StateMover: func(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+				if !isSaltyResource(req, "salty_grain") || req.SourceState == nil {
+					return
+				}
+
+				var source GrainResourceModel
+				resp.Diagnostics.Append(req.SourceState.Get(ctx, &source)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				values := grainListValues(source.GrainValue)
+				if len(values) != 1 {
+					resp.Diagnostics.AddError(
+						"Cannot move the grain",
+						fmt.Sprintf("salty_grain %s holds %d values, only a grain with exactly one value can become a salty_grain_string.", source.Id.ValueString(), len(values)),
+					)
+					return
+				}
+
+				target := GrainStringResourceModel{
+					Id:                    source.Id,
+					Server:                source.Server,
+					MinionId:              source.MinionId,
+					UyuniSystemName:       source.UyuniSystemName,
+					Uyuni:                 source.Uyuni,
+					GrainKey:              source.GrainKey,
+					GrainValue:            types.StringValue(values[0]),
+					ApplyState:            source.ApplyState,
+					SyncBeforeApply:       source.SyncBeforeApply,
+					KeepOnDestroy:         source.KeepOnDestroy,
+					PreCommand:            source.PreCommand,
+					PostCommand:           source.PostCommand,
+					HookOnFailure:         source.HookOnFailure,
+					Delimiter:             source.Delimiter,
+					OnUnreachable:         source.OnUnreachable,
+					Saltenv:               source.Saltenv,
+					Pillar:                source.Pillar,
+					ApplyStateFailureMode: source.ApplyStateFailureMode,
+					StatesChanged:         source.StatesChanged,
+					StatesFailed:          source.StatesFailed,
+					DurationSeconds:       source.DurationSeconds,
+					Protect:               source.Protect,
+					AllowedValues:         source.AllowedValues,
+					ValueRegex:            source.ValueRegex,
+				}
+				resp.Diagnostics.Append(resp.TargetState.Set(ctx, &target)...)
+				resp.Diagnostics.Append(resp.TargetPrivate.SetKey(ctx, grainConversionKey, []byte(`"list"`))...)
+			},
+		},
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"slices"
+	"strings"
+)
+
+// previewPlan warns which values the apply is going to append to and remove from the live grain.
+// Values already on the minion are not appended again, so the HCL diff alone can be misleading.
+func (r *GrainResource) previewPlan(ctx context.Context, plan GrainResourceModel, creating bool, diags *diag.Diagnostics) {
+	if plan.Server.IsUnknown() || plan.GrainKey.IsUnknown() || plan.GrainValue.IsUnknown() {
+		return
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(plan.Uyuni), plan.Server.ValueString(), uyuniSystemName(plan.Server.ValueString(), plan.UyuniSystemName))
+	var readGrain string
+	if err == nil {
+		readGrain, err = r.grainItems.get(ctx, r.transport, minion, plan.Server.ValueString(), plan.GrainKey.ValueString(), inherited(plan.Delimiter, r.defaultDelimiter))
+	}
+	if err != nil {
+		diags.AddWarning(
+			"Cannot preview the grain change",
+			fmt.Sprintf("cannot read the grain %s on the Salt Minion %s for plan_preview: %s", plan.GrainKey.ValueString(), plan.Server.ValueString(), err),
+		)
+		return
+	}
+
+	if err := grainTypeMismatch(readGrain); err != nil {
+		diags.AddWarning(
+			"Grain type mismatch",
+			fmt.Sprintf("the apply will fail on the grain %s of the Salt Minion %s: %s", plan.GrainKey.ValueString(), plan.Server.ValueString(), err),
+		)
+		return
+	}
+
+	live := SaltGrainModel{}
+	_ = json.Unmarshal(saltJSON(readGrain), &live)
+
+	planned := grainListValues(plan.GrainValue)
+	var appended, removed []string
+	for _, value := range planned {
+		if !slices.Contains(live.Roles, value) {
+			appended = append(appended, value)
+		}
+	}
+	// a create leaves the values it finds alone unless it replaces the whole list, an update
+	// removes the ones no longer configured
+	if !creating || plan.ManagementMode.ValueString() == grainModeReplace {
+		for _, value := range live.Roles {
+			if !slices.Contains(planned, value) {
+				removed = append(removed, value)
+			}
+		}
+	}
+
+	var changes []string
+	if len(appended) > 0 {
+		changes = append(changes, fmt.Sprintf("append %q", appended))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("remove %q", removed))
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	diags.AddWarning(
+		"Grain change preview",
+		fmt.Sprintf("the apply will %s on the grain %s of the Salt Minion %s", strings.Join(changes, " and "), plan.GrainKey.ValueString(), plan.Server.ValueString()),
+	)
+}
+
+// previewPlan warns which value the apply is going to replace on the minion.
+func (r *GrainStringResource) previewPlan(ctx context.Context, plan GrainStringResourceModel, diags *diag.Diagnostics) {
+	if plan.Server.IsUnknown() || plan.GrainKey.IsUnknown() || plan.GrainValue.IsUnknown() {
+		return
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(plan.Uyuni), plan.Server.ValueString(), uyuniSystemName(plan.Server.ValueString(), plan.UyuniSystemName))
+	var readGrain string
+	if err == nil {
+		readGrain, err = r.grainItems.get(ctx, r.transport, minion, plan.Server.ValueString(), plan.GrainKey.ValueString(), inherited(plan.Delimiter, r.defaultDelimiter))
+	}
+	if err != nil {
+		diags.AddWarning(
+			"Cannot preview the grain change",
+			fmt.Sprintf("cannot read the grain %s on the Salt Minion %s for plan_preview: %s", plan.GrainKey.ValueString(), plan.Server.ValueString(), err),
+		)
+		return
+	}
+
+	live := SaltGrainStringModel{}
+	_ = json.Unmarshal(saltJSON(readGrain), &live)
+
+	switch live.Value {
+	case plan.GrainValue.ValueString():
+		return
+	case "":
+		diags.AddWarning(
+			"Grain change preview",
+			fmt.Sprintf("the apply will set the grain %s of the Salt Minion %s to %q, it is not set yet", plan.GrainKey.ValueString(), plan.Server.ValueString(), plan.GrainValue.ValueString()),
+		)
+	default:
+		diags.AddWarning(
+			"Grain change preview",
+			fmt.Sprintf("the apply will replace %q with %q in the grain %s of the Salt Minion %s", live.Value, plan.GrainValue.ValueString(), plan.GrainKey.ValueString(), plan.Server.ValueString()),
+		)
+	}
+}
@@ -4,28 +4,30 @@
 package provider
 
 import (
-	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"golang.org/x/crypto/ssh"
-	"io"
-	"net/http"
-	"net/http/cookiejar"
+	"slices"
 	"strings"
-	"time"
+	"terraform-provider-salty/internal/saltclient"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &GrainResource{}
+var _ resource.ResourceWithUpgradeState = &GrainResource{}
 var _ resource.ResourceWithImportState = &GrainResource{}
+var _ resource.ResourceWithModifyPlan = &GrainResource{}
+var _ resource.ResourceWithConfigValidators = &GrainResource{}
 
 func NewGrainResource() resource.Resource {
 	return &GrainResource{}
@@ -33,42 +35,117 @@ func NewGrainResource() resource.Resource {
 
 // GrainResource defines the resource implementation.
 type GrainResource struct {
-	username      *string
-	privateKey    *string
-	uyuniBaseURL  *string
-	uyuniUsername *string
-	uyuniPassword *string
+	minionConnection
+	uyuniGrainFallback bool
+	grainItems         *grainItemsCache
+	unreachable        *unreachableServers
+	defaultDelimiter   string
+	planPreview        bool
 }
 
 // GrainResourceModel describes the resource data model.
 type GrainResourceModel struct {
-	Id         types.String `tfsdk:"id"`
-	Server     types.String `tfsdk:"server"`
-	GrainKey   types.String `tfsdk:"grain_key"`
-	GrainValue types.List   `tfsdk:"grain_value"`
-	ApplyState types.Bool   `tfsdk:"apply_state"`
+	Id                    types.String        `tfsdk:"id"`
+	Server                types.String        `tfsdk:"server"`
+	UyuniSystemName       types.String        `tfsdk:"uyuni_system_name"`
+	Uyuni                 *uyuniEndpointModel `tfsdk:"uyuni"`
+	MinionId              types.String        `tfsdk:"minion_id"`
+	GrainKey              types.String        `tfsdk:"grain_key"`
+	GrainValue            types.List          `tfsdk:"grain_value"`
+	ApplyState            types.Bool          `tfsdk:"apply_state"`
+	SyncBeforeApply       types.Bool          `tfsdk:"sync_before_apply"`
+	KeepOnDestroy         types.Bool          `tfsdk:"keep_on_destroy"`
+	PreCommand            types.String        `tfsdk:"pre_command"`
+	PostCommand           types.String        `tfsdk:"post_command"`
+	HookOnFailure         types.String        `tfsdk:"hook_on_failure"`
+	Delimiter             types.String        `tfsdk:"delimiter"`
+	OnUnreachable         types.String        `tfsdk:"on_unreachable"`
+	Saltenv               types.String        `tfsdk:"saltenv"`
+	Pillar                types.Map           `tfsdk:"pillar"`
+	ApplyStateFailureMode types.String        `tfsdk:"apply_state_failure_mode"`
+	StatesChanged         types.Int64         `tfsdk:"states_changed"`
+	StatesFailed          types.Int64         `tfsdk:"states_failed"`
+	DurationSeconds       types.Float64       `tfsdk:"duration_seconds"`
+	Protect               types.Bool          `tfsdk:"protect"`
+	AllowedValues         types.List          `tfsdk:"allowed_values"`
+	ValueRegex            types.String        `tfsdk:"value_regex"`
+	ActualValues          types.List          `tfsdk:"actual_values"`
+	ManagementMode        types.String        `tfsdk:"management_mode"`
+}
+
+// stateApply returns the state.apply run configured by data.
+func (data GrainResourceModel) stateApply() stateApply {
+	return stateApply{
+		Server:          data.Server.ValueString(),
+		SystemName:      uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName),
+		Uyuni:           data.Uyuni,
+		SyncBeforeApply: data.SyncBeforeApply.ValueBool(),
+		Saltenv:         data.Saltenv,
+		Pillar:          data.Pillar,
+		FailureMode:     data.ApplyStateFailureMode,
+	}
 }
 
 type SaltGrainModel struct {
 	Roles []string `json:"local"`
 }
 
+// grainTypeMismatch returns an error when the grain in a grains.get output is set, but not to a
+// list, naming the resource type for its actual shape. Decoded into SaltGrainModel such a grain
+// would look empty and be overwritten.
+func grainTypeMismatch(output string) error {
+	var grain struct {
+		Local any `json:"local"`
+	}
+	if err := json.Unmarshal(saltJSON(output), &grain); err != nil {
+		return nil
+	}
+
+	switch value := grain.Local.(type) {
+	case string:
+		// grains.get returns an empty string for a missing grain
+		if value != "" {
+			return fmt.Errorf("the grain holds the string %q, manage it with salty_grain_string instead", value)
+		}
+	case map[string]any:
+		return fmt.Errorf("the grain holds a dictionary, manage it with salty_grain_json instead")
+	case float64, bool:
+		return fmt.Errorf("the grain holds the scalar %v, manage it with salty_grain_json instead", value)
+	}
+	return nil
+}
+
 func (r *GrainResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_grain"
 }
 
 func (r *GrainResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: 3,
+
 		// This description is used by the documentation generator and the language server.
 		MarkdownDescription: "Example resource",
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				Computed: true,
+				MarkdownDescription: "`server|grain_key`, also the ID to import the grain with, e.g. `web01.example.com|roles`.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
 			"server": schema.StringAttribute{
 				Required: true,
 			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks and by `uyuni_grain_read_fallback`"),
+			"uyuni":             uyuniEndpointAttribute(),
+			"minion_id": schema.StringAttribute{
+				MarkdownDescription: "Salt minion ID of `server`, read from its `id` grain. It often differs from the SSH address.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
 			"grain_key": schema.StringAttribute{
 				Required: true,
 			},
@@ -79,10 +156,94 @@ func (r *GrainResource) Schema(ctx context.Context, req resource.SchemaRequest,
 			"apply_state": schema.BoolAttribute{
 				Required: true,
 			},
+			"sync_before_apply": schema.BoolAttribute{
+				MarkdownDescription: "Run `saltutil.sync_all` before `state.apply` so freshly pushed custom modules and states are used.",
+				Optional:            true,
+			},
+			"delimiter": schema.StringAttribute{
+				MarkdownDescription: "Delimiter of the levels of a nested `grain_key`, used to read, write and remove the grain. Defaults to the provider's `default_delimiter`, or `:` as in Salt.",
+				Optional:            true,
+			},
+			"on_unreachable": onUnreachableAttribute(),
+			"saltenv": schema.StringAttribute{
+				MarkdownDescription: "Salt environment of the `state.apply` run of `apply_state`, e.g. `staging` to exercise the states of a non-base environment. Defaults to the provider's `default_saltenv`.",
+				Optional:            true,
+			},
+			"pillar": schema.MapAttribute{
+				MarkdownDescription: "Pillar data passed to the `state.apply` run as `pillar='{...}'`, overriding the pillar of the minion for this run only.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"apply_state_failure_mode": schema.StringAttribute{
+				MarkdownDescription: "What failed states in the `state.apply` run do: `error` fails the operation, `warn` only reports them as a warning and `ignore` just logs the result, e.g. while a new state is still being worked on. Defaults to `error`.",
+				Optional:            true,
+			},
+			"states_changed": schema.Int64Attribute{
+				MarkdownDescription: "Number of states with changes in the `state.apply` run of the last create or update. Null when `apply_state` is not set.",
+				Computed:            true,
+			},
+			"states_failed": schema.Int64Attribute{
+				MarkdownDescription: "Number of failed states in the `state.apply` run of the last create or update.",
+				Computed:            true,
+			},
+			"duration_seconds": schema.Float64Attribute{
+				MarkdownDescription: "Sum of the state durations of the `state.apply` run of the last create or update, in seconds.",
+				Computed:            true,
+			},
+			"pre_command": schema.StringAttribute{
+				MarkdownDescription: "Shell command run on the server before the grain is changed, e.g. to drain it from a load balancer. The grain is left alone when it fails, unless `hook_on_failure` is `continue`.",
+				Optional:            true,
+			},
+			"post_command": schema.StringAttribute{
+				MarkdownDescription: "Shell command run on the server once the grain was changed and the state applied, e.g. to restart a service or put the server back into its load balancer.",
+				Optional:            true,
+			},
+			"hook_on_failure": schema.StringAttribute{
+				MarkdownDescription: "What a failing `pre_command` or `post_command` does, `fail` the operation or `continue` with a warning. Defaults to `fail`.",
+				Optional:            true,
+			},
+			"keep_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Leave the grain on the minion when the resource is destroyed, e.g. when handing its management over to another system.",
+				Optional:            true,
+			},
+			"protect": schema.BoolAttribute{
+				MarkdownDescription: "Refuse to destroy the grain or remove any of its values until the flag is removed.",
+				Optional:            true,
+			},
+			"allowed_values": schema.ListAttribute{
+				MarkdownDescription: "Values `grain_value` may take. Checked at plan time.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"value_regex": schema.StringAttribute{
+				MarkdownDescription: "Regular expression every value of `grain_value` must match. Checked at plan time.",
+				Optional:            true,
+			},
+			"actual_values": schema.ListAttribute{
+				MarkdownDescription: "Values present in the grain on the minion which are not part of `grain_value`, e.g. added out of band. Planned empty on updates, which remove them.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"management_mode": schema.StringAttribute{
+				MarkdownDescription: "How the values reach the grain: `append` adds the missing values and removes the ones no longer configured with `grains.append` and `grains.remove`, leaving values found on a create alone. `replace` writes the whole list with one `grains.set`, values set outside of Terraform included. Defaults to `append`.",
+				Optional:            true,
+			},
 		},
 	}
 }
 
+// UpgradeState migrates states written with earlier schema versions.
+func (r *GrainResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return stateUpgraders(
+		// 0 -> 1: schema versioning introduced, attributes unchanged
+		unchangedState,
+		// 1 -> 2: grain keys and values are quoted explicitly, the state never held the quotes
+		unchangedState,
+		// 2 -> 3: the ID separates the server from the grain key with a pipe
+		structuredGrainIDState,
+	)
+}
+
 func (r *GrainResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
@@ -101,14 +262,18 @@ func (r *GrainResource) Configure(ctx context.Context, req resource.ConfigureReq
 		return
 	}
 
-	r.username = &data.Username
-	r.privateKey = &data.PrivateKey
-	r.uyuniBaseURL = &data.UyuniBaseURL
-	r.uyuniUsername = &data.UyuniUsername
-	r.uyuniPassword = &data.UyuniPassword
+	r.minionConnection.configure(data)
+	r.uyuniGrainFallback = data.UyuniGrainReadFallback
+	r.grainItems = data.GrainItems
+	r.unreachable = data.Unreachable
+	r.defaultDelimiter = data.DefaultDelimiter
+	r.planPreview = data.PlanPreview
 }
 
 func (r *GrainResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
 	var data GrainResourceModel
 
 	// Read Terraform plan data into the model
@@ -118,7 +283,14 @@ func (r *GrainResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
-	err := r.waitMinionIsUp(ctx, data)
+	ctx = redactValues(ctx, grainListValues(data.GrainValue)...)
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"failed to wait for the minion to be up",
@@ -127,50 +299,136 @@ func (r *GrainResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
-	for _, value := range data.GrainValue.Elements() {
-		runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.append %s %s", data.GrainKey.String(), value.String())
-		_, err := r.runRemoteCommand(runCommand, ctx, data)
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	if data.MinionId.IsUnknown() {
+		minionId, err := r.grainItems.saltMinionID(ctx, r.transport, minion, data.Server.ValueString())
 		if err != nil {
 			resp.Diagnostics.AddError(
-				"Cannot create the grain value on the Salt Minion",
-				fmt.Sprintf("cannot create the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
+				"Cannot read the Salt minion ID",
+				fmt.Sprintf("cannot read the id grain on the Salt Minion %s: %s", data.Server.ValueString(), err),
 			)
+			return
 		}
+		data.MinionId = types.StringValue(minionId)
+	}
+
+	if !runHook(ctx, r.transport, data.Server.ValueString(), "pre_command", data.PreCommand, data.HookOnFailure, &resp.Diagnostics) {
+		return
+	}
+
+	// values already on the minion, e.g. left over by a failed apply, are not appended twice
+	runCommand := minion.Command("grains.get", grainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	existingGrain, err := r.transport.Run(ctx, data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot get the grain value on the Salt Minion",
+			fmt.Sprintf("cannot get the grain value on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+	if err := grainTypeMismatch(existingGrain); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("grain_key"),
+			"Grain type mismatch",
+			fmt.Sprintf("cannot manage the grain %s on the Salt Minion %s as a list: %s", data.GrainKey.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+	existing := SaltGrainModel{}
+	_ = json.Unmarshal(saltJSON(existingGrain), &existing)
+
+	if data.ManagementMode.ValueString() == grainModeReplace {
+		r.setGrainValues(ctx, minion, data, &resp.Diagnostics)
 		if resp.Diagnostics.HasError() {
 			return
 		}
+	} else {
+		var missing, skipped []string
+		for _, value := range grainListValues(data.GrainValue) {
+			if slices.Contains(existing.Roles, value) {
+				skipped = append(skipped, value)
+				continue
+			}
+			missing = append(missing, value)
+		}
+
+		// one grains.append of the whole list, so a failure leaves no values half appended
+		if len(missing) > 0 {
+			runCommand := minion.Command("grains.append", appendGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), missing)...)
+			_, err := r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+			if err != nil {
+				resp.Diagnostics.AddError(
+					"Cannot create the grain value on the Salt Minion",
+					fmt.Sprintf("cannot create the grain values %s on the Salt Minion %s: %s", strings.Join(missing, ", "), data.Server.ValueString(), err),
+				)
+				return
+			}
+		}
+		if len(skipped) > 0 {
+			resp.Diagnostics.AddWarning(
+				"Grain values already present",
+				fmt.Sprintf("the grain %s on the Salt Minion %s already holds %s, these values were not appended again and are managed by this resource from now on", data.GrainKey.ValueString(), data.Server.ValueString(), strings.Join(skipped, ", ")),
+			)
+		}
+	}
+
+	// values already present before the create are not managed by this resource
+	runCommand = minion.Command("grains.get", grainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	readGrain, err := r.transport.Run(ctx, data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot get the grain value on the Salt Minion",
+			fmt.Sprintf("cannot get the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
 	}
 
+	liveGrains := SaltGrainModel{}
+	_ = json.Unmarshal(saltJSON(readGrain), &liveGrains)
+	data.ActualValues = unmanagedGrainValues(data.GrainValue, liveGrains.Roles)
+
 	// For the purposes of this example code, hardcoding a response value to
 	// save into the Terraform state.
-	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.GrainKey.ValueString()))
+	data.Id = types.StringValue(grainID(data.Server.ValueString(), data.GrainKey.ValueString()))
 
-	// Write logs using the tflog package
-	// Documentation: https://terraform.io/plugin/log
-	tflog.Info(ctx, "created a resource")
-	b, _ := json.MarshalIndent(data, "", "    ")
-	tflog.Info(ctx, string(b))
+	tflog.Info(ctx, "created the grain", map[string]any{logFieldServer: data.Server.ValueString(), logFieldGrainKey: data.GrainKey.ValueString()})
 
+	var summary *highstateSummary
 	if data.ApplyState.ValueBool() {
-		applyStateResult, err := r.applyState(ctx, data)
+		applied, err := r.applyState(ctx, data.stateApply())
 		if err != nil {
 			resp.Diagnostics.AddError(
 				err.Error(),
 				err.Error())
+			// the grain is already written, keep it managed instead of orphaning it
+			data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			return
 		}
-		resp.Diagnostics.AddWarning("apply state result", applyStateResult)
-		if resp.Diagnostics.HasError() {
+		summary = applied
+		if !reportHighstate(ctx, data.Server.ValueString(), data.ApplyStateFailureMode, applied, &resp.Diagnostics) {
+			data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 			return
 		}
 	}
+	data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+
+	runHook(ctx, r.transport, data.Server.ValueString(), "post_command", data.PostCommand, data.HookOnFailure, &resp.Diagnostics)
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *GrainResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	tflog.Info(ctx, "READ called here")
-
 	var data GrainResourceModel
 
 	diags := req.State.Get(ctx, &data)
@@ -181,7 +439,13 @@ func (r *GrainResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
-	err := r.waitMinionIsUp(ctx, data)
+	if unreachableRead(ctx, r.transport, r.unreachable, data.Server.ValueString(), data.OnUnreachable, resp) {
+		return
+	}
+
+	ctx = redactValues(ctx, grainListValues(data.GrainValue)...)
+
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"failed to wait for the minion to be up",
@@ -190,8 +454,29 @@ func (r *GrainResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
-	runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.get %s --out=json", data.GrainKey.String())
-	readGrain, err := r.runRemoteCommand(runCommand, ctx, data)
+	var readGrain, minionId string
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err == nil {
+		readGrain, err = r.grainItems.get(ctx, r.transport, minion, data.Server.ValueString(), data.GrainKey.ValueString(), inherited(data.Delimiter, r.defaultDelimiter))
+	}
+	if err == nil {
+		minionId, err = r.grainItems.saltMinionID(ctx, r.transport, minion, data.Server.ValueString())
+	}
+	if err != nil && r.uyuniGrainFallback {
+		resp.Diagnostics.AddWarning(
+			"Grain read through Uyuni",
+			fmt.Sprintf("cannot read the grain value on the Salt Minion %s over SSH, falling back to Uyuni: %s", data.Server.ValueString(), err),
+		)
+		readGrain, err = r.uyuni.withEndpoint(data.Uyuni).ReadGrain(ctx, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName), data.GrainKey.ValueString())
+		if errors.Is(err, saltclient.ErrUyuniGrainNotStored) {
+			// nothing known about the grain, an empty value would plan to write it all again
+			resp.Diagnostics.AddWarning(
+				"Grain not stored in Uyuni",
+				fmt.Sprintf("keeping the grain values of the Salt Minion %s from state: %s", data.Server.ValueString(), err),
+			)
+			return
+		}
+	}
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Cannot create the grain value on the Salt Minion",
@@ -202,21 +487,30 @@ func (r *GrainResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
-	tflog.Info(ctx, "readGrain, raw JSON:")
-	tflog.Info(ctx, readGrain)
-
-	liveGrains := SaltGrainModel{}
-	_ = json.Unmarshal([]byte(readGrain), &liveGrains)
+	// a grain moved from salty_grain_string holds a string until the next apply converts it
+	if movedGrain(ctx, req.Private) {
+		readGrain = stringGrainAsList(readGrain)
+	}
 
-	tflog.Info(ctx, "decoded grains from JSON:")
-	for _, role := range liveGrains.Roles {
-		tflog.Info(ctx, role)
+	// leave the state alone instead of planning to overwrite a grain of another shape
+	if err := grainTypeMismatch(readGrain); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("grain_key"),
+			"Grain type mismatch",
+			fmt.Sprintf("cannot manage the grain %s on the Salt Minion %s as a list: %s", data.GrainKey.ValueString(), data.Server.ValueString(), err),
+		)
+		return
 	}
 
+	liveGrains := SaltGrainModel{}
+	_ = json.Unmarshal(saltJSON(readGrain), &liveGrains)
+
 	if liveGrains.Roles == nil {
 		liveGrains.Roles = []string{}
 	}
 
+	data.ActualValues = unmanagedGrainValues(data.GrainValue, liveGrains.Roles)
+
 	var grainItems []attr.Value
 	for _, item := range liveGrains.Roles {
 		grainItems = append(grainItems, types.StringValue(item))
@@ -230,11 +524,14 @@ func (r *GrainResource) Read(ctx context.Context, req resource.ReadRequest, resp
 
 	data.GrainValue = listVal
 
-	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.GrainKey.ValueString()))
+	// the Uyuni fallback does not tell the minion ID, keep the known one then
+	if minionId != "" {
+		data.MinionId = types.StringValue(minionId)
+	}
 
-	tflog.Info(ctx, "read a resource")
-	b, _ := json.MarshalIndent(data, "", "    ")
-	tflog.Info(ctx, string(b))
+	data.Id = types.StringValue(grainID(data.Server.ValueString(), data.GrainKey.ValueString()))
+
+	tflog.Debug(ctx, "read the grain", map[string]any{logFieldServer: data.Server.ValueString(), logFieldGrainKey: data.GrainKey.ValueString()})
 
 	diags = resp.State.Set(ctx, &data)
 	resp.Diagnostics.Append(diags...)
@@ -244,6 +541,9 @@ func (r *GrainResource) Read(ctx context.Context, req resource.ReadRequest, resp
 }
 
 func (r *GrainResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
 	var data GrainResourceModel
 
 	// Read Terraform plan data into the model
@@ -253,7 +553,14 @@ func (r *GrainResource) Update(ctx context.Context, req resource.UpdateRequest,
 		return
 	}
 
-	err := r.waitMinionIsUp(ctx, data)
+	ctx = redactValues(ctx, grainListValues(data.GrainValue)...)
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"failed to wait for the minion to be up",
@@ -262,75 +569,29 @@ func (r *GrainResource) Update(ctx context.Context, req resource.UpdateRequest,
 		return
 	}
 
-	runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.get %s --out=json", data.GrainKey.String())
-	readGrain, err := r.runRemoteCommand(runCommand, ctx, data)
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Cannot get the grain value on the Salt Minion",
-			fmt.Sprintf("cannot get the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
 		)
-	}
-	if resp.Diagnostics.HasError() {
-		return
-	}
-
-	tflog.Info(ctx, "readGrain, raw JSON:")
-	tflog.Info(ctx, readGrain)
-
-	liveGrains := SaltGrainModel{}
-	err = json.Unmarshal([]byte(readGrain), &liveGrains)
-	if err != nil {
 		return
 	}
 
-	// porovnam state s tim co je v grains a smazu to, co tam byt nema
-
-	tflog.Info(ctx, "UPDATE called here")
-	tflog.Info(ctx, fmt.Sprintf("%v", data.GrainValue.Elements()))
-	tflog.Info(ctx, fmt.Sprintf("%v", liveGrains.Roles))
-	tflog.Info(ctx, "===============")
-
-	var grainValueStr types.String
-	var ok bool
-
-	for _, grainValue := range data.GrainValue.Elements() {
-		if grainValueStr, ok = grainValue.(types.String); !ok {
+	if data.MinionId.IsUnknown() {
+		minionId, err := r.grainItems.saltMinionID(ctx, r.transport, minion, data.Server.ValueString())
+		if err != nil {
 			resp.Diagnostics.AddError(
-				"cannot convert grain to String, type conversion failed",
-				fmt.Sprintf("cannot convert grain to String, type conversion failed: %s", err),
+				"Cannot read the Salt minion ID",
+				fmt.Sprintf("cannot read the id grain on the Salt Minion %s: %s", data.Server.ValueString(), err),
 			)
 			return
 		}
-
-		isFound := false
-		for _, stateGrainValue := range liveGrains.Roles {
-			tflog.Info(ctx, fmt.Sprintf("COMPARING: %s and %s", grainValueStr.ValueString(), stateGrainValue))
-			if grainValueStr.ValueString() == stateGrainValue {
-				isFound = true
-			}
-		}
-		if !isFound {
-			// if not found, the grain needs to be added
-
-			runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.append %s %s --out=json", data.GrainKey.String(), grainValue)
-			tflog.Info(ctx, runCommand)
-			appendGrain, err := r.runRemoteCommand(runCommand, ctx, data)
-			if err != nil {
-				resp.Diagnostics.AddError(
-					"Cannot append the grain value on the Salt Minion",
-					fmt.Sprintf("cannot append the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
-				)
-			}
-			tflog.Info(ctx, appendGrain)
-			if resp.Diagnostics.HasError() {
-				return
-			}
-		}
+		data.MinionId = types.StringValue(minionId)
 	}
 
-	// update grains from what is now on the minion side
-	runCommand = fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.get %s --out=json", data.GrainKey.String())
-	readGrain, err = r.runRemoteCommand(runCommand, ctx, data)
+	runCommand := minion.Command("grains.get", grainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	readGrain, err := r.transport.Run(ctx, data.Server.ValueString(), runCommand, 0)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Cannot get the grain value on the Salt Minion",
@@ -341,66 +602,81 @@ func (r *GrainResource) Update(ctx context.Context, req resource.UpdateRequest,
 		return
 	}
 
-	liveGrains = SaltGrainModel{}
-	err = json.Unmarshal([]byte(readGrain), &liveGrains)
+	converting := movedGrain(ctx, req.Private)
+	if converting {
+		readGrain = stringGrainAsList(readGrain)
+	}
+
+	if err := grainTypeMismatch(readGrain); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("grain_key"),
+			"Grain type mismatch",
+			fmt.Sprintf("cannot manage the grain %s on the Salt Minion %s as a list: %s", data.GrainKey.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	liveGrains := SaltGrainModel{}
+	err = json.Unmarshal(saltJSON(readGrain), &liveGrains)
 	if err != nil {
 		return
 	}
-	tflog.Info(ctx, fmt.Sprintf("AKTUALIZOVANE HODNOTY GRAINS Z MINIONA: %v", liveGrains))
+	// the values about to be removed are as sensitive as the configured ones
+	ctx = redactValues(ctx, liveGrains.Roles...)
 
-	// porovnam grains se statem a pridam to, co v nem neni
-	for _, stateGrainValue := range liveGrains.Roles {
-		isFound := false
-		for _, grainValue := range data.GrainValue.Elements() {
-			if grainValueStr, ok = grainValue.(types.String); !ok {
-				resp.Diagnostics.AddError(
-					"cannot convert grain to String, type conversion failed",
-					fmt.Sprintf("cannot convert grain to String, type conversion failed: %s", err),
-				)
-				return
-			}
+	if !runHook(ctx, r.transport, data.Server.ValueString(), "pre_command", data.PreCommand, data.HookOnFailure, &resp.Diagnostics) {
+		return
+	}
 
-			tflog.Info(ctx, "hodnoty pro porovnavani:")
-			tflog.Info(ctx, stateGrainValue)
-			tflog.Info(ctx, grainValue.String())
+	// grains.append cannot turn the string grain of a moved salty_grain_string into a list
+	if converting || data.ManagementMode.ValueString() == grainModeReplace {
+		r.setGrainValues(ctx, minion, data, &resp.Diagnostics)
+	} else {
+		r.reconcileGrainValues(ctx, minion, data, liveGrains.Roles, &resp.Diagnostics)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, grainConversionKey, nil)...)
 
-			if stateGrainValue == grainValueStr.ValueString() {
-				isFound = true
-			}
-		}
-		if !isFound {
-			// tento grain se musi na minionovi smazat
+	data.Id = types.StringValue(grainID(data.Server.ValueString(), data.GrainKey.ValueString()))
 
-			runCommand = fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.remove %s %s --out=json", data.GrainKey.String(), stateGrainValue)
-			tflog.Info(ctx, runCommand)
-			appendGrain, err := r.runRemoteCommand(runCommand, ctx, data)
-			if err != nil {
-				resp.Diagnostics.AddError(
-					"Cannot delete the grain value on the Salt Minion",
-					fmt.Sprintf("cannot delete the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
-				)
-			}
-			tflog.Info(ctx, appendGrain)
-			if resp.Diagnostics.HasError() {
-				return
-			}
-		}
+	// the update removed every value which is not configured, unless others were added since
+	runCommand = minion.Command("grains.get", grainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	readGrain, err = r.transport.Run(ctx, data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot get the grain value on the Salt Minion",
+			fmt.Sprintf("cannot get the grain value on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
 	}
+	liveGrains = SaltGrainModel{}
+	_ = json.Unmarshal(saltJSON(readGrain), &liveGrains)
+	data.ActualValues = unmanagedGrainValues(data.GrainValue, liveGrains.Roles)
 
+	var summary *highstateSummary
 	if data.ApplyState.ValueBool() {
-		applyStateResult, err := r.applyState(ctx, data)
+		applied, err := r.applyState(ctx, data.stateApply())
 		if err != nil {
 			resp.Diagnostics.AddError(
 				err.Error(),
 				err.Error())
+			// the grain is already written, keep it managed instead of orphaning it
+			data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			return
 		}
-		resp.Diagnostics.AddWarning("apply state result", applyStateResult)
-		if resp.Diagnostics.HasError() {
+		summary = applied
+		if !reportHighstate(ctx, data.Server.ValueString(), data.ApplyStateFailureMode, applied, &resp.Diagnostics) {
+			data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 			return
 		}
 	}
+	data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
 
-	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.GrainKey.ValueString()))
+	runHook(ctx, r.transport, data.Server.ValueString(), "post_command", data.PostCommand, data.HookOnFailure, &resp.Diagnostics)
 
 	diags := resp.State.Set(ctx, &data)
 	resp.Diagnostics.Append(diags...)
@@ -410,6 +686,9 @@ func (r *GrainResource) Update(ctx context.Context, req resource.UpdateRequest,
 }
 
 func (r *GrainResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
 	var data GrainResourceModel
 
 	// Read Terraform prior state data into the model
@@ -420,7 +699,19 @@ func (r *GrainResource) Delete(ctx context.Context, req resource.DeleteRequest,
 		return
 	}
 
-	err := r.waitMinionIsUp(ctx, data)
+	ctx = redactValues(ctx, grainListValues(data.GrainValue)...)
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	if data.KeepOnDestroy.ValueBool() {
+		tflog.Info(ctx, fmt.Sprintf("keep_on_destroy is set, leaving grain %s on %s", data.GrainKey.ValueString(), data.Server.ValueString()))
+		return
+	}
+
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"failed to wait for the minion to be up",
@@ -429,15 +720,24 @@ func (r *GrainResource) Delete(ctx context.Context, req resource.DeleteRequest,
 		return
 	}
 
-	tflog.Info(ctx, "DELETE - Data from the state: ")
-	tflog.Info(ctx, data.Server.String())
-	tflog.Info(ctx, data.Id.String())
-	tflog.Info(ctx, data.GrainKey.String())
-	tflog.Info(ctx, data.GrainValue.String())
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
 
-	for _, grainValue := range data.GrainValue.Elements() {
-		runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.remove %s %s --out=json", data.GrainKey.String(), grainValue)
-		_, err := r.runRemoteCommand(runCommand, ctx, data)
+	tflog.Debug(ctx, "deleting the grain", map[string]any{logFieldServer: data.Server.ValueString(), logFieldGrainKey: data.GrainKey.ValueString()})
+
+	if !runHook(ctx, r.transport, data.Server.ValueString(), "pre_command", data.PreCommand, data.HookOnFailure, &resp.Diagnostics) {
+		return
+	}
+
+	for _, grainValue := range grainListValues(data.GrainValue) {
+		runCommand := minion.Command("grains.remove", removeGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), grainValue)...)
+		_, err := r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
 		if err != nil {
 			resp.Diagnostics.AddError(
 				err.Error(),
@@ -449,176 +749,249 @@ func (r *GrainResource) Delete(ctx context.Context, req resource.DeleteRequest,
 	}
 
 	if data.ApplyState.ValueBool() {
-		applyStateResult, err := r.applyState(ctx, data)
+		applied, err := r.applyState(ctx, data.stateApply())
 		if err != nil {
 			resp.Diagnostics.AddError(
 				err.Error(),
 				err.Error())
+			return
 		}
-		resp.Diagnostics.AddWarning("apply state result", applyStateResult)
-		if resp.Diagnostics.HasError() {
+		if !reportHighstate(ctx, data.Server.ValueString(), data.ApplyStateFailureMode, applied, &resp.Diagnostics) {
 			return
 		}
 	}
-}
 
-func (r *GrainResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	runHook(ctx, r.transport, data.Server.ValueString(), "post_command", data.PostCommand, data.HookOnFailure, &resp.Diagnostics)
 }
 
-func (r *GrainResource) applyState(ctx context.Context, data GrainResourceModel) (string, error) {
-	runCommand := "while true; do found=0; for f in /var/cache/venv-salt-minion/proc/*; do grep state.apply $f; if [ $? -eq 0 ]; then found=1; fi; done; if [ $found -eq 0 ]; then break; fi; sleep 1; done; /usr/lib/venv-salt-minion/bin/salt-call state.apply >> /var/log/state.apply.tf.log 2>&1"
-	applyStateResult, err := r.runRemoteCommand(runCommand, ctx, data)
-	if err != nil {
-		return applyStateResult, fmt.Errorf("cannot apply state: %s", err.Error())
+func (r *GrainResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		var state GrainResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if state.Protect.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Grain is protected",
+				fmt.Sprintf("grain %s on the Salt Minion %s has protect = true, remove the flag before destroying it", state.GrainKey.ValueString(), state.Server.ValueString()),
+			)
+		}
+		return
 	}
 
-	return applyStateResult, nil
-}
+	var server types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("server"), &server)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-func (r *GrainResource) waitMinionIsUp(ctx context.Context, data GrainResourceModel) error {
-	timeout := 30 * time.Minute
-	deadline := time.Now().Add(timeout)
+	// the server usually comes from a compute resource created in the same run,
+	// defer the grain until its address is known instead of planning blindly
+	if server.IsUnknown() && req.ClientCapabilities.DeferralAllowed {
+		tflog.Info(ctx, "deferring the grain until the server is known")
+		resp.Deferred = &resource.Deferred{
+			Reason: resource.DeferredReasonResourceConfigUnknown,
+		}
+		return
+	}
 
-	tflog.Info(ctx, "starting to wait for the minion to be up")
+	// the flag guards against removing values from a protected grain as well
+	if !req.State.Raw.IsNull() {
+		var plan, state GrainResourceModel
+		resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
 
-	for {
-		if time.Now().After(deadline) {
-			return fmt.Errorf("timeout reached after %d minutes; salt-key for %s not accepted", timeout, data.Server.ValueString())
+		// another server may be another minion, its ID is read again
+		if !plan.Server.Equal(state.Server) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("minion_id"), types.StringUnknown())...)
 		}
 
-		found, err := CheckServerAccepted(*r.uyuniBaseURL, *r.uyuniUsername, *r.uyuniPassword, data.Server.ValueString())
-		if err != nil {
-			return fmt.Errorf("error checking salt-key acceptance of %s: %s", data.Server.ValueString(), err)
+		// the update converting a moved grain reads the minion ID again
+		if movedGrain(ctx, req.Private) && len(resp.RequiresReplace) == 0 {
+			resp.Diagnostics.AddWarning(
+				"Grain conversion pending",
+				fmt.Sprintf("the grain %s on the Salt Minion %s was moved from salty_grain_string, this apply rewrites the string as a list", plan.GrainKey.ValueString(), plan.Server.ValueString()),
+			)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("minion_id"), types.StringUnknown())...)
 		}
 
-		tflog.Info(ctx, fmt.Sprintf("called checkServerAccepted with result: %v, error: %s", found, err))
+		// an update removes every value which is not configured, a replacement reads them again
+		if !req.Plan.Raw.Equal(req.State.Raw) && len(resp.RequiresReplace) == 0 {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("actual_values"), types.ListValueMust(types.StringType, []attr.Value{}))...)
+		}
 
-		if found {
-			return nil
+		if plan.Protect.ValueBool() && !plan.GrainValue.IsUnknown() {
+			removed := unmanagedGrainValues(plan.GrainValue, grainListValues(state.GrainValue))
+			if len(removed.Elements()) > 0 {
+				resp.Diagnostics.AddError(
+					"Grain is protected",
+					fmt.Sprintf("grain %s on the Salt Minion %s has protect = true, remove the flag before removing the values %s", plan.GrainKey.ValueString(), plan.Server.ValueString(), removed),
+				)
+			}
 		}
-		time.Sleep(10 * time.Second)
 	}
 
-	// runCommand := "while [ ! -f /etc/venv-salt-minion/pki/minion/minion_master.pub ]; do sleep 1; done"
-	// _, err := r.runRemoteCommand(runCommand, ctx, data)
-	// if err != nil {
-	// 		return fmt.Errorf("failed to wait for the minion to be up: %s", err.Error())
-	// }
-	// return nil
+	if r.planPreview && !req.Plan.Raw.Equal(req.State.Raw) {
+		var plan GrainResourceModel
+		resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+		if !resp.Diagnostics.HasError() {
+			r.previewPlan(ctx, plan, req.State.Raw.IsNull(), &resp.Diagnostics)
+		}
+	}
 }
 
-func (r *GrainResource) runRemoteCommand(runCommand string, ctx context.Context, data GrainResourceModel) (string, error) {
-	signer, err := ssh.ParsePrivateKey([]byte(*r.privateKey))
-	if err != nil {
-		return "", fmt.Errorf("malformed private key: %s, please report this issue to the provider developers", err)
+func (r *GrainResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		grainValueValidator{list: true},
+		grainKeyValidator{},
+		serverAddressValidator{},
+		hookOnFailureValidator{},
+		applyStateFailureModeValidator{},
+		grainManagementModeValidator{},
+		onUnreachableValidator{},
 	}
+}
 
-	config := &ssh.ClientConfig{
-		User: *r.username,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
+func (r *GrainResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importGrainState(ctx, req, resp)
+}
 
-	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:22", data.Server.ValueString()), config)
-	if err != nil {
-		return "", fmt.Errorf("cannot connect to the Salt Minion %s: %s", data.Server.ValueString(), err)
-	}
+// reconcileGrainValues appends the values of data missing from live, the values of the grain
+// on the minion, and removes the values no longer configured one by one.
+func (r *GrainResource) reconcileGrainValues(ctx context.Context, minion saltMinionInstall, data GrainResourceModel, live []string, diags *diag.Diagnostics) {
+	var err error
 
-	session, err := client.NewSession()
-	if err != nil {
-		return "", fmt.Errorf("cannot create session with the Salt Minion %s: %s", data.Server.ValueString(), err)
-	}
+	// porovnam state s tim co je v grains a smazu to, co tam byt nema
 
-	tflog.Info(ctx, runCommand)
-	cmdOutput, err := session.Output(runCommand)
-	tflog.Info(ctx, string(cmdOutput))
+	var grainValueStr types.String
+	var ok bool
 
-	if err != nil {
-		return "", fmt.Errorf("cannot run the command %s on Salt Minion %s: %s", runCommand, data.Server.ValueString(), err)
-	}
+	for _, grainValue := range data.GrainValue.Elements() {
+		if grainValueStr, ok = grainValue.(types.String); !ok {
+			diags.AddError(
+				"cannot convert grain to String, type conversion failed",
+				fmt.Sprintf("cannot convert grain to String, type conversion failed: %s", err),
+			)
+			return
+		}
 
-	return string(cmdOutput), nil
-}
+		isFound := false
+		for _, stateGrainValue := range live {
+			if grainValueStr.ValueString() == stateGrainValue {
+				isFound = true
+			}
+		}
+		if !isFound {
+			// if not found, the grain needs to be added
 
-// CheckServerAccepted logs in and checks if a server is in the accepted list.
-func CheckServerAccepted(baseURL, username, password, serverName string) (bool, error) {
-	// Create HTTP client with cookie jar
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		return false, fmt.Errorf("failed to create cookie jar: %w", err)
-	}
-	client := &http.Client{
-		Jar: jar,
+			runCommand := minion.Command("grains.append", appendGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), []string{grainValueStr.ValueString()})...)
+			_, err := r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+			if err != nil {
+				diags.AddError(
+					"Cannot append the grain value on the Salt Minion",
+					fmt.Sprintf("cannot append the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
+				)
+			}
+			if diags.HasError() {
+				return
+			}
+		}
 	}
 
-	// Login payload
-	loginPayload := map[string]string{
-		"login":    username,
-		"password": password,
-	}
-	payloadBytes, err := json.Marshal(loginPayload)
+	// update grains from what is now on the minion side
+	runCommand := minion.Command("grains.get", grainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	readGrain, err := r.transport.Run(ctx, data.Server.ValueString(), runCommand, 0)
 	if err != nil {
-		return false, fmt.Errorf("failed to marshal login payload: %w", err)
+		diags.AddError(
+			"Cannot get the grain value on the Salt Minion",
+			fmt.Sprintf("cannot get the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
+		)
 	}
-
-	// Perform login request
-	loginURL := fmt.Sprintf("%s/auth/login", strings.TrimRight(baseURL, "/"))
-	req, err := http.NewRequest("POST", loginURL, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		return false, fmt.Errorf("failed to create login request: %w", err)
+	if diags.HasError() {
+		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-	// Skip TLS verification
-	client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
 
-	resp, err := client.Do(req)
+	liveGrains := SaltGrainModel{}
+	err = json.Unmarshal(saltJSON(readGrain), &liveGrains)
 	if err != nil {
-		return false, fmt.Errorf("login request failed: %w", err)
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("login failed: %s", string(body))
-	}
+	// porovnam grains se statem a pridam to, co v nem neni
+	for _, stateGrainValue := range liveGrains.Roles {
+		isFound := false
+		for _, grainValue := range data.GrainValue.Elements() {
+			if grainValueStr, ok = grainValue.(types.String); !ok {
+				diags.AddError(
+					"cannot convert grain to String, type conversion failed",
+					fmt.Sprintf("cannot convert grain to String, type conversion failed: %s", err),
+				)
+				return
+			}
 
-	// Fetch accepted list
-	acceptedListURL := fmt.Sprintf("%s/saltkey/acceptedList", strings.TrimRight(baseURL, "/"))
-	req, err = http.NewRequest("GET", acceptedListURL, nil)
-	if err != nil {
-		return false, fmt.Errorf("failed to create acceptedList request: %w", err)
+			if stateGrainValue == grainValueStr.ValueString() {
+				isFound = true
+			}
+		}
+		if !isFound {
+			// tento grain se musi na minionovi smazat
+
+			runCommand = minion.Command("grains.remove", removeGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), stateGrainValue)...)
+			_, err := r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+			if err != nil {
+				diags.AddError(
+					"Cannot delete the grain value on the Salt Minion",
+					fmt.Sprintf("cannot delete the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
+				)
+			}
+			if diags.HasError() {
+				return
+			}
+		}
 	}
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	resp, err = client.Do(req)
+// setGrainValues writes the whole list of data with a single grains.set, replacing whatever
+// the grain held before.
+func (r *GrainResource) setGrainValues(ctx context.Context, minion saltMinionInstall, data GrainResourceModel, diags *diag.Diagnostics) {
+	values, _ := json.Marshal(nonNil(grainListValues(data.GrainValue)))
+	runCommand := minion.Command("grains.set", setGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), string(values))...)
+	_, err := r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
 	if err != nil {
-		return false, fmt.Errorf("acceptedList request failed: %w", err)
+		diags.AddError(
+			"Cannot set the grain value on the Salt Minion",
+			fmt.Sprintf("cannot set the grain value on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("failed to fetch acceptedList: %s", string(body))
+// unmanagedGrainValues returns the live grain values which are not part of the configured values.
+func unmanagedGrainValues(configured types.List, live []string) types.List {
+	managed := map[string]bool{}
+	for _, value := range configured.Elements() {
+		if str, ok := value.(types.String); ok {
+			managed[str.ValueString()] = true
+		}
 	}
 
-	// Parse the result
-	var result struct {
-		Success bool     `json:"success"`
-		Result  []string `json:"result"`
+	unmanaged := []attr.Value{}
+	for _, value := range live {
+		if !managed[value] {
+			unmanaged = append(unmanaged, types.StringValue(value))
+		}
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return false, fmt.Errorf("failed to parse acceptedList response: %w", err)
-	}
+	return types.ListValueMust(types.StringType, unmanaged)
+}
 
-	// Check if the server is in the list
-	for _, s := range result.Result {
-		if s == serverName {
-			return true, nil
+// grainListValues returns the string values of a grain list.
+func grainListValues(values types.List) []string {
+	var result []string
+	for _, value := range values.Elements() {
+		if str, ok := value.(types.String); ok {
+			result = append(result, str.ValueString())
 		}
 	}
-	return false, nil
+	return result
 }
@@ -0,0 +1,50 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"testing"
+)
+
+func TestAccGrainResource(t *testing.T) {
+	testAccFixture(t, "grain_resource")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccProviderConfig + testAccGrainResourceConfig(`["web"]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("salty_grain.test", "id", testAccServer+"|roles"),
+					resource.TestCheckResourceAttr("salty_grain.test", "minion_id", "minion"),
+					resource.TestCheckResourceAttr("salty_grain.test", "grain_value.#", "1"),
+					resource.TestCheckResourceAttr("salty_grain.test", "grain_value.0", "web"),
+					resource.TestCheckResourceAttr("salty_grain.test", "actual_values.#", "0"),
+				),
+			},
+			// Update and Read testing
+			{
+				Config: testAccProviderConfig + testAccGrainResourceConfig(`["web", "db"]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("salty_grain.test", "grain_value.#", "2"),
+					resource.TestCheckResourceAttr("salty_grain.test", "grain_value.1", "db"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func testAccGrainResourceConfig(grainValue string) string {
+	return `
+resource "salty_grain" "test" {
+  server      = "` + testAccServer + `"
+  grain_key   = "roles"
+  grain_value = ` + grainValue + `
+  apply_state = false
+}
+`
+}
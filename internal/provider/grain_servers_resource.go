@@ -0,0 +1,440 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &GrainServersResource{}
+var _ resource.ResourceWithModifyPlan = &GrainServersResource{}
+var _ resource.ResourceWithConfigValidators = &GrainServersResource{}
+
+// grainServerOK is the server_status of a server holding the configured grain.
+const grainServerOK = "ok"
+
+func NewGrainServersResource() resource.Resource {
+	return &GrainServersResource{}
+}
+
+// GrainServersResource defines the resource implementation.
+type GrainServersResource struct {
+	minionConnection
+	grainItems       *grainItemsCache
+	defaultDelimiter string
+	parallelism      int
+}
+
+// GrainServersResourceModel describes the resource data model.
+type GrainServersResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	Servers       types.Set    `tfsdk:"servers"`
+	GrainKey      types.String `tfsdk:"grain_key"`
+	GrainValue    types.String `tfsdk:"grain_value"`
+	Delimiter     types.String `tfsdk:"delimiter"`
+	KeepOnDestroy types.Bool   `tfsdk:"keep_on_destroy"`
+	ServerStatus  types.Map    `tfsdk:"server_status"`
+	// UyuniSystemNames maps the servers registered in Uyuni under another name than their
+	// minion ID to that name.
+	UyuniSystemNames types.Map `tfsdk:"uyuni_system_names"`
+}
+
+func (r *GrainServersResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_grain_servers"
+}
+
+func (r *GrainServersResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt Minion grain with a string value, set on many servers at once. Up to the provider's `parallelism` servers are worked on at the same time. Servers which failed are recorded in `server_status` and retried on the next apply, the others are left alone.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "`grain_key` and a hash of the `servers` the resource was created with, telling apart the resources setting the same grain on different servers.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"servers": schema.SetAttribute{
+				MarkdownDescription: "SSH addresses of the minions holding the grain.",
+				ElementType:         types.StringType,
+				Required:            true,
+			},
+			"grain_key": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"grain_value": schema.StringAttribute{
+				Required: true,
+			},
+			"delimiter": schema.StringAttribute{
+				MarkdownDescription: "Delimiter of the levels of a nested `grain_key`, used to read, write and remove the grain. Defaults to the provider's `default_delimiter`, or `:` as in Salt.",
+				Optional:            true,
+			},
+			"keep_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Leave the grain on the minions when the resource is destroyed or a server is removed from `servers`.",
+				Optional:            true,
+			},
+			"uyuni_system_names": schema.MapAttribute{
+				MarkdownDescription: "Names of the systems in Uyuni keyed by their address in `servers`, used for the salt-key acceptance checks. Set it for the servers whose minion ID differs from the address, e.g. a short name while SSH needs the FQDN. Servers not listed default to the host part of their address.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"server_status": schema.MapAttribute{
+				MarkdownDescription: "Outcome of the last apply per server, `ok` or the error the server failed with.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *GrainServersResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+	r.grainItems = data.GrainItems
+	r.defaultDelimiter = data.DefaultDelimiter
+	r.parallelism = data.Parallelism
+}
+
+func (r *GrainServersResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data GrainServersResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = redactValues(ctx, data.GrainValue.ValueString())
+
+	servers := setServers(data.Servers)
+	failed := forEachServer(servers, r.parallelism, func(server string) error {
+		return r.setGrain(ctx, server, data)
+	})
+	r.setServerStatus(ctx, &data, serverStatus(servers, failed), &resp.Diagnostics)
+
+	data.Id = types.StringValue(grainServersID(data.GrainKey.ValueString(), servers))
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainServersResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data GrainServersResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = redactValues(ctx, data.GrainValue.ValueString())
+
+	status := map[string]string{}
+	resp.Diagnostics.Append(data.ServerStatus.ElementsAs(ctx, &status, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// failed servers are retried anyway, only the converged ones are checked for drift
+	var converged []string
+	for server, current := range status {
+		if current == grainServerOK {
+			converged = append(converged, server)
+		}
+	}
+
+	failed := forEachServer(converged, r.parallelism, func(server string) error {
+		value, found, err := r.readGrain(ctx, server, data)
+		switch {
+		case err != nil:
+			return fmt.Errorf("cannot read the grain: %s", err)
+		case !found:
+			return fmt.Errorf("grain is missing on the minion")
+		case value != data.GrainValue.ValueString():
+			return fmt.Errorf("grain was changed on the minion")
+		}
+		return nil
+	})
+	for server, err := range failed {
+		status[server] = err.Error()
+	}
+
+	mapVal, diags := types.MapValueFrom(ctx, types.StringType, status)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ServerStatus = mapVal
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainServersResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data, state GrainServersResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = redactValues(ctx, data.GrainValue.ValueString(), state.GrainValue.ValueString())
+
+	previous := map[string]string{}
+	resp.Diagnostics.Append(state.ServerStatus.ElementsAs(ctx, &previous, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	servers := setServers(data.Servers)
+	unchanged := data.GrainValue.Equal(state.GrainValue) && data.Delimiter.Equal(state.Delimiter)
+
+	var pending []string
+	for _, server := range servers {
+		if !unchanged || previous[server] != grainServerOK {
+			pending = append(pending, server)
+		}
+	}
+	failed := forEachServer(pending, r.parallelism, func(server string) error {
+		return r.setGrain(ctx, server, data)
+	})
+
+	var removed []string
+	for _, server := range setServers(state.Servers) {
+		if !slices.Contains(servers, server) && !state.KeepOnDestroy.ValueBool() {
+			removed = append(removed, server)
+		}
+	}
+	notDeleted := forEachServer(removed, r.parallelism, func(server string) error {
+		return r.deleteGrain(ctx, server, state)
+	})
+	if len(notDeleted) > 0 {
+		resp.Diagnostics.AddWarning(
+			"Cannot delete the grain from removed servers",
+			fmt.Sprintf("cannot delete the grain %s from servers removed from servers, delete it by hand:\n%s", state.GrainKey.ValueString(), serverErrors(notDeleted)),
+		)
+	}
+
+	r.setServerStatus(ctx, &data, serverStatus(servers, failed), &resp.Diagnostics)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainServersResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data GrainServersResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.KeepOnDestroy.ValueBool() {
+		tflog.Info(ctx, fmt.Sprintf("keep_on_destroy is set, leaving grain %s on the servers", data.GrainKey.ValueString()))
+		return
+	}
+
+	failed := forEachServer(setServers(data.Servers), r.parallelism, func(server string) error {
+		return r.deleteGrain(ctx, server, data)
+	})
+	if len(failed) > 0 {
+		resp.Diagnostics.AddError(
+			"Cannot delete the grain",
+			fmt.Sprintf("cannot delete the grain %s on %d servers:\n%s", data.GrainKey.ValueString(), len(failed), serverErrors(failed)),
+		)
+	}
+}
+
+func (r *GrainServersResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		grainKeyValidator{},
+		serverAddressValidator{set: true},
+	}
+}
+
+// ModifyPlan plans an update while any server has not converged, so the next apply retries it.
+func (r *GrainServersResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var state GrainServersResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, current := range state.ServerStatus.Elements() {
+		if str, ok := current.(types.String); ok && str.ValueString() != grainServerOK {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("server_status"), types.MapUnknown(types.StringType))...)
+			return
+		}
+	}
+}
+
+// setServerStatus records status in data and warns about the servers which failed.
+func (r *GrainServersResource) setServerStatus(ctx context.Context, data *GrainServersResourceModel, status map[string]string, diags *diag.Diagnostics) {
+	var failed []string
+	for server, current := range status {
+		if current != grainServerOK {
+			failed = append(failed, fmt.Sprintf("%s: %s", server, current))
+		}
+	}
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		diags.AddWarning(
+			"Grain not set on all servers",
+			fmt.Sprintf("the grain %s could not be set on %d of %d servers, they are retried on the next apply:\n%s", data.GrainKey.ValueString(), len(failed), len(status), strings.Join(failed, "\n")),
+		)
+	}
+
+	mapVal, d := types.MapValueFrom(ctx, types.StringType, status)
+	diags.Append(d...)
+	data.ServerStatus = mapVal
+}
+
+// setGrain sets the grain of data on server.
+func (r *GrainServersResource) setGrain(ctx context.Context, server string, data GrainServersResourceModel) error {
+	defer r.serverLocks.lock(server)()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(server)
+
+	if err := r.waitMinionIsUp(ctx, nil, data.systemName(server)); err != nil {
+		return fmt.Errorf("failed to wait for the minion to be up: %s", err)
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, server, data.systemName(server))
+	if err != nil {
+		return err
+	}
+
+	runCommand := minion.Command("grains.set", setGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), saltclient.StringArg(data.GrainValue.ValueString()))...)
+	_, err = r.transport.Run(mutating(ctx), server, runCommand, 0)
+	return err
+}
+
+// readGrain returns the value of the grain of data on server, and whether it is set at all.
+func (r *GrainServersResource) readGrain(ctx context.Context, server string, data GrainServersResourceModel) (string, bool, error) {
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, server, data.systemName(server))
+	if err != nil {
+		return "", false, err
+	}
+
+	readGrain, err := r.grainItems.get(ctx, r.transport, minion, server, data.GrainKey.ValueString(), inherited(data.Delimiter, r.defaultDelimiter))
+	if err != nil {
+		return "", false, err
+	}
+
+	var live struct {
+		Value *string `json:"local"`
+	}
+	if err := json.Unmarshal(saltJSON(readGrain), &live); err != nil {
+		return "", false, fmt.Errorf("cannot parse the grain value: %s", err)
+	}
+	if live.Value == nil || *live.Value == "" {
+		return "", false, nil
+	}
+	return *live.Value, true, nil
+}
+
+// deleteGrain removes the grain of data from server.
+func (r *GrainServersResource) deleteGrain(ctx context.Context, server string, data GrainServersResourceModel) error {
+	defer r.serverLocks.lock(server)()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(server)
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, server, data.systemName(server))
+	if err != nil {
+		return err
+	}
+
+	runCommand := minion.Command("grains.set", deleteGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	_, err = r.transport.Run(mutating(ctx), server, runCommand, 0)
+	return err
+}
+
+// grainServersID returns the ID of the grain grainKey set on servers, sorted as by setServers.
+func grainServersID(grainKey string, servers []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(servers, "\n")))
+	return grainKey + grainIDSeparator + hex.EncodeToString(sum[:8])
+}
+
+// systemName returns the name server is registered under in Uyuni.
+func (data GrainServersResourceModel) systemName(server string) string {
+	name, _ := data.UyuniSystemNames.Elements()[server].(types.String)
+	return uyuniSystemName(server, name)
+}
+
+// serverStatus returns the server_status of servers, ok unless they failed.
+func serverStatus(servers []string, failed map[string]error) map[string]string {
+	status := map[string]string{}
+	for _, server := range servers {
+		status[server] = grainServerOK
+		if err, ok := failed[server]; ok {
+			status[server] = err.Error()
+		}
+	}
+	return status
+}
+
+// setServers returns the servers of a servers attribute in a stable order.
+func setServers(servers types.Set) []string {
+	result := setStrings(servers)
+	slices.Sort(result)
+	return result
+}
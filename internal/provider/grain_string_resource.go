@@ -6,19 +6,24 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"golang.org/x/crypto/ssh"
-	"time"
+	"terraform-provider-salty/internal/saltclient"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &GrainStringResource{}
+var _ resource.ResourceWithUpgradeState = &GrainStringResource{}
 var _ resource.ResourceWithImportState = &GrainStringResource{}
+var _ resource.ResourceWithModifyPlan = &GrainStringResource{}
+var _ resource.ResourceWithConfigValidators = &GrainStringResource{}
 
 func NewGrainStringResource() resource.Resource {
 	return &GrainStringResource{}
@@ -26,20 +31,53 @@ func NewGrainStringResource() resource.Resource {
 
 // GrainResource defines the resource implementation.
 type GrainStringResource struct {
-	username      *string
-	privateKey    *string
-	uyuniBaseURL  *string
-	uyuniUsername *string
-	uyuniPassword *string
+	minionConnection
+	uyuniGrainFallback bool
+	grainItems         *grainItemsCache
+	unreachable        *unreachableServers
+	defaultDelimiter   string
+	planPreview        bool
 }
 
 // GrainResourceModel describes the resource data model.
 type GrainStringResourceModel struct {
-	Id         types.String `tfsdk:"id"`
-	Server     types.String `tfsdk:"server"`
-	GrainKey   types.String `tfsdk:"grain_key"`
-	GrainValue types.String `tfsdk:"grain_value"`
-	ApplyState types.Bool   `tfsdk:"apply_state"`
+	Id                    types.String        `tfsdk:"id"`
+	Server                types.String        `tfsdk:"server"`
+	UyuniSystemName       types.String        `tfsdk:"uyuni_system_name"`
+	Uyuni                 *uyuniEndpointModel `tfsdk:"uyuni"`
+	MinionId              types.String        `tfsdk:"minion_id"`
+	GrainKey              types.String        `tfsdk:"grain_key"`
+	GrainValue            types.String        `tfsdk:"grain_value"`
+	ApplyState            types.Bool          `tfsdk:"apply_state"`
+	SyncBeforeApply       types.Bool          `tfsdk:"sync_before_apply"`
+	KeepOnDestroy         types.Bool          `tfsdk:"keep_on_destroy"`
+	PreCommand            types.String        `tfsdk:"pre_command"`
+	PostCommand           types.String        `tfsdk:"post_command"`
+	HookOnFailure         types.String        `tfsdk:"hook_on_failure"`
+	Delimiter             types.String        `tfsdk:"delimiter"`
+	OnUnreachable         types.String        `tfsdk:"on_unreachable"`
+	Saltenv               types.String        `tfsdk:"saltenv"`
+	Pillar                types.Map           `tfsdk:"pillar"`
+	ApplyStateFailureMode types.String        `tfsdk:"apply_state_failure_mode"`
+	StatesChanged         types.Int64         `tfsdk:"states_changed"`
+	StatesFailed          types.Int64         `tfsdk:"states_failed"`
+	DurationSeconds       types.Float64       `tfsdk:"duration_seconds"`
+	Protect               types.Bool          `tfsdk:"protect"`
+	AllowedValues         types.List          `tfsdk:"allowed_values"`
+	ValueRegex            types.String        `tfsdk:"value_regex"`
+}
+
+// stateApply returns the state.apply run configured by data.
+func (data GrainStringResourceModel) stateApply() stateApply {
+	return stateApply{
+		Server:          data.Server.ValueString(),
+		SystemName:      uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName),
+		Uyuni:           data.Uyuni,
+		SyncBeforeApply: data.SyncBeforeApply.ValueBool(),
+		Saltenv:         data.Saltenv,
+		Pillar:          data.Pillar,
+		FailureMode:     data.ApplyStateFailureMode,
+	}
 }
 
 type SaltGrainStringModel struct {
@@ -52,16 +90,31 @@ func (r *GrainStringResource) Metadata(ctx context.Context, req resource.Metadat
 
 func (r *GrainStringResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: 3,
+
 		// This description is used by the documentation generator and the language server.
 		MarkdownDescription: "Salt Grain resource (string)",
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				Computed: true,
+				MarkdownDescription: "`server|grain_key`, also the ID to import the grain with, e.g. `web01.example.com|roles`.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
 			"server": schema.StringAttribute{
 				Required: true,
 			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks and by `uyuni_grain_read_fallback`"),
+			"uyuni":             uyuniEndpointAttribute(),
+			"minion_id": schema.StringAttribute{
+				MarkdownDescription: "Salt minion ID of `server`, read from its `id` grain. It often differs from the SSH address.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
 			"grain_key": schema.StringAttribute{
 				Required: true,
 			},
@@ -71,10 +124,85 @@ func (r *GrainStringResource) Schema(ctx context.Context, req resource.SchemaReq
 			"apply_state": schema.BoolAttribute{
 				Required: true,
 			},
+			"sync_before_apply": schema.BoolAttribute{
+				MarkdownDescription: "Run `saltutil.sync_all` before `state.apply` so freshly pushed custom modules and states are used.",
+				Optional:            true,
+			},
+			"delimiter": schema.StringAttribute{
+				MarkdownDescription: "Delimiter of the levels of a nested `grain_key`, used to read, write and remove the grain. Defaults to the provider's `default_delimiter`, or `:` as in Salt.",
+				Optional:            true,
+			},
+			"on_unreachable": onUnreachableAttribute(),
+			"saltenv": schema.StringAttribute{
+				MarkdownDescription: "Salt environment of the `state.apply` run of `apply_state`, e.g. `staging` to exercise the states of a non-base environment. Defaults to the provider's `default_saltenv`.",
+				Optional:            true,
+			},
+			"pillar": schema.MapAttribute{
+				MarkdownDescription: "Pillar data passed to the `state.apply` run as `pillar='{...}'`, overriding the pillar of the minion for this run only.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"apply_state_failure_mode": schema.StringAttribute{
+				MarkdownDescription: "What failed states in the `state.apply` run do: `error` fails the operation, `warn` only reports them as a warning and `ignore` just logs the result, e.g. while a new state is still being worked on. Defaults to `error`.",
+				Optional:            true,
+			},
+			"states_changed": schema.Int64Attribute{
+				MarkdownDescription: "Number of states with changes in the `state.apply` run of the last create or update. Null when `apply_state` is not set.",
+				Computed:            true,
+			},
+			"states_failed": schema.Int64Attribute{
+				MarkdownDescription: "Number of failed states in the `state.apply` run of the last create or update.",
+				Computed:            true,
+			},
+			"duration_seconds": schema.Float64Attribute{
+				MarkdownDescription: "Sum of the state durations of the `state.apply` run of the last create or update, in seconds.",
+				Computed:            true,
+			},
+			"pre_command": schema.StringAttribute{
+				MarkdownDescription: "Shell command run on the server before the grain is changed, e.g. to drain it from a load balancer. The grain is left alone when it fails, unless `hook_on_failure` is `continue`.",
+				Optional:            true,
+			},
+			"post_command": schema.StringAttribute{
+				MarkdownDescription: "Shell command run on the server once the grain was changed and the state applied, e.g. to restart a service or put the server back into its load balancer.",
+				Optional:            true,
+			},
+			"hook_on_failure": schema.StringAttribute{
+				MarkdownDescription: "What a failing `pre_command` or `post_command` does, `fail` the operation or `continue` with a warning. Defaults to `fail`.",
+				Optional:            true,
+			},
+			"keep_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Leave the grain on the minion when the resource is destroyed, e.g. when handing its management over to another system.",
+				Optional:            true,
+			},
+			"protect": schema.BoolAttribute{
+				MarkdownDescription: "Refuse to destroy the grain or change its value until the flag is removed.",
+				Optional:            true,
+			},
+			"allowed_values": schema.ListAttribute{
+				MarkdownDescription: "Values `grain_value` may be set to. Checked at plan time.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"value_regex": schema.StringAttribute{
+				MarkdownDescription: "Regular expression `grain_value` must match. Checked at plan time.",
+				Optional:            true,
+			},
 		},
 	}
 }
 
+// UpgradeState migrates states written with earlier schema versions.
+func (r *GrainStringResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return stateUpgraders(
+		// 0 -> 1: schema versioning introduced, attributes unchanged
+		unchangedState,
+		// 1 -> 2: grain keys and values are quoted explicitly, the state never held the quotes
+		unchangedState,
+		// 2 -> 3: the ID separates the server from the grain key with a pipe
+		structuredGrainIDState,
+	)
+}
+
 func (r *GrainStringResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
@@ -93,14 +221,18 @@ func (r *GrainStringResource) Configure(ctx context.Context, req resource.Config
 		return
 	}
 
-	r.username = &data.Username
-	r.privateKey = &data.PrivateKey
-	r.uyuniBaseURL = &data.UyuniBaseURL
-	r.uyuniUsername = &data.UyuniUsername
-	r.uyuniPassword = &data.UyuniPassword
+	r.minionConnection.configure(data)
+	r.uyuniGrainFallback = data.UyuniGrainReadFallback
+	r.grainItems = data.GrainItems
+	r.unreachable = data.Unreachable
+	r.defaultDelimiter = data.DefaultDelimiter
+	r.planPreview = data.PlanPreview
 }
 
 func (r *GrainStringResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
 	var data GrainStringResourceModel
 
 	// Read Terraform plan data into the model
@@ -110,7 +242,14 @@ func (r *GrainStringResource) Create(ctx context.Context, req resource.CreateReq
 		return
 	}
 
-	err := r.waitMinionIsUp(ctx, data)
+	ctx = redactValues(ctx, data.GrainValue.ValueString())
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"failed to wait for the minion to be up",
@@ -119,8 +258,33 @@ func (r *GrainStringResource) Create(ctx context.Context, req resource.CreateReq
 		return
 	}
 
-	runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.setval %s %s", data.GrainKey.String(), data.GrainValue.String())
-	_, err = r.runRemoteCommand(runCommand, ctx, data)
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	if data.MinionId.IsUnknown() {
+		minionId, err := r.grainItems.saltMinionID(ctx, r.transport, minion, data.Server.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Cannot read the Salt minion ID",
+				fmt.Sprintf("cannot read the id grain on the Salt Minion %s: %s", data.Server.ValueString(), err),
+			)
+			return
+		}
+		data.MinionId = types.StringValue(minionId)
+	}
+
+	if !runHook(ctx, r.transport, data.Server.ValueString(), "pre_command", data.PreCommand, data.HookOnFailure, &resp.Diagnostics) {
+		return
+	}
+
+	runCommand := minion.Command("grains.set", setGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), saltclient.StringArg(data.GrainValue.ValueString()))...)
+	_, err = r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Cannot create the grain value on the Salt Minion",
@@ -131,34 +295,38 @@ func (r *GrainStringResource) Create(ctx context.Context, req resource.CreateReq
 
 	// For the purposes of this example code, hardcoding a response value to
 	// save into the Terraform state.
-	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.GrainKey.ValueString()))
+	data.Id = types.StringValue(grainID(data.Server.ValueString(), data.GrainKey.ValueString()))
 
-	// Write logs using the tflog package
-	// Documentation: https://terraform.io/plugin/log
-	tflog.Info(ctx, "created a resource")
-	b, _ := json.MarshalIndent(data, "", "    ")
-	tflog.Info(ctx, string(b))
+	tflog.Info(ctx, "created the grain", map[string]any{logFieldServer: data.Server.ValueString(), logFieldGrainKey: data.GrainKey.ValueString()})
 
+	var summary *highstateSummary
 	if data.ApplyState.ValueBool() {
-		applyResult, err := r.applyState(ctx, data)
+		applied, err := r.applyState(ctx, data.stateApply())
 		if err != nil {
 			resp.Diagnostics.AddError(
 				err.Error(),
 				err.Error())
+			// the grain is already written, keep it managed instead of orphaning it
+			data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			return
 		}
-		resp.Diagnostics.AddWarning("state apply result", applyResult)
-		if resp.Diagnostics.HasError() {
+		summary = applied
+		if !reportHighstate(ctx, data.Server.ValueString(), data.ApplyStateFailureMode, applied, &resp.Diagnostics) {
+			data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 			return
 		}
 	}
+	data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+
+	runHook(ctx, r.transport, data.Server.ValueString(), "post_command", data.PostCommand, data.HookOnFailure, &resp.Diagnostics)
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *GrainStringResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	tflog.Info(ctx, "READ called here")
-
 	var data GrainStringResourceModel
 
 	diags := req.State.Get(ctx, &data)
@@ -169,7 +337,13 @@ func (r *GrainStringResource) Read(ctx context.Context, req resource.ReadRequest
 		return
 	}
 
-	err := r.waitMinionIsUp(ctx, data)
+	if unreachableRead(ctx, r.transport, r.unreachable, data.Server.ValueString(), data.OnUnreachable, resp) {
+		return
+	}
+
+	ctx = redactValues(ctx, data.GrainValue.ValueString())
+
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"failed to wait for the minion to be up",
@@ -178,8 +352,29 @@ func (r *GrainStringResource) Read(ctx context.Context, req resource.ReadRequest
 		return
 	}
 
-	runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.get %s --out=json", data.GrainKey.String())
-	readGrain, err := r.runRemoteCommand(runCommand, ctx, data)
+	var readGrain, minionId string
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err == nil {
+		readGrain, err = r.grainItems.get(ctx, r.transport, minion, data.Server.ValueString(), data.GrainKey.ValueString(), inherited(data.Delimiter, r.defaultDelimiter))
+	}
+	if err == nil {
+		minionId, err = r.grainItems.saltMinionID(ctx, r.transport, minion, data.Server.ValueString())
+	}
+	if err != nil && r.uyuniGrainFallback {
+		resp.Diagnostics.AddWarning(
+			"Grain read through Uyuni",
+			fmt.Sprintf("cannot read the grain value on the Salt Minion %s over SSH, falling back to Uyuni: %s", data.Server.ValueString(), err),
+		)
+		readGrain, err = r.uyuni.withEndpoint(data.Uyuni).ReadGrain(ctx, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName), data.GrainKey.ValueString())
+		if errors.Is(err, saltclient.ErrUyuniGrainNotStored) {
+			// nothing known about the grain, an empty value would plan to write it all again
+			resp.Diagnostics.AddWarning(
+				"Grain not stored in Uyuni",
+				fmt.Sprintf("keeping the grain values of the Salt Minion %s from state: %s", data.Server.ValueString(), err),
+			)
+			return
+		}
+	}
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Cannot create the grain value on the Salt Minion",
@@ -190,14 +385,22 @@ func (r *GrainStringResource) Read(ctx context.Context, req resource.ReadRequest
 		return
 	}
 
-	tflog.Info(ctx, "readGrain, raw JSON:")
-	tflog.Info(ctx, readGrain)
+	// a grain moved from salty_grain holds a list until the next apply converts it
+	if movedGrain(ctx, req.Private) {
+		readGrain, err = listGrainAsString(readGrain)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("grain_key"),
+				"Grain type mismatch",
+				fmt.Sprintf("cannot take over the grain %s on the Salt Minion %s moved from salty_grain: %s", data.GrainKey.ValueString(), data.Server.ValueString(), err),
+			)
+			return
+		}
+	}
 
 	liveGrains := SaltGrainStringModel{}
-	_ = json.Unmarshal([]byte(readGrain), &liveGrains)
-
-	tflog.Info(ctx, "decoded grains from JSON:")
-	tflog.Info(ctx, liveGrains.Value)
+	_ = json.Unmarshal(saltJSON(readGrain), &liveGrains)
+	ctx = redactValues(ctx, liveGrains.Value)
 
 	// if liveGrains.Value == nil {
 	//	liveGrains.Value = ""
@@ -206,11 +409,14 @@ func (r *GrainStringResource) Read(ctx context.Context, req resource.ReadRequest
 	strVal := types.StringValue(liveGrains.Value)
 	data.GrainValue = strVal
 
-	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.GrainKey.ValueString()))
+	// the Uyuni fallback does not tell the minion ID, keep the known one then
+	if minionId != "" {
+		data.MinionId = types.StringValue(minionId)
+	}
+
+	data.Id = types.StringValue(grainID(data.Server.ValueString(), data.GrainKey.ValueString()))
 
-	tflog.Info(ctx, "read a resource")
-	b, _ := json.MarshalIndent(data, "", "    ")
-	tflog.Info(ctx, string(b))
+	tflog.Debug(ctx, "read the grain", map[string]any{logFieldServer: data.Server.ValueString(), logFieldGrainKey: data.GrainKey.ValueString()})
 
 	diags = resp.State.Set(ctx, &data)
 	resp.Diagnostics.Append(diags...)
@@ -220,6 +426,9 @@ func (r *GrainStringResource) Read(ctx context.Context, req resource.ReadRequest
 }
 
 func (r *GrainStringResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
 	var data GrainStringResourceModel
 
 	// Read Terraform plan data into the model
@@ -229,7 +438,14 @@ func (r *GrainStringResource) Update(ctx context.Context, req resource.UpdateReq
 		return
 	}
 
-	err := r.waitMinionIsUp(ctx, data)
+	ctx = redactValues(ctx, data.GrainValue.ValueString())
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"failed to wait for the minion to be up",
@@ -238,35 +454,100 @@ func (r *GrainStringResource) Update(ctx context.Context, req resource.UpdateReq
 		return
 	}
 
-	runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.setval %s %s --out=json", data.GrainKey.String(), data.GrainValue.String())
-	tflog.Info(ctx, runCommand)
-	setGrain, err := r.runRemoteCommand(runCommand, ctx, data)
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	if data.MinionId.IsUnknown() {
+		minionId, err := r.grainItems.saltMinionID(ctx, r.transport, minion, data.Server.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Cannot read the Salt minion ID",
+				fmt.Sprintf("cannot read the id grain on the Salt Minion %s: %s", data.Server.ValueString(), err),
+			)
+			return
+		}
+		data.MinionId = types.StringValue(minionId)
+	}
+
+	data.Id = types.StringValue(grainID(data.Server.ValueString(), data.GrainKey.ValueString()))
+
+	// skip the write (and the highstate it would trigger) when the minion already has the value
+	runCommand := minion.Command("grains.get", grainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	readGrain, err := r.transport.Run(ctx, data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot get the grain value on the Salt Minion",
+			fmt.Sprintf("cannot get the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	// the list grain of a moved salty_grain is written as a string even when it holds the value
+	converting := movedGrain(ctx, req.Private)
+	if converting {
+		if _, err := listGrainAsString(readGrain); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("grain_key"),
+				"Grain type mismatch",
+				fmt.Sprintf("cannot take over the grain %s on the Salt Minion %s moved from salty_grain: %s", data.GrainKey.ValueString(), data.Server.ValueString(), err),
+			)
+			return
+		}
+	}
+
+	liveGrains := SaltGrainStringModel{}
+	if err := json.Unmarshal(saltJSON(readGrain), &liveGrains); err == nil && !converting && liveGrains.Value == data.GrainValue.ValueString() {
+		tflog.Info(ctx, fmt.Sprintf("grain %s on %s already has the planned value, skipping the update", data.GrainKey.ValueString(), data.Server.ValueString()))
+		data.StatesChanged, data.StatesFailed, data.DurationSeconds = types.Int64Null(), types.Int64Null(), types.Float64Null()
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	if !runHook(ctx, r.transport, data.Server.ValueString(), "pre_command", data.PreCommand, data.HookOnFailure, &resp.Diagnostics) {
+		return
+	}
+
+	runCommand = minion.Command("grains.set", setGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString(), saltclient.StringArg(data.GrainValue.ValueString()))...)
+	_, err = r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Cannot append the grain value on the Salt Minion",
 			fmt.Sprintf("cannot append the grain value on theSalt Minion %s: %s", data.Server.ValueString(), err),
 		)
 	}
-	tflog.Info(ctx, setGrain)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, grainConversionKey, nil)...)
 
+	var summary *highstateSummary
 	if data.ApplyState.ValueBool() {
-		applyResult, err := r.applyState(ctx, data)
+		applied, err := r.applyState(ctx, data.stateApply())
 		if err != nil {
 			resp.Diagnostics.AddError(
 				err.Error(),
 				err.Error())
+			// the grain is already written, keep it managed instead of orphaning it
+			data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			return
 		}
-		resp.Diagnostics.AddWarning("apply state result", applyResult)
-		if resp.Diagnostics.HasError() {
+		summary = applied
+		if !reportHighstate(ctx, data.Server.ValueString(), data.ApplyStateFailureMode, applied, &resp.Diagnostics) {
+			data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 			return
 		}
-
 	}
+	data.StatesChanged, data.StatesFailed, data.DurationSeconds = summary.values()
 
-	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.GrainKey.ValueString()))
+	runHook(ctx, r.transport, data.Server.ValueString(), "post_command", data.PostCommand, data.HookOnFailure, &resp.Diagnostics)
 
 	diags := resp.State.Set(ctx, &data)
 	resp.Diagnostics.Append(diags...)
@@ -276,6 +557,9 @@ func (r *GrainStringResource) Update(ctx context.Context, req resource.UpdateReq
 }
 
 func (r *GrainStringResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
 	var data GrainStringResourceModel
 
 	// Read Terraform prior state data into the model
@@ -286,13 +570,21 @@ func (r *GrainStringResource) Delete(ctx context.Context, req resource.DeleteReq
 		return
 	}
 
-	tflog.Info(ctx, "DELETE - Data from the state: ")
-	tflog.Info(ctx, data.Server.String())
-	tflog.Info(ctx, data.Id.String())
-	tflog.Info(ctx, data.GrainKey.String())
-	tflog.Info(ctx, data.GrainValue.String())
+	ctx = redactValues(ctx, data.GrainValue.ValueString())
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	if data.KeepOnDestroy.ValueBool() {
+		tflog.Info(ctx, fmt.Sprintf("keep_on_destroy is set, leaving grain %s on %s", data.GrainKey.ValueString(), data.Server.ValueString()))
+		return
+	}
+
+	tflog.Debug(ctx, "deleting the grain", map[string]any{logFieldServer: data.Server.ValueString(), logFieldGrainKey: data.GrainKey.ValueString()})
 
-	err := r.waitMinionIsUp(ctx, data)
+	err := r.waitMinionIsUp(ctx, data.Uyuni, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"failed to wait for the minion to be up",
@@ -301,8 +593,21 @@ func (r *GrainStringResource) Delete(ctx context.Context, req resource.DeleteReq
 		return
 	}
 
-	runCommand := fmt.Sprintf("/usr/lib/venv-salt-minion/bin/salt-call grains.delkey %s --out=json", data.GrainKey.String())
-	_, err = r.runRemoteCommand(runCommand, ctx, data)
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni.withEndpoint(data.Uyuni), data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	if !runHook(ctx, r.transport, data.Server.ValueString(), "pre_command", data.PreCommand, data.HookOnFailure, &resp.Diagnostics) {
+		return
+	}
+
+	runCommand := minion.Command("grains.set", deleteGrainArgs(inherited(data.Delimiter, r.defaultDelimiter), data.GrainKey.ValueString())...)
+	_, err = r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			err.Error(),
@@ -311,90 +616,101 @@ func (r *GrainStringResource) Delete(ctx context.Context, req resource.DeleteReq
 	}
 
 	if data.ApplyState.ValueBool() {
-		applyResult, err := r.applyState(ctx, data)
+		applied, err := r.applyState(ctx, data.stateApply())
 		if err != nil {
 			resp.Diagnostics.AddError(
 				err.Error(),
 				err.Error())
+			return
 		}
-		resp.Diagnostics.AddWarning("apply state result", applyResult)
-		if resp.Diagnostics.HasError() {
+		if !reportHighstate(ctx, data.Server.ValueString(), data.ApplyStateFailureMode, applied, &resp.Diagnostics) {
 			return
 		}
-
 	}
-}
 
-func (r *GrainStringResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
-}
-
-func (r *GrainStringResource) applyState(ctx context.Context, data GrainStringResourceModel) (string, error) {
-	runCommand := "while true; do found=0; for f in /var/cache/venv-salt-minion/proc/*; do grep state.apply $f; if [ $? -eq 0 ]; then found=1; fi; done; if [ $found -eq 0 ]; then break; fi; sleep 1; done; /usr/lib/venv-salt-minion/bin/salt-call state.apply >> /var/log/state.apply.tf.log 2>&1"
-	applyStateResult, err := r.runRemoteCommand(runCommand, ctx, data)
-	if err != nil {
-		return "", fmt.Errorf("cannot apply state: %s", err.Error())
-	}
-
-	return applyStateResult, nil
+	runHook(ctx, r.transport, data.Server.ValueString(), "post_command", data.PostCommand, data.HookOnFailure, &resp.Diagnostics)
 }
 
-func (r *GrainStringResource) runRemoteCommand(runCommand string, ctx context.Context, data GrainStringResourceModel) (string, error) {
-	signer, err := ssh.ParsePrivateKey([]byte(*r.privateKey))
-	if err != nil {
-		return "", fmt.Errorf("malformed private key: %s, please report this issue to the provider developers", err)
-	}
-
-	config := &ssh.ClientConfig{
-		User: *r.username,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
-
-	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:22", data.Server.ValueString()), config)
-	if err != nil {
-		return "", fmt.Errorf("cannot connect to the Salt Minion %s: %s", data.Server.ValueString(), err)
+func (r *GrainStringResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		var state GrainStringResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if state.Protect.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Grain is protected",
+				fmt.Sprintf("grain %s on the Salt Minion %s has protect = true, remove the flag before destroying it", state.GrainKey.ValueString(), state.Server.ValueString()),
+			)
+		}
+		return
 	}
 
-	session, err := client.NewSession()
-	if err != nil {
-		return "", fmt.Errorf("cannot create session with the Salt Minion %s: %s", data.Server.ValueString(), err)
+	var server types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("server"), &server)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	tflog.Info(ctx, runCommand)
-	cmdOutput, err := session.Output(runCommand)
-	tflog.Info(ctx, string(cmdOutput))
-
-	if err != nil {
-		return "", fmt.Errorf("cannot run the command %s on Salt Minion %s: %s", runCommand, data.Server.ValueString(), err)
+	// the server usually comes from a compute resource created in the same run,
+	// defer the grain until its address is known instead of planning blindly
+	if server.IsUnknown() && req.ClientCapabilities.DeferralAllowed {
+		tflog.Info(ctx, "deferring the grain until the server is known")
+		resp.Deferred = &resource.Deferred{
+			Reason: resource.DeferredReasonResourceConfigUnknown,
+		}
+		return
 	}
 
-	return string(cmdOutput), nil
-}
-
-func (r *GrainStringResource) waitMinionIsUp(ctx context.Context, data GrainStringResourceModel) error {
-	timeout := 30 * time.Minute
-	deadline := time.Now().Add(timeout)
-
-	tflog.Info(ctx, "starting to wait for the minion to be up")
+	// the flag guards against overwriting the value of a protected grain as well
+	if !req.State.Raw.IsNull() {
+		var plan, state GrainStringResourceModel
+		resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
 
-	for {
-		if time.Now().After(deadline) {
-			return fmt.Errorf("timeout reached after %d minutes; salt-key for %s not accepted", timeout, data.Server.ValueString())
+		// another server may be another minion, its ID is read again
+		if !plan.Server.Equal(state.Server) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("minion_id"), types.StringUnknown())...)
 		}
 
-		found, err := CheckServerAccepted(*r.uyuniBaseURL, *r.uyuniUsername, *r.uyuniPassword, data.Server.ValueString())
-		if err != nil {
-			return fmt.Errorf("error checking salt-key acceptance of %s: %s", data.Server.ValueString(), err)
+		// the update converting a moved grain reads the minion ID again
+		if movedGrain(ctx, req.Private) && len(resp.RequiresReplace) == 0 {
+			resp.Diagnostics.AddWarning(
+				"Grain conversion pending",
+				fmt.Sprintf("the grain %s on the Salt Minion %s was moved from salty_grain, this apply rewrites the list as a string", plan.GrainKey.ValueString(), plan.Server.ValueString()),
+			)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("minion_id"), types.StringUnknown())...)
 		}
 
-		tflog.Info(ctx, fmt.Sprintf("called checkServerAccepted with result: %v, error: %s", found, err))
+		if plan.Protect.ValueBool() && !plan.GrainValue.IsUnknown() && !plan.GrainValue.Equal(state.GrainValue) {
+			resp.Diagnostics.AddError(
+				"Grain is protected",
+				fmt.Sprintf("grain %s on the Salt Minion %s has protect = true, remove the flag before changing its value", plan.GrainKey.ValueString(), plan.Server.ValueString()),
+			)
+		}
+	}
 
-		if found {
-			return nil
+	if r.planPreview && !req.Plan.Raw.Equal(req.State.Raw) {
+		var plan GrainStringResourceModel
+		resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+		if !resp.Diagnostics.HasError() {
+			r.previewPlan(ctx, plan, &resp.Diagnostics)
 		}
-		time.Sleep(10 * time.Second)
 	}
 }
+
+func (r *GrainStringResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		grainValueValidator{list: false},
+		grainKeyValidator{},
+		serverAddressValidator{},
+		hookOnFailureValidator{},
+		applyStateFailureModeValidator{},
+		onUnreachableValidator{},
+	}
+}
+
+func (r *GrainStringResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importGrainState(ctx, req, resp)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"testing"
+)
+
+func TestAccGrainStringResource(t *testing.T) {
+	testAccFixture(t, "grain_string_resource")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccProviderConfig + testAccGrainStringResourceConfig("production"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("salty_grain_string.test", "id", testAccServer+"|environment"),
+					resource.TestCheckResourceAttr("salty_grain_string.test", "minion_id", "minion"),
+					resource.TestCheckResourceAttr("salty_grain_string.test", "grain_value", "production"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "salty_grain_string.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"apply_state"},
+			},
+			// Update and Read testing
+			{
+				Config: testAccProviderConfig + testAccGrainStringResourceConfig("staging"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("salty_grain_string.test", "grain_value", "staging"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func testAccGrainStringResourceConfig(grainValue string) string {
+	return `
+resource "salty_grain_string" "test" {
+  server      = "` + testAccServer + `"
+  grain_key   = "environment"
+  grain_value = "` + grainValue + `"
+  apply_state = false
+}
+`
+}
@@ -0,0 +1,275 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+	"unicode"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ resource.ConfigValidator = grainValueValidator{}
+var _ resource.ConfigValidator = grainKeyValidator{}
+var _ resource.ConfigValidator = serverAddressValidator{}
+
+// hostnamePattern matches DNS names and SSH config host aliases.
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9_])?(\.[A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9_])?)*\.?$`)
+
+// grainValueValidator checks grain_value against allowed_values and value_regex at plan time,
+// before any connection to the minion is made.
+type grainValueValidator struct {
+	// list is set for resources whose grain_value is a list of strings.
+	list bool
+}
+
+func (v grainValueValidator) Description(ctx context.Context) string {
+	return "grain_value must be one of allowed_values and match value_regex"
+}
+
+func (v grainValueValidator) MarkdownDescription(ctx context.Context) string {
+	return "`grain_value` must be one of `allowed_values` and match `value_regex`"
+}
+
+func (v grainValueValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	if v.list {
+		var grainValue types.List
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("grain_value"), &grainValue)...)
+		if !grainValue.IsNull() && !grainValue.IsUnknown() && len(grainValue.Elements()) == 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("grain_value"),
+				"Empty grain value",
+				"grain_value must hold at least one value, destroy the resource to remove the grain.",
+			)
+		}
+	}
+
+	var allowedValues types.List
+	var valueRegex types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("allowed_values"), &allowedValues)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("value_regex"), &valueRegex)...)
+	if resp.Diagnostics.HasError() || allowedValues.IsUnknown() || valueRegex.IsUnknown() {
+		return
+	}
+
+	var allowed []string
+	if !allowedValues.IsNull() {
+		resp.Diagnostics.Append(allowedValues.ElementsAs(ctx, &allowed, true)...)
+	}
+
+	var pattern *regexp.Regexp
+	if !valueRegex.IsNull() {
+		var err error
+		pattern, err = regexp.Compile(valueRegex.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("value_regex"),
+				"Invalid grain value regex",
+				fmt.Sprintf("value_regex is not a valid regular expression: %s", err),
+			)
+			return
+		}
+	}
+
+	if allowed == nil && pattern == nil {
+		return
+	}
+
+	values := map[int]types.String{}
+	if v.list {
+		var grainValue types.List
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("grain_value"), &grainValue)...)
+		if resp.Diagnostics.HasError() || grainValue.IsNull() || grainValue.IsUnknown() {
+			return
+		}
+		for i, element := range grainValue.Elements() {
+			if str, ok := element.(types.String); ok {
+				values[i] = str
+			}
+		}
+	} else {
+		var grainValue types.String
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("grain_value"), &grainValue)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		values[-1] = grainValue
+	}
+
+	for i, value := range values {
+		if value.IsNull() || value.IsUnknown() {
+			continue
+		}
+
+		attribute := path.Root("grain_value")
+		if i >= 0 {
+			attribute = attribute.AtListIndex(i)
+		}
+
+		if allowed != nil && !slices.Contains(allowed, value.ValueString()) {
+			resp.Diagnostics.AddAttributeError(
+				attribute,
+				"Grain value not allowed",
+				fmt.Sprintf("The grain value %q is not one of allowed_values %q.", value.ValueString(), allowed),
+			)
+		}
+		if pattern != nil && !pattern.MatchString(value.ValueString()) {
+			resp.Diagnostics.AddAttributeError(
+				attribute,
+				"Grain value does not match value_regex",
+				fmt.Sprintf("The grain value %q does not match value_regex %q.", value.ValueString(), pattern),
+			)
+		}
+	}
+}
+
+// grainKeyValidator checks grain_key at plan time. A key holding the delimiter addresses a
+// nested grain, as in Salt with its default colon.
+type grainKeyValidator struct{}
+
+func (v grainKeyValidator) Description(ctx context.Context) string {
+	return "grain_key must not be empty or contain whitespace"
+}
+
+func (v grainKeyValidator) MarkdownDescription(ctx context.Context) string {
+	return "`grain_key` must not be empty or contain whitespace"
+}
+
+func (v grainKeyValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var grainKey types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("grain_key"), &grainKey)...)
+	if resp.Diagnostics.HasError() || grainKey.IsNull() || grainKey.IsUnknown() {
+		return
+	}
+
+	key := grainKey.ValueString()
+	switch {
+	case key == "":
+		resp.Diagnostics.AddAttributeError(
+			path.Root("grain_key"),
+			"Empty grain key",
+			"grain_key must not be empty.",
+		)
+	case strings.IndexFunc(key, unicode.IsSpace) >= 0:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("grain_key"),
+			"Whitespace in grain key",
+			fmt.Sprintf("The grain key %q contains whitespace, which Salt cannot address.", key),
+		)
+	}
+}
+
+// serverAddressValidator checks that server, or every element of servers, is a hostname or an
+// IP address with an optional port, before any connection is attempted.
+type serverAddressValidator struct {
+	// set is set for resources taking a set of servers.
+	set bool
+}
+
+func (v serverAddressValidator) Description(ctx context.Context) string {
+	return "servers must be hostnames or IP addresses with an optional port"
+}
+
+func (v serverAddressValidator) MarkdownDescription(ctx context.Context) string {
+	return "servers must be hostnames or IP addresses with an optional port"
+}
+
+func (v serverAddressValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	servers := map[string]path.Path{}
+	if v.set {
+		var serverSet types.Set
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("servers"), &serverSet)...)
+		if resp.Diagnostics.HasError() || serverSet.IsNull() || serverSet.IsUnknown() {
+			return
+		}
+		for _, element := range serverSet.Elements() {
+			if str, ok := element.(types.String); ok && !str.IsUnknown() {
+				servers[str.ValueString()] = path.Root("servers").AtSetValue(str)
+			}
+		}
+	} else {
+		var server types.String
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("server"), &server)...)
+		if resp.Diagnostics.HasError() || server.IsNull() || server.IsUnknown() {
+			return
+		}
+		servers[server.ValueString()] = path.Root("server")
+	}
+
+	for server, attribute := range servers {
+		if err := validateServerAddress(server); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				attribute,
+				"Invalid server address",
+				fmt.Sprintf("The server %q is not a valid address: %s.", server, err),
+			)
+		}
+	}
+}
+
+// validateServerAddress returns why server is neither a hostname nor an IP address, optionally
+// followed by a port.
+func validateServerAddress(server string) error {
+	host, port := saltclient.SplitServerAddress(server)
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("port %q is not a number between 1 and 65535", port)
+		}
+	}
+
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if len(host) > 253 || !hostnamePattern.MatchString(host) {
+		return fmt.Errorf("%q is neither a hostname nor an IP address", host)
+	}
+	return nil
+}
+
+// Supported values of management_mode.
+const (
+	grainModeAppend  = "append"
+	grainModeReplace = "replace"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ resource.ConfigValidator = grainManagementModeValidator{}
+
+// grainManagementModeValidator checks management_mode at plan time.
+type grainManagementModeValidator struct{}
+
+func (v grainManagementModeValidator) Description(ctx context.Context) string {
+	return "management_mode must be append or replace"
+}
+
+func (v grainManagementModeValidator) MarkdownDescription(ctx context.Context) string {
+	return "`management_mode` must be `append` or `replace`"
+}
+
+func (v grainManagementModeValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var mode types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("management_mode"), &mode)...)
+	if resp.Diagnostics.HasError() || mode.IsNull() || mode.IsUnknown() {
+		return
+	}
+
+	switch mode.ValueString() {
+	case grainModeAppend, grainModeReplace:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("management_mode"),
+			"Unsupported management_mode",
+			fmt.Sprintf("management_mode must be %s or %s, got %q", grainModeAppend, grainModeReplace, mode.ValueString()),
+		)
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &GrainsFileResource{}
+var _ resource.ResourceWithUpgradeState = &GrainsFileResource{}
+var _ resource.ResourceWithValidateConfig = &GrainsFileResource{}
+var _ resource.ResourceWithImportState = &GrainsFileResource{}
+
+func NewGrainsFileResource() resource.Resource {
+	return &GrainsFileResource{}
+}
+
+// GrainsFileResource defines the resource implementation.
+type GrainsFileResource struct {
+	minionConnection
+	grainItems *grainItemsCache
+}
+
+// GrainsFileResourceModel describes the resource data model.
+type GrainsFileResourceModel struct {
+	Id              types.String  `tfsdk:"id"`
+	Server          types.String  `tfsdk:"server"`
+	UyuniSystemName types.String  `tfsdk:"uyuni_system_name"`
+	Grains          types.Dynamic `tfsdk:"grains"`
+	Path            types.String  `tfsdk:"path"`
+}
+
+type SaltGrainsFileModel struct {
+	Grains map[string]any `json:"local"`
+}
+
+func (r *GrainsFileResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_grains_file"
+}
+
+func (r *GrainsFileResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 2,
+
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt Minion grains file, written atomically as a whole in YAML. Creating it fails when the server already has a grains file, e.g. written by the `salty_grain` resources, import it with the server address instead.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks"),
+			"grains": schema.DynamicAttribute{
+				MarkdownDescription: "All grains of the grains file as an object, e.g. `{ roles = [\"web\"], port = 8080 }`. Values keep their type, strings, numbers, booleans, lists and nested objects are written as such. Grains not listed here are removed from the file.",
+				Required:            true,
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Location of the grains file on the minion.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+// UpgradeState migrates states written with earlier schema versions.
+func (r *GrainsFileResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return stateUpgraders(
+		// 0 -> 1: schema versioning introduced, attributes unchanged
+		unchangedState,
+		// 1 -> 2: grains is a dynamic value instead of a map of strings
+		dynamicGrainsFileState,
+	)
+}
+
+// dynamicGrainsFileState wraps the map of strings of the grains in the type information stored
+// along dynamic values.
+func dynamicGrainsFileState(attributes map[string]any) error {
+	if grains, ok := attributes["grains"]; ok && grains != nil {
+		attributes["grains"] = map[string]any{"type": []any{"map", "string"}, "value": grains}
+	}
+	return nil
+}
+
+func (r *GrainsFileResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data GrainsFileResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.Grains.IsUnknown() || data.Grains.IsUnderlyingValueUnknown() {
+		return
+	}
+
+	switch data.Grains.UnderlyingValue().(type) {
+	case types.Object, types.Map:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("grains"),
+			"Grains are not an object",
+			"grains must be an object or a map with the grain names as keys.",
+		)
+	}
+}
+
+func (r *GrainsFileResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("server"), req.ID)...)
+}
+
+func (r *GrainsFileResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+	r.grainItems = data.GrainItems
+}
+
+func (r *GrainsFileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data GrainsFileResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	err := r.writeGrains(ctx, &data, true)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot write the grains file",
+			fmt.Sprintf("cannot write the grains file on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(data.Server.ValueString())
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainsFileResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data GrainsFileResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	// let Salt render the file, it may have been edited by hand in plain YAML
+	runCommand := fmt.Sprintf("if [ -f %s ]; then %s; else echo '{\"local\": null}'; fi", minion.GrainsFile, minion.LocalCommand("slsutil.renderer", minion.GrainsFile, "default_renderer=yaml"))
+	readGrains, err := r.transport.Run(tflog.MaskFieldValuesWithFieldKeys(ctx, logFieldOutput), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the grains file",
+			fmt.Sprintf("cannot read the grains file on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	liveGrains := SaltGrainsFileModel{}
+	decoder := json.NewDecoder(bytes.NewReader(saltJSON(readGrains)))
+	decoder.UseNumber()
+	err = decoder.Decode(&liveGrains)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot parse the grains file",
+			fmt.Sprintf("cannot parse the grains file on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	if liveGrains.Grains == nil {
+		tflog.Info(ctx, fmt.Sprintf("grains file %s is gone, removing it from state", minion.GrainsFile))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// the configuration may type the same grains differently, e.g. as a map of strings
+	prior, err := dynamicToNative(data.Grains)
+	if err != nil || !sameNativeValues(prior, liveGrains.Grains) {
+		grains, err := nativeToDynamic(liveGrains.Grains)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Cannot parse the grains file",
+				fmt.Sprintf("cannot convert the grains file on the Salt Minion %s: %s", data.Server.ValueString(), err),
+			)
+			return
+		}
+		data.Grains = types.DynamicValue(grains)
+	}
+	data.Path = types.StringValue(minion.GrainsFile)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainsFileResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data GrainsFileResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	err := r.writeGrains(ctx, &data, false)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot write the grains file",
+			fmt.Sprintf("cannot write the grains file on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *GrainsFileResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data GrainsFileResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	// the grains change, reads later in this operation must not be served stale values
+	defer r.grainItems.invalidate(data.Server.ValueString())
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	runCommand := fmt.Sprintf("rm -f %s && %s", minion.GrainsFile, minion.Command("saltutil.refresh_grains"))
+	_, err = r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the grains file",
+			fmt.Sprintf("cannot delete the grains file on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+	}
+}
+
+// writeGrains replaces the grains file in a single rename and refreshes the grains afterwards.
+// On create an existing grains file is left alone and reported as an error.
+func (r *GrainsFileResource) writeGrains(ctx context.Context, data *GrainsFileResourceModel, create bool) error {
+	err := r.waitMinionIsUp(ctx, nil, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return fmt.Errorf("failed to wait for the minion to be up: %s", err)
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return err
+	}
+
+	if create {
+		// the file may hold grains written by hand or by the salty_grain resources
+		output, err := r.transport.Run(ctx, data.Server.ValueString(), fmt.Sprintf("if [ -s %s ]; then echo exists; fi", minion.GrainsFile), 0)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(output) == "exists" {
+			return fmt.Errorf("%s already exists, import it with `terraform import` instead of overwriting its grains", minion.GrainsFile)
+		}
+	}
+
+	grains, err := dynamicToNative(data.Grains)
+	if err != nil {
+		return fmt.Errorf("cannot convert the grains: %s", err)
+	}
+
+	// yaml.v3 sorts the keys for a stable file and quotes strings YAML 1.1 reads as booleans
+	content, err := yaml.Marshal(grains)
+	if err != nil {
+		return fmt.Errorf("cannot render the grains file: %s", err)
+	}
+
+	runCommand := fmt.Sprintf("printf '%%s' %[1]s > %[2]s.tmp && mv %[2]s.tmp %[2]s && %[3]s",
+		saltclient.ShellQuote(string(content)), minion.GrainsFile, minion.Command("saltutil.refresh_grains"))
+	// the command carries every grain value
+	_, err = r.transport.Run(mutating(redactCommand(ctx)), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		return err
+	}
+
+	data.Path = types.StringValue(minion.GrainsFile)
+	return nil
+}
@@ -0,0 +1,221 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"slices"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &HighstateTestDataSource{}
+
+func NewHighstateTestDataSource() datasource.DataSource {
+	return &HighstateTestDataSource{}
+}
+
+// HighstateTestDataSource defines the data source implementation.
+type HighstateTestDataSource struct {
+	transport         Transport
+	uyuni             *UyuniClient
+	minionInstalls    *minionInstallCache
+	applyStateTimeout time.Duration
+	stateLock         stateLock
+	defaultSaltenv    string
+}
+
+// HighstateTestDataSourceModel describes the data source data model.
+type HighstateTestDataSourceModel struct {
+	Id             types.String `tfsdk:"id"`
+	Server         types.String `tfsdk:"server"`
+	Saltenv        types.String `tfsdk:"saltenv"`
+	StatesTotal    types.Int64  `tfsdk:"states_total"`
+	StatesToChange types.Int64  `tfsdk:"states_to_change"`
+	StatesFailed   types.Int64  `tfsdk:"states_failed"`
+	ToChange       types.List   `tfsdk:"to_change"`
+	Failed         types.List   `tfsdk:"failed"`
+}
+
+// saltTestState is a state in the output of a state.apply test=True run. The result is null
+// for a state which would change.
+type saltTestState struct {
+	ID      string         `json:"__id__"`
+	Result  *bool          `json:"result"`
+	Changes map[string]any `json:"changes"`
+}
+
+func (d *HighstateTestDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_highstate_test"
+}
+
+func (d *HighstateTestDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Drift of a Salt Minion from its highstate, found with `state.apply test=True` without changing anything. Read by scheduled plans, it feeds drift dashboards.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+			},
+			"saltenv": schema.StringAttribute{
+				MarkdownDescription: "Salt environment of the test run. Defaults to the provider's `default_saltenv`.",
+				Optional:            true,
+			},
+			"states_total": schema.Int64Attribute{
+				MarkdownDescription: "Number of states in the highstate",
+				Computed:            true,
+			},
+			"states_to_change": schema.Int64Attribute{
+				MarkdownDescription: "Number of states a `state.apply` would change",
+				Computed:            true,
+			},
+			"states_failed": schema.Int64Attribute{
+				MarkdownDescription: "Number of states failing already in the test run, e.g. for a missing requisite",
+				Computed:            true,
+			},
+			"to_change": schema.ListAttribute{
+				MarkdownDescription: "IDs of the states a `state.apply` would change, sorted",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"failed": schema.ListAttribute{
+				MarkdownDescription: "IDs of the states failing in the test run, sorted",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *HighstateTestDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.transport = data.Transport
+	d.uyuni = data.Uyuni
+	d.minionInstalls = data.MinionInstalls
+	d.applyStateTimeout = data.ApplyStateTimeout
+	d.stateLock = data.StateLock
+	d.defaultSaltenv = data.DefaultSaltenv
+}
+
+func (d *HighstateTestDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data HighstateTestDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.Server.ValueString()
+	minion, err := d.minionInstalls.detect(ctx, d.transport, d.uyuni, server, minionID(server))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", server, err),
+		)
+		return
+	}
+
+	// the minion refuses a test run as well while another state run is in progress
+	if _, err := waitForStateRuns(ctx, d.transport, minion, server, d.stateLock.MaxWait); err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot test the highstate",
+			fmt.Sprintf("cannot test the highstate of the Salt Minion %s: %s", server, err),
+		)
+		return
+	}
+
+	args := []string{"test=True"}
+	if saltenv := inherited(data.Saltenv, d.defaultSaltenv); saltenv != "" {
+		args = append(args, "saltenv="+saltenv)
+	}
+	output, err := d.transport.Run(ctx, server, minion.Command("state.apply", args...), d.applyStateTimeout)
+	if errors.Is(err, saltclient.ErrCommandTimeout) {
+		resp.Diagnostics.AddError(
+			"Cannot test the highstate",
+			fmt.Sprintf("the highstate test of the Salt Minion %s did not finish within apply_state_timeout (%s)", server, d.applyStateTimeout),
+		)
+		return
+	}
+
+	// failing states make salt-call exit non-zero, their output is reported all the same
+	var highstate struct {
+		Local map[string]saltTestState `json:"local"`
+	}
+	if parseErr := json.Unmarshal(saltJSON(output), &highstate); parseErr != nil && err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot test the highstate",
+			fmt.Sprintf("cannot test the highstate of the Salt Minion %s: %s", server, err),
+		)
+		return
+	} else if parseErr != nil {
+		// a render error is reported as a list of messages instead of the states
+		resp.Diagnostics.AddError(
+			"Cannot parse the highstate test",
+			fmt.Sprintf("cannot parse the highstate test of the Salt Minion %s, the states may not compile: %s", server, output),
+		)
+		return
+	}
+
+	var toChange, failed []string
+	for key, state := range highstate.Local {
+		id := state.ID
+		if id == "" {
+			id = key
+		}
+		switch {
+		case state.Result != nil && !*state.Result:
+			failed = append(failed, id)
+		case state.Result == nil || len(state.Changes) > 0:
+			toChange = append(toChange, id)
+		}
+	}
+	slices.Sort(toChange)
+	slices.Sort(failed)
+
+	toChangeList, diags := types.ListValueFrom(ctx, types.StringType, nonNil(toChange))
+	resp.Diagnostics.Append(diags...)
+	failedList, diags := types.ListValueFrom(ctx, types.StringType, nonNil(failed))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue(server)
+	data.StatesTotal = types.Int64Value(int64(len(highstate.Local)))
+	data.StatesToChange = types.Int64Value(int64(len(toChange)))
+	data.StatesFailed = types.Int64Value(int64(len(failed)))
+	data.ToChange = toChangeList
+	data.Failed = failedList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Supported values of hook_on_failure.
+const (
+	hookOnFailureFail     = "fail"
+	hookOnFailureContinue = "continue"
+)
+
+// runHook runs the command of the pre_command or post_command hook on server, if one is set. A
+// failure is an error, or only a warning when onFailure is continue. It reports whether the
+// operation may go on.
+func runHook(ctx context.Context, transport Transport, server string, hook string, runCommand types.String, onFailure types.String, diags *diag.Diagnostics) bool {
+	if runCommand.ValueString() == "" {
+		return true
+	}
+
+	_, err := transport.Run(mutating(ctx), server, runCommand.ValueString(), 0)
+	if err == nil {
+		return true
+	}
+
+	if onFailure.ValueString() == hookOnFailureContinue {
+		diags.AddWarning(
+			fmt.Sprintf("The %s hook failed", hook),
+			fmt.Sprintf("the %s hook failed on the Salt Minion %s, continuing as hook_on_failure is %s: %s", hook, server, hookOnFailureContinue, err),
+		)
+		return true
+	}
+
+	diags.AddError(
+		fmt.Sprintf("The %s hook failed", hook),
+		fmt.Sprintf("the %s hook failed on the Salt Minion %s, set hook_on_failure = %q to carry on regardless: %s", hook, server, hookOnFailureContinue, err),
+	)
+	return false
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ resource.ConfigValidator = hookOnFailureValidator{}
+
+// hookOnFailureValidator checks hook_on_failure at plan time.
+type hookOnFailureValidator struct{}
+
+func (v hookOnFailureValidator) Description(ctx context.Context) string {
+	return "hook_on_failure must be fail or continue"
+}
+
+func (v hookOnFailureValidator) MarkdownDescription(ctx context.Context) string {
+	return "`hook_on_failure` must be `fail` or `continue`"
+}
+
+func (v hookOnFailureValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var onFailure types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("hook_on_failure"), &onFailure)...)
+	if resp.Diagnostics.HasError() || onFailure.IsNull() || onFailure.IsUnknown() {
+		return
+	}
+
+	switch onFailure.ValueString() {
+	case hookOnFailureFail, hookOnFailureContinue:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("hook_on_failure"),
+			"Unsupported hook_on_failure",
+			fmt.Sprintf("hook_on_failure must be %s or %s, got %q", hookOnFailureFail, hookOnFailureContinue, onFailure.ValueString()),
+		)
+	}
+}
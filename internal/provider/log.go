@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Fields of the structured log entries.
+const (
+	logFieldServer   = "server"
+	logFieldCommand  = "command"
+	logFieldOutput   = "output"
+	logFieldGrainKey = "grain_key"
+)
+
+// redactValues returns ctx masking values in the messages and fields logged with it, so grain
+// values and other possibly secret content stay out of the Terraform log. The values are masked
+// as encoded by saltclient.StringArg and quoted by saltclient.ShellQuote too, which is how they
+// appear in the remote commands.
+func redactValues(ctx context.Context, values ...string) context.Context {
+	var masked []string
+	for _, value := range values {
+		// masking the empty string would mask everything
+		if value == "" {
+			continue
+		}
+		masked = append(masked, saltclient.ShellQuote(saltclient.StringArg(value)), saltclient.ShellQuote(value), saltclient.StringArg(value), value)
+	}
+	if len(masked) == 0 {
+		return ctx
+	}
+
+	ctx = tflog.MaskMessageStrings(ctx, masked...)
+	ctx = tflog.MaskAllFieldValuesStrings(ctx, masked...)
+	// kept for the commands shown outside the log, see redactedCommand
+	redacted, _ := ctx.Value(redactedValuesKey{}).([]string)
+	return context.WithValue(ctx, redactedValuesKey{}, append(slices.Clip(redacted), masked...))
+}
+
+type redactedValuesKey struct{}
+
+type redactedCommandKey struct{}
+
+// redactCommand returns ctx masking the whole command logged with it, for commands carrying
+// file contents.
+func redactCommand(ctx context.Context) context.Context {
+	ctx = tflog.MaskFieldValuesWithFieldKeys(ctx, logFieldCommand)
+	return context.WithValue(ctx, redactedCommandKey{}, true)
+}
+
+// redactedCommand returns runCommand as it may be shown in a diagnostic, with the values
+// redacted by redactValues and redactCommand masked as they are in the log.
+func redactedCommand(ctx context.Context, runCommand string) string {
+	if whole, _ := ctx.Value(redactedCommandKey{}).(bool); whole {
+		return "***"
+	}
+	redacted, _ := ctx.Value(redactedValuesKey{}).([]string)
+	for _, value := range redacted {
+		runCommand = strings.ReplaceAll(runCommand, value, "***")
+	}
+	return runCommand
+}
@@ -0,0 +1,363 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &MineFunctionResource{}
+
+func NewMineFunctionResource() resource.Resource {
+	return &MineFunctionResource{}
+}
+
+// MineFunctionResource defines the resource implementation.
+type MineFunctionResource struct {
+	minionConnection
+}
+
+// MineFunctionResourceModel describes the resource data model.
+type MineFunctionResourceModel struct {
+	Id              types.String `tfsdk:"id"`
+	Server          types.String `tfsdk:"server"`
+	UyuniSystemName types.String `tfsdk:"uyuni_system_name"`
+	Name            types.String `tfsdk:"name"`
+	Function        types.String `tfsdk:"function"`
+	Args            types.List   `tfsdk:"args"`
+	RestartMinion   types.Bool   `tfsdk:"restart_minion"`
+	Path            types.String `tfsdk:"path"`
+}
+
+// mineFunctionsConfig is the minion.d drop-in file of a mine function. JSON is valid YAML, so
+// the minion reads it like any other configuration file.
+type mineFunctionsConfig struct {
+	MineFunctions map[string][]any `json:"mine_functions"`
+}
+
+func (r *MineFunctionResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mine_function"
+}
+
+func (r *MineFunctionResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt Mine function of a minion, kept in a drop-in file in `minion.d` and published with `mine.update` once changed. Lets the states of other minions discover this one, e.g. by the grains set with this provider.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks"),
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name the mine data is published under, e.g. `internal_ip_addrs`, or the function itself when `function` is not set.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"function": schema.StringAttribute{
+				MarkdownDescription: "Execution module function run for the mine, e.g. `network.ip_addrs`. Defaults to `name`.",
+				Optional:            true,
+			},
+			"args": schema.ListAttribute{
+				MarkdownDescription: "Positional arguments of the function, e.g. `[\"eth0\"]`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"restart_minion": schema.BoolAttribute{
+				MarkdownDescription: "Restart the salt-minion service when the function changes, so the running minion refreshes it on its own `mine_interval` too. Without it only the `mine.update` run by the provider sees the change.",
+				Optional:            true,
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Location of the drop-in file on the minion.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *MineFunctionResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+}
+
+func (r *MineFunctionResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data MineFunctionResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	err := r.writeMineFunction(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot write the mine function",
+			fmt.Sprintf("cannot write the mine function %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.Name.ValueString()))
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MineFunctionResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MineFunctionResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	configPath := mineFunctionPath(minion, data.Name.ValueString())
+	runCommand := fmt.Sprintf("if [ -f %[1]s ]; then echo present; cat %[1]s; else echo absent; fi", saltclient.ShellQuote(configPath))
+	output, err := r.transport.Run(ctx, data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the mine function",
+			fmt.Sprintf("cannot read the mine function %s on the Salt Minion %s: %s", configPath, data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	status, content, _ := strings.Cut(output, "\n")
+	if status != "present" {
+		tflog.Info(ctx, fmt.Sprintf("mine function %s is gone, removing it from state", configPath))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	function, args, ok := parseMineFunction(content, data.Name.ValueString())
+	if !ok {
+		// a file edited by hand shows up as a diff, so the apply writes it again
+		tflog.Info(ctx, fmt.Sprintf("mine function %s was changed outside of Terraform", configPath))
+		function, args = "", nil
+	}
+
+	if function == data.Name.ValueString() && data.Function.IsNull() {
+		data.Function = types.StringNull()
+	} else {
+		data.Function = types.StringValue(function)
+	}
+
+	if len(args) == 0 && data.Args.IsNull() {
+		data.Args = types.ListNull(types.StringType)
+	} else {
+		var argValues []attr.Value
+		for _, arg := range args {
+			argValues = append(argValues, types.StringValue(arg))
+		}
+		listVal, diags := types.ListValue(types.StringType, argValues)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Args = listVal
+	}
+	data.Path = types.StringValue(configPath)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MineFunctionResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data MineFunctionResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	err := r.writeMineFunction(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot write the mine function",
+			fmt.Sprintf("cannot write the mine function %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MineFunctionResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data MineFunctionResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	// the published data would otherwise stay in the mine until the master flushes it
+	configPath := mineFunctionPath(minion, data.Name.ValueString())
+	runCommand := fmt.Sprintf("rm -f %s && %s", saltclient.ShellQuote(configPath), minion.Command("mine.delete", data.Name.ValueString()))
+	if data.RestartMinion.ValueBool() {
+		runCommand = fmt.Sprintf("%s && systemctl restart %s", runCommand, minion.Service)
+	}
+
+	_, err = r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the mine function",
+			fmt.Sprintf("cannot delete the mine function %s on the Salt Minion %s: %s", configPath, data.Server.ValueString(), err),
+		)
+	}
+}
+
+// writeMineFunction atomically replaces the drop-in file of the mine function, publishes it with
+// mine.update and restarts the minion if requested.
+func (r *MineFunctionResource) writeMineFunction(ctx context.Context, data *MineFunctionResourceModel) error {
+	err := r.waitMinionIsUp(ctx, nil, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return fmt.Errorf("failed to wait for the minion to be up: %s", err)
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return err
+	}
+
+	var spec []any
+	if data.Function.ValueString() != "" {
+		spec = append(spec, map[string]string{"mine_function": data.Function.ValueString()})
+	}
+	for _, arg := range grainListValues(data.Args) {
+		spec = append(spec, arg)
+	}
+	content, err := json.Marshal(mineFunctionsConfig{MineFunctions: map[string][]any{data.Name.ValueString(): spec}})
+	if err != nil {
+		return err
+	}
+
+	configPath := mineFunctionPath(minion, data.Name.ValueString())
+	runCommand := fmt.Sprintf("mkdir -p %[1]s && printf '%%s' %[2]s > %[3]s.tmp && mv %[3]s.tmp %[3]s && %[4]s",
+		saltclient.ShellQuote(minion.ConfDir), saltclient.ShellQuote(string(content)), saltclient.ShellQuote(configPath), minion.Command("mine.update"))
+	if data.RestartMinion.ValueBool() {
+		runCommand = fmt.Sprintf("%s && systemctl restart %s", runCommand, minion.Service)
+	}
+
+	_, err = r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		return err
+	}
+
+	data.Path = types.StringValue(configPath)
+	return nil
+}
+
+// parseMineFunction returns the function and arguments of the mine function name in the content
+// of its drop-in file, with the function defaulting to name. It reports false for a file not
+// written by writeMineFunction.
+func parseMineFunction(content string, name string) (string, []string, bool) {
+	var config mineFunctionsConfig
+	if err := json.Unmarshal([]byte(content), &config); err != nil {
+		return "", nil, false
+	}
+	spec, ok := config.MineFunctions[name]
+	if !ok {
+		return "", nil, false
+	}
+
+	function := name
+	var args []string
+	for i, item := range spec {
+		switch item := item.(type) {
+		case map[string]any:
+			mineFunction, ok := item["mine_function"].(string)
+			if i > 0 || !ok {
+				return "", nil, false
+			}
+			function = mineFunction
+		case string:
+			args = append(args, item)
+		default:
+			return "", nil, false
+		}
+	}
+	return function, args, true
+}
+
+// mineFunctionPath returns the location of the drop-in file of the mine function name.
+func mineFunctionPath(minion saltMinionInstall, name string) string {
+	return minionConfigPath(minion, "mine_"+name)
+}
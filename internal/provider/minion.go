@@ -0,0 +1,512 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/sync/singleflight"
+	"slices"
+	"strings"
+	"sync"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// saltMinionInstall describes where a minion keeps its salt-call binary and configuration.
+type saltMinionInstall = saltclient.MinionInstall
+
+// saltMinionInstalls lists the supported installations in detection order.
+var saltMinionInstalls = []saltMinionInstall{
+	// venv-salt-minion as bundled by Uyuni
+	{SaltCall: "/usr/lib/venv-salt-minion/bin/salt-call", ConfDir: "/etc/venv-salt-minion/minion.d", GrainsFile: "/etc/venv-salt-minion/grains", Service: "venv-salt-minion"},
+	// traditionally packaged salt-minion
+	{SaltCall: "/usr/bin/salt-call", ConfDir: "/etc/salt/minion.d", GrainsFile: "/etc/salt/grains", Service: "salt-minion"},
+}
+
+// minionInstallCache remembers the detected installation per server, so detection only runs
+// on the first connection.
+type minionInstallCache struct {
+	mu       sync.Mutex
+	installs map[string]saltMinionInstall
+	// masterless marks the detected installations as Local.
+	masterless bool
+	// waitForCloudInit holds the detection back until cloud-init finished on the server.
+	waitForCloudInit bool
+}
+
+func newMinionInstallCache(masterless bool, waitForCloudInit bool) *minionInstallCache {
+	return &minionInstallCache{installs: map[string]saltMinionInstall{}, masterless: masterless, waitForCloudInit: waitForCloudInit}
+}
+
+// detect returns the installation of server, known as systemName in Uyuni. On first use it runs
+// a preflight check and probes the server through transport, later calls are served from the cache.
+func (c *minionInstallCache) detect(ctx context.Context, transport Transport, uyuni *UyuniClient, server string, systemName string) (saltMinionInstall, error) {
+	c.mu.Lock()
+	install, ok := c.installs[server]
+	c.mu.Unlock()
+	if ok {
+		return install, nil
+	}
+
+	if c.waitForCloudInit {
+		err := waitCloudInit(ctx, transport, server)
+		if err != nil {
+			return saltMinionInstall{}, err
+		}
+	}
+
+	// standalone minions have no salt-key to check
+	if c.masterless {
+		uyuni = nil
+	}
+	found, err := preflightCheck(ctx, transport, uyuni, server, systemName)
+	if err != nil {
+		return saltMinionInstall{}, err
+	}
+
+	for _, install := range saltMinionInstalls {
+		if install.SaltCall == found {
+			install.Local = c.masterless
+			c.mu.Lock()
+			c.installs[server] = install
+			c.mu.Unlock()
+			return install, nil
+		}
+	}
+
+	return saltMinionInstall{}, fmt.Errorf("unexpected salt-call location %q", found)
+}
+
+// waitCloudInit waits until cloud-init finished the first boot of server, which may still be
+// installing or reconfiguring the minion. Servers without cloud-init pass right away.
+func waitCloudInit(ctx context.Context, transport Transport, server string) error {
+	// exit status 2 of newer cloud-init versions means done with recoverable errors; without the
+	// status command, boot-finished is written once the final stage completed
+	runCommand := "if command -v cloud-init >/dev/null 2>&1; then cloud-init status --wait >/dev/null; rc=$?; [ $rc -eq 0 ] || [ $rc -eq 2 ]; " +
+		"elif [ -d /var/lib/cloud/instance ]; then while [ ! -f /var/lib/cloud/instance/boot-finished ]; do sleep 5; done; fi"
+	_, err := transport.Run(ctx, server, runCommand, 0)
+	if err != nil {
+		return fmt.Errorf("cloud-init did not finish successfully on %s, see cloud-init status --long on the server: %w", server, err)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("cloud-init finished on %s", server))
+	return nil
+}
+
+// uyuniSystemNameAttribute is the uyuni_system_name attribute of the resources managing a Salt
+// Minion, usedFor says what the resource needs the name for.
+func uyuniSystemNameAttribute(usedFor string) schema.StringAttribute {
+	return schema.StringAttribute{
+		MarkdownDescription: "Name of the system in Uyuni, used " + usedFor + ". Set it when the minion ID differs from `server`, e.g. a short name while SSH needs the FQDN. Defaults to the host part of `server`.",
+		Optional:            true,
+	}
+}
+
+// uyuniSystemName returns the name server is registered under in Uyuni, systemName when set
+// and its minion ID otherwise.
+func uyuniSystemName(server string, systemName types.String) string {
+	if systemName.ValueString() != "" {
+		return systemName.ValueString()
+	}
+	return minionID(server)
+}
+
+// minionIsUpGroup coalesces concurrent waits for the same minion into a single poll loop.
+var minionIsUpGroup singleflight.Group
+
+// minionIsUpTimeout bounds the wait for the salt-key of a minion to be accepted.
+const minionIsUpTimeout = 30 * time.Minute
+
+// waitMinionIsUp polls Uyuni until the salt-key of the system named systemName is accepted.
+// Resources waiting for the same minion at the same time share one poll loop and its result.
+func waitMinionIsUp(ctx context.Context, uyuni *UyuniClient, events *saltEventBus, systemName string) error {
+	key := fmt.Sprintf("%s|%s", uyuni.BaseURL, systemName)
+	result := minionIsUpGroup.DoChan(key, func() (any, error) {
+		// the poll loop is shared, the caller starting it going away must not fail the others
+		pollCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), minionIsUpTimeout)
+		defer cancel()
+		return nil, pollMinionIsUp(pollCtx, uyuni, events, systemName)
+	})
+
+	start := time.Now()
+	defer func() {
+		uyuni.Timings.record(ctx, timingWaitMinion, time.Since(start), map[string]string{logFieldServer: systemName})
+	}()
+
+	select {
+	case res := <-result:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pollMinionIsUp checks Uyuni every 10 seconds or, with events, each time the minion starts and
+// at least once a minute, until ctx is done.
+func pollMinionIsUp(ctx context.Context, uyuni *UyuniClient, events *saltEventBus, systemName string) error {
+	tflog.Info(ctx, "starting to wait for the minion to be up")
+
+	for {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timeout reached after %s; salt-key for %s not accepted", minionIsUpTimeout, systemName)
+		}
+
+		found, err := uyuni.CheckServerAccepted(ctx, systemName)
+		if err != nil {
+			return fmt.Errorf("error checking salt-key acceptance of %s: %s", systemName, err)
+		}
+
+		tflog.Info(ctx, fmt.Sprintf("called checkServerAccepted with result: %v, error: %s", found, err))
+
+		if found {
+			return nil
+		}
+
+		if events != nil {
+			err := events.waitMinionStart(ctx, systemName, time.Minute)
+			if err == nil {
+				continue
+			}
+			tflog.Warn(ctx, fmt.Sprintf("cannot listen for the start event of %s, polling Uyuni instead: %s", systemName, err))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// saltEventBus listens on the event bus of the Salt master, reached through the Transport.
+type saltEventBus struct {
+	Transport Transport
+	Master    string
+}
+
+// waitMinionStart returns once the start event of the minion is fired on the master, or quietly
+// after timeout so the caller can check again whether it was missed.
+func (b *saltEventBus) waitMinionStart(ctx context.Context, minionId string, timeout time.Duration) error {
+	tag := fmt.Sprintf("salt/minion/%s/start", minionId)
+	// closing the session does not stop salt-run on the master, timeout(1) ends it there
+	runCommand := fmt.Sprintf("timeout %d salt-run state.event %s count=1 quiet=True", int(timeout.Seconds()), saltclient.ShellQuote(tag))
+	_, err := b.Transport.Run(ctx, b.Master, runCommand, timeout+30*time.Second)
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) && exitErr.ExitStatus() == timeoutExitStatus {
+		return nil
+	}
+	return err
+}
+
+// timeoutExitStatus is the exit status of a command ended by timeout(1).
+const timeoutExitStatus = 124
+
+// preflightError aggregates the failed preflight checks of a server with remediation hints.
+type preflightError struct {
+	server   string
+	failures []string
+}
+
+func (e *preflightError) Error() string {
+	return fmt.Sprintf("preflight check of %s failed:\n- %s", e.server, strings.Join(e.failures, "\n- "))
+}
+
+// preflightCheck verifies that server is reachable over SSH, has salt-call installed and, when
+// Uyuni is configured, that the salt-key of systemName is accepted. All failures are reported
+// together. It returns the location of salt-call, probed in the order of saltMinionInstalls.
+func preflightCheck(ctx context.Context, transport Transport, uyuni *UyuniClient, server string, systemName string) (string, error) {
+	check := &preflightError{server: server}
+
+	var paths []string
+	for _, install := range saltMinionInstalls {
+		paths = append(paths, install.SaltCall)
+	}
+	// the exit status tells a missing salt-call apart from SSH failing in a single round trip
+	runCommand := fmt.Sprintf("for p in %s; do if [ -x $p ]; then echo $p; exit 0; fi; done; exit 127", strings.Join(paths, " "))
+	output, err := transport.Run(ctx, server, runCommand, 0)
+	var unreachable *saltclient.UnreachableError
+	var exitErr interface{ ExitStatus() int }
+	switch {
+	case errors.As(err, &unreachable):
+		check.failures = append(check.failures, unreachable.Error())
+	case errors.As(err, &exitErr) && exitErr.ExitStatus() == 127:
+		check.failures = append(check.failures, fmt.Sprintf("salt-call is not installed in %s; install venv-salt-minion or salt-minion, e.g. by bootstrapping the system in Uyuni", strings.Join(paths, " or ")))
+	case err != nil:
+		check.failures = append(check.failures, fmt.Sprintf("SSH is not usable (%s); check that %s resolves, its SSH port is open and the provider's username and SSH credentials are authorized", err, server))
+	}
+
+	if uyuni != nil {
+		accepted, err := uyuni.CheckServerAccepted(ctx, systemName)
+		if err != nil {
+			check.failures = append(check.failures, fmt.Sprintf("cannot check the salt-key in Uyuni %s (%s); check uyuni_base_url and the Uyuni credentials", uyuni.BaseURL, err))
+		} else if !accepted {
+			check.failures = append(check.failures, fmt.Sprintf("the salt-key of %s is not accepted in Uyuni; accept it under Salt > Keys or enable wait_for_key_acceptance, and set uyuni_system_name if the minion ID differs from the server address", systemName))
+		}
+	}
+
+	if len(check.failures) > 0 {
+		return "", check
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("preflight check of %s passed", server))
+	return strings.TrimSpace(output), nil
+}
+
+// Retcodes of salt-call state.apply run with --retcode-passthrough.
+const (
+	saltRetcodeStateFailure = 1
+	saltRetcodeCompileError = 2
+)
+
+// stateApplyError turns a failed state.apply run into an actionable error based on its retcode.
+func stateApplyError(server string, err error) error {
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) {
+		switch exitErr.ExitStatus() {
+		case saltRetcodeStateFailure:
+			return fmt.Errorf("state.apply on Salt Minion %s finished with failed states, see /var/log/state.apply.tf.log on the minion for which states failed and why", server)
+		case saltRetcodeCompileError:
+			return fmt.Errorf("state.apply on Salt Minion %s could not compile the states, check the top file and the SLS files for render errors, details are in /var/log/state.apply.tf.log on the minion", server)
+		}
+	}
+
+	return fmt.Errorf("cannot apply state: %s", err.Error())
+}
+
+// Bounds of the backoff between the checks of waitForStateRuns and the retries of runStateApply.
+const (
+	stateRunPollInterval    = time.Second
+	stateRunMaxPollInterval = 30 * time.Second
+)
+
+// Defaults of the state_lock_retries and state_lock_max_wait provider attributes.
+const (
+	defaultStateLockRetries = 3
+	defaultStateLockMaxWait = 30 * time.Minute
+)
+
+// stateLock bounds how long state.apply waits for another state run on the minion to finish.
+type stateLock struct {
+	// Retries is how often a state.apply refused by the minion for another run is retried.
+	Retries int
+	// MaxWait is the total time spent waiting on the other runs.
+	MaxWait time.Duration
+}
+
+// waitForStateRuns waits until no state run is in progress on server, as a second one would
+// be refused by the minion installed as m. It polls saltutil.is_running with an exponential
+// backoff and gives up after timeout, a negative timeout waits until ctx is done. It returns
+// how long it waited.
+func waitForStateRuns(ctx context.Context, transport Transport, m saltMinionInstall, server string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	interval := stateRunPollInterval
+
+	for {
+		output, err := transport.Run(ctx, server, m.LocalCommand("saltutil.is_running", "state.*"), 0)
+		if err != nil {
+			return time.Since(start), fmt.Errorf("cannot check for running states: %s", err)
+		}
+
+		var running struct {
+			Local []any `json:"local"`
+		}
+		if err := json.Unmarshal(saltJSON(output), &running); err != nil {
+			return time.Since(start), fmt.Errorf("cannot parse the saltutil.is_running output: %s", err)
+		}
+		waited := time.Since(start)
+		if len(running.Local) == 0 {
+			return waited, nil
+		}
+
+		if timeout >= 0 && waited+interval > timeout {
+			return waited, fmt.Errorf("another state run is still in progress after waiting %s", waited.Round(time.Second))
+		}
+
+		tflog.Debug(ctx, "another state run is in progress, waiting", map[string]any{logFieldServer: server})
+		select {
+		case <-ctx.Done():
+			return waited, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, stateRunMaxPollInterval)
+	}
+}
+
+// runStateApply runs runCommand, a StateApplyCommand of m, on server once no other state run
+// is in progress. As another run may start between the check and the state.apply, a run refused
+// by the minion is retried with a backoff within the bounds of lock. It returns how long it
+// waited for the other runs.
+func runStateApply(ctx context.Context, transport Transport, m saltMinionInstall, server string, runCommand string, timeout time.Duration, lock stateLock) (string, time.Duration, error) {
+	var waited time.Duration
+	backoff := stateRunPollInterval
+
+	for attempt := 0; ; attempt++ {
+		elapsed, err := waitForStateRuns(ctx, transport, m, server, max(lock.MaxWait-waited, 0))
+		waited += elapsed
+		if err != nil {
+			return "", waited, err
+		}
+
+		output, err := transport.Run(mutating(ctx), server, runCommand, timeout)
+		if err == nil || !stateRunConflict(output) {
+			return output, waited, err
+		}
+		if attempt >= lock.Retries || waited+backoff > lock.MaxWait {
+			return output, waited, fmt.Errorf("another state run kept the minion busy, gave up after %d retries and %s of waiting, raise state_lock_retries or state_lock_max_wait to wait longer", attempt, waited.Round(time.Second))
+		}
+
+		tflog.Info(ctx, "state.apply was refused as another state run started, retrying", map[string]any{logFieldServer: server})
+		select {
+		case <-ctx.Done():
+			return "", waited, ctx.Err()
+		case <-time.After(backoff):
+		}
+		waited += backoff
+		backoff = min(backoff*2, stateRunMaxPollInterval)
+	}
+}
+
+// stateRunConflict reports whether a state.apply output is the refusal of the minion to start
+// a state run while another one is in progress.
+func stateRunConflict(output string) bool {
+	var refused struct {
+		Local []string `json:"local"`
+	}
+	if err := json.Unmarshal(saltJSON(output), &refused); err != nil {
+		return false
+	}
+	return slices.ContainsFunc(refused.Local, func(message string) bool { return strings.Contains(message, "is running as PID") })
+}
+
+// defaultGrainDelimiter separates the levels of nested grain keys unless configured otherwise.
+const defaultGrainDelimiter = ":"
+
+// grainArgs returns the arguments of a grains function, with the delimiter argument appended
+// unless it is Salt's default.
+func grainArgs(delimiter string, args ...string) []string {
+	if delimiter != "" && delimiter != defaultGrainDelimiter {
+		args = append(args, "delimiter="+delimiter)
+	}
+	return args
+}
+
+// setGrainArgs returns the grains.set arguments writing value to key, nested at delimiter unlike
+// grains.setval. force lets the value replace a list or dictionary.
+func setGrainArgs(delimiter string, key string, value string) []string {
+	return grainArgs(delimiter, key, value, "force=True")
+}
+
+// deleteGrainArgs returns the grains.set arguments removing key, nested at delimiter, which
+// grains.delkey cannot address. Salt parses the None argument as a null value.
+func deleteGrainArgs(delimiter string, key string) []string {
+	return grainArgs(delimiter, key, "None", "destructive=True", "force=True")
+}
+
+// appendGrainArgs returns the grains.append arguments adding values to the list grain key,
+// nested at delimiter. The values are sent as a JSON list, so each of them stays a string.
+func appendGrainArgs(delimiter string, key string, values []string) []string {
+	content, _ := json.Marshal(nonNil(values))
+	return grainArgs(delimiter, key, string(content))
+}
+
+// removeGrainArgs returns the grains.remove arguments removing value from the list grain key,
+// nested at delimiter. value is encoded as a string like appendGrainArgs does, Salt would not
+// find "8080" appended as a string when it is sent as the number 8080.
+func removeGrainArgs(delimiter string, key string, value string) []string {
+	return grainArgs(delimiter, key, saltclient.StringArg(value))
+}
+
+// inherited returns value, or the provider-level fallback when value is not set.
+func inherited(value types.String, fallback string) string {
+	if value.ValueString() != "" {
+		return value.ValueString()
+	}
+	return fallback
+}
+
+// highstateSummary sums up the JSON output of a state.apply run.
+type highstateSummary struct {
+	Changed  int64
+	Failed   int64
+	Duration float64
+	// Waited is how long the run waited for another state run on the minion to finish.
+	Waited time.Duration
+}
+
+// parseHighstate returns the summary of a state.apply output, nil unless it is a highstate.
+func parseHighstate(output string) *highstateSummary {
+	var highstate struct {
+		Local map[string]struct {
+			Result  *bool          `json:"result"`
+			Changes map[string]any `json:"changes"`
+			// milliseconds, as a number or, in older Salt versions, a string like "12.3 ms"
+			Duration any `json:"duration"`
+		} `json:"local"`
+	}
+	if err := json.Unmarshal(saltJSON(output), &highstate); err != nil || highstate.Local == nil {
+		return nil
+	}
+
+	summary := &highstateSummary{}
+	var milliseconds float64
+	for _, state := range highstate.Local {
+		if len(state.Changes) > 0 {
+			summary.Changed++
+		}
+		if state.Result != nil && !*state.Result {
+			summary.Failed++
+		}
+		switch duration := state.Duration.(type) {
+		case float64:
+			milliseconds += duration
+		case string:
+			var value float64
+			if _, err := fmt.Sscanf(duration, "%g", &value); err == nil {
+				milliseconds += value
+			}
+		}
+	}
+	summary.Duration = milliseconds / 1000
+	return summary
+}
+
+func (s *highstateSummary) String() string {
+	if s == nil {
+		return "state.apply finished, its output could not be summarized"
+	}
+	result := fmt.Sprintf("%d states changed, %d failed, %.1f seconds", s.Changed, s.Failed, s.Duration)
+	if s.Waited >= stateRunPollInterval {
+		result = fmt.Sprintf("%s, after waiting %s for another state run", result, s.Waited.Round(time.Second))
+	}
+	return result
+}
+
+// values returns the states_changed, states_failed and duration_seconds attributes, null for a
+// nil summary.
+func (s *highstateSummary) values() (types.Int64, types.Int64, types.Float64) {
+	if s == nil {
+		return types.Int64Null(), types.Int64Null(), types.Float64Null()
+	}
+	return types.Int64Value(s.Changed), types.Int64Value(s.Failed), types.Float64Value(s.Duration)
+}
+
+// saltJSON returns the JSON document in a salt-call output, skipping deprecation warnings and
+// other noise printed on the lines before it.
+func saltJSON(output string) []byte {
+	for line := output; line != ""; {
+		if strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[") {
+			return []byte(line)
+		}
+		_, rest, found := strings.Cut(line, "\n")
+		if !found {
+			break
+		}
+		line = rest
+	}
+	return []byte(output)
+}
@@ -0,0 +1,289 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &MinionConfigResource{}
+var _ resource.ResourceWithUpgradeState = &MinionConfigResource{}
+
+func NewMinionConfigResource() resource.Resource {
+	return &MinionConfigResource{}
+}
+
+// MinionConfigResource defines the resource implementation.
+type MinionConfigResource struct {
+	minionConnection
+}
+
+// MinionConfigResourceModel describes the resource data model.
+type MinionConfigResourceModel struct {
+	Id              types.String `tfsdk:"id"`
+	Server          types.String `tfsdk:"server"`
+	UyuniSystemName types.String `tfsdk:"uyuni_system_name"`
+	Name            types.String `tfsdk:"name"`
+	Content         types.String `tfsdk:"content"`
+	RestartMinion   types.Bool   `tfsdk:"restart_minion"`
+	Path            types.String `tfsdk:"path"`
+}
+
+func (r *MinionConfigResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_minion_config"
+}
+
+func (r *MinionConfigResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt Minion configuration drop-in file in `minion.d`",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks"),
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the drop-in file, without the `.conf` suffix.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"content": schema.StringAttribute{
+				MarkdownDescription: "YAML content of the drop-in file.",
+				Required:            true,
+			},
+			"restart_minion": schema.BoolAttribute{
+				MarkdownDescription: "Restart the salt-minion service when the file changes, so the configuration takes effect.",
+				Optional:            true,
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Location of the drop-in file on the minion.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+// UpgradeState migrates states written with earlier schema versions.
+func (r *MinionConfigResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return stateUpgraders(
+		// 0 -> 1: schema versioning introduced, attributes unchanged
+		unchangedState,
+	)
+}
+
+func (r *MinionConfigResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+}
+
+func (r *MinionConfigResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data MinionConfigResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	err := r.writeConfig(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot write the minion configuration",
+			fmt.Sprintf("cannot write the minion configuration %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.Name.ValueString()))
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MinionConfigResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MinionConfigResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	configPath := minionConfigPath(minion, data.Name.ValueString())
+	runCommand := fmt.Sprintf("if [ -f %[1]s ]; then echo present; cat %[1]s; else echo absent; fi", saltclient.ShellQuote(configPath))
+	output, err := r.transport.Run(tflog.MaskFieldValuesWithFieldKeys(ctx, logFieldOutput), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the minion configuration",
+			fmt.Sprintf("cannot read the minion configuration %s on the Salt Minion %s: %s", configPath, data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	status, content, _ := strings.Cut(output, "\n")
+	if status != "present" {
+		tflog.Info(ctx, fmt.Sprintf("minion configuration %s is gone, removing it from state", configPath))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	data.Content = types.StringValue(content)
+	data.Path = types.StringValue(configPath)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MinionConfigResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data MinionConfigResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	err := r.writeConfig(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot write the minion configuration",
+			fmt.Sprintf("cannot write the minion configuration %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MinionConfigResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data MinionConfigResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	configPath := minionConfigPath(minion, data.Name.ValueString())
+	runCommand := fmt.Sprintf("rm -f %s", saltclient.ShellQuote(configPath))
+	if data.RestartMinion.ValueBool() {
+		runCommand = fmt.Sprintf("%s && systemctl restart %s", runCommand, minion.Service)
+	}
+
+	_, err = r.transport.Run(mutating(ctx), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the minion configuration",
+			fmt.Sprintf("cannot delete the minion configuration %s on the Salt Minion %s: %s", configPath, data.Server.ValueString(), err),
+		)
+	}
+}
+
+// writeConfig atomically replaces the drop-in file and restarts the minion if requested.
+func (r *MinionConfigResource) writeConfig(ctx context.Context, data *MinionConfigResourceModel) error {
+	err := r.waitMinionIsUp(ctx, nil, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return fmt.Errorf("failed to wait for the minion to be up: %s", err)
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return err
+	}
+
+	configPath := minionConfigPath(minion, data.Name.ValueString())
+	runCommand := fmt.Sprintf("mkdir -p %[1]s && printf '%%s' %[2]s > %[3]s.tmp && mv %[3]s.tmp %[3]s",
+		saltclient.ShellQuote(minion.ConfDir), saltclient.ShellQuote(data.Content.ValueString()), saltclient.ShellQuote(configPath))
+	if data.RestartMinion.ValueBool() {
+		runCommand = fmt.Sprintf("%s && systemctl restart %s", runCommand, minion.Service)
+	}
+
+	// the command carries the whole configuration, credentials included
+	_, err = r.transport.Run(mutating(redactCommand(ctx)), data.Server.ValueString(), runCommand, 0)
+	if err != nil {
+		return err
+	}
+
+	data.Path = types.StringValue(configPath)
+	return nil
+}
+
+// minionConfigPath returns the location of the named drop-in file.
+func minionConfigPath(minion saltMinionInstall, name string) string {
+	return fmt.Sprintf("%s/%s.conf", minion.ConfDir, name)
+}
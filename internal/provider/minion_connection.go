@@ -0,0 +1,105 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// minionConnection is what every resource managing something on a Salt Minion takes from the
+// provider configuration: how commands reach the minion, how to wait for it and how to apply
+// states on it. The resources embed it and call configure from their Configure.
+type minionConnection struct {
+	transport            Transport
+	uyuni                *UyuniClient
+	saltEvents           *saltEventBus
+	waitForKeyAcceptance bool
+	minionInstalls       *minionInstallCache
+	serverLocks          *serverLocks
+	applyStateTimeout    time.Duration
+	stateLock            stateLock
+	defaultSaltenv       string
+}
+
+func (c *minionConnection) configure(data *providerData) {
+	c.transport = data.Transport
+	c.uyuni = data.Uyuni
+	c.saltEvents = data.SaltEvents
+	c.waitForKeyAcceptance = data.WaitForKeyAcceptance
+	c.minionInstalls = data.MinionInstalls
+	c.serverLocks = data.ServerLocks
+	c.applyStateTimeout = data.ApplyStateTimeout
+	c.stateLock = data.StateLock
+	c.defaultSaltenv = data.DefaultSaltenv
+}
+
+// waitMinionIsUp waits for the minion systemName to be registered, when wait_for_key_acceptance
+// is set. endpoint overrides the Uyuni server of the provider and may be nil.
+func (c *minionConnection) waitMinionIsUp(ctx context.Context, endpoint *uyuniEndpointModel, systemName string) error {
+	if !c.waitForKeyAcceptance {
+		return nil
+	}
+
+	return waitMinionIsUp(ctx, c.uyuni.withEndpoint(endpoint), c.saltEvents, systemName)
+}
+
+// stateApply is a state.apply run of a resource with its apply_state settings.
+type stateApply struct {
+	Server          string
+	SystemName      string
+	Uyuni           *uyuniEndpointModel
+	SyncBeforeApply bool
+	Saltenv         types.String
+	Pillar          types.Map
+	FailureMode     types.String
+}
+
+// applyState runs state.apply on the minion and returns its summary, nil when the output
+// cannot be parsed. Failed states are an error unless the failure mode tolerates them.
+func (c *minionConnection) applyState(ctx context.Context, run stateApply) (*highstateSummary, error) {
+	minion, err := c.minionInstalls.detect(ctx, c.transport, c.uyuni.withEndpoint(run.Uyuni), run.Server, run.SystemName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot apply state: %s", err.Error())
+	}
+
+	if run.SyncBeforeApply {
+		_, err := c.transport.Run(mutating(ctx), run.Server, minion.Command("saltutil.sync_all"), 0)
+		if err != nil {
+			return nil, fmt.Errorf("cannot sync modules before applying state: %s", err.Error())
+		}
+	}
+
+	pillar := map[string]string{}
+	if diags := run.Pillar.ElementsAs(ctx, &pillar, false); diags.HasError() {
+		return nil, fmt.Errorf("cannot convert pillar to strings")
+	}
+	if len(pillar) > 0 {
+		// the pillar data may carry secrets
+		ctx = redactCommand(ctx)
+	}
+
+	runCommand := minion.StateApplyCommand(inherited(run.Saltenv, c.defaultSaltenv), pillar)
+	output, waited, err := runStateApply(ctx, c.transport, minion, run.Server, runCommand, c.applyStateTimeout, c.stateLock)
+	if summary := tolerateStatesFailed(run.FailureMode, output, err); summary != nil {
+		summary.Waited = waited
+		return summary, nil
+	}
+	if errors.Is(err, saltclient.ErrCommandTimeout) {
+		return nil, fmt.Errorf("state.apply did not finish on Salt Minion %s within apply_state_timeout (%s)", run.Server, c.applyStateTimeout)
+	}
+	if err != nil {
+		return nil, stateApplyError(run.Server, err)
+	}
+
+	summary := parseHighstate(output)
+	if summary != nil {
+		summary.Waited = waited
+	}
+	return summary, nil
+}
@@ -0,0 +1,159 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &MinionKeyFingerprintDataSource{}
+
+func NewMinionKeyFingerprintDataSource() datasource.DataSource {
+	return &MinionKeyFingerprintDataSource{}
+}
+
+// MinionKeyFingerprintDataSource defines the data source implementation.
+type MinionKeyFingerprintDataSource struct {
+	transport  Transport
+	saltMaster string
+}
+
+// MinionKeyFingerprintDataSourceModel describes the data source data model.
+type MinionKeyFingerprintDataSourceModel struct {
+	Id          types.String `tfsdk:"id"`
+	MinionID    types.String `tfsdk:"minion_id"`
+	Fingerprint types.String `tfsdk:"fingerprint"`
+	Status      types.String `tfsdk:"status"`
+}
+
+// saltKeyFingerprints is the salt-key -f --out=json output, the fingerprints by minion ID of
+// each key state.
+type saltKeyFingerprints struct {
+	Accepted map[string]string `json:"minions"`
+	Pending  map[string]string `json:"minions_pre"`
+	Rejected map[string]string `json:"minions_rejected"`
+	Denied   map[string]string `json:"minions_denied"`
+}
+
+func (d *MinionKeyFingerprintDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_minion_key_fingerprint"
+}
+
+func (d *MinionKeyFingerprintDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Fingerprint of the key a Salt Minion submitted, read with `salt-key -f` over SSH to the `salt_master`, so a precondition can assert the expected key before `salty_minion_key` or the Uyuni acceptance proceeds. The Uyuni API does not expose fingerprints, with Uyuni set `salt_master` to the Uyuni server, which runs the Salt master.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"minion_id": schema.StringAttribute{
+				MarkdownDescription: "ID of the minion as known to the Salt master.",
+				Required:            true,
+			},
+			"fingerprint": schema.StringAttribute{
+				MarkdownDescription: "Fingerprint of the minion's public key in the master's `hash_type`, e.g. `3c:5a:...`, null while the minion has not submitted a key",
+				Computed:            true,
+			},
+			"status": schema.StringAttribute{
+				MarkdownDescription: "State of the key on the master: `accepted`, `pending`, `rejected`, `denied` or `absent`",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *MinionKeyFingerprintDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.transport = data.Transport
+	d.saltMaster = data.SaltMaster
+}
+
+func (d *MinionKeyFingerprintDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data MinionKeyFingerprintDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if d.saltMaster == "" {
+		resp.Diagnostics.AddError(
+			"Salt master is not configured",
+			"The salty_minion_key_fingerprint data source requires salt_master to be set on the provider, with Uyuni to the address of the Uyuni server.",
+		)
+		return
+	}
+
+	minionID := data.MinionID.ValueString()
+	runCommand := fmt.Sprintf("salt-key --out=json -f %s", saltclient.ShellQuote(minionID))
+	output, err := d.transport.Run(ctx, d.saltMaster, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the minion key fingerprint",
+			fmt.Sprintf("cannot read the key fingerprint of %s on the Salt master %s: %s", minionID, d.saltMaster, err),
+		)
+		return
+	}
+
+	fingerprints := saltKeyFingerprints{}
+	// salt-key prints nothing at all for a minion without a key
+	if strings.TrimSpace(output) != "" {
+		if err := json.Unmarshal(saltJSON(output), &fingerprints); err != nil {
+			resp.Diagnostics.AddError(
+				"Cannot parse the minion key fingerprint",
+				fmt.Sprintf("cannot parse the key fingerprint of %s on the Salt master %s: %s", minionID, d.saltMaster, err),
+			)
+			return
+		}
+	}
+
+	data.Status = types.StringValue("absent")
+	data.Fingerprint = types.StringNull()
+	for _, keys := range []struct {
+		status       string
+		fingerprints map[string]string
+	}{
+		{"accepted", fingerprints.Accepted},
+		{"pending", fingerprints.Pending},
+		{"rejected", fingerprints.Rejected},
+		{"denied", fingerprints.Denied},
+	} {
+		if fingerprint, ok := keys.fingerprints[minionID]; ok {
+			data.Status = types.StringValue(keys.status)
+			data.Fingerprint = types.StringValue(fingerprint)
+			break
+		}
+	}
+	data.Id = types.StringValue(minionID)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
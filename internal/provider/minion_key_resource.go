@@ -0,0 +1,294 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &MinionKeyResource{}
+var _ resource.ResourceWithValidateConfig = &MinionKeyResource{}
+
+const defaultMinionKeyTimeout = 10 * time.Minute
+
+func NewMinionKeyResource() resource.Resource {
+	return &MinionKeyResource{}
+}
+
+// MinionKeyResource defines the resource implementation.
+type MinionKeyResource struct {
+	transport  Transport
+	saltMaster string
+}
+
+// MinionKeyResourceModel describes the resource data model.
+type MinionKeyResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	MinionID      types.String `tfsdk:"minion_id"`
+	Timeout       types.String `tfsdk:"timeout"`
+	KeepOnDestroy types.Bool   `tfsdk:"keep_on_destroy"`
+}
+
+// saltKeyList is the salt-key --out=json output.
+type saltKeyList struct {
+	Accepted []string `json:"minions"`
+	Pending  []string `json:"minions_pre"`
+	Rejected []string `json:"minions_rejected"`
+	Denied   []string `json:"minions_denied"`
+}
+
+func (r *MinionKeyResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_minion_key"
+}
+
+func (r *MinionKeyResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Key of a Salt Minion, accepted with `salt-key` over SSH to the `salt_master`. Replaces the Uyuni key acceptance for plain Salt masters, let the other resources of the minion depend on it.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"minion_id": schema.StringAttribute{
+				MarkdownDescription: "ID of the minion whose key is accepted.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"timeout": schema.StringAttribute{
+				MarkdownDescription: "How long to wait for the minion to submit its key, as a Go duration. Defaults to `10m`.",
+				Optional:            true,
+			},
+			"keep_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Leave the key accepted when the resource is destroyed. By default the key is deleted from the master.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *MinionKeyResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data MinionKeyResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Timeout.IsNull() && !data.Timeout.IsUnknown() {
+		if _, err := time.ParseDuration(data.Timeout.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("timeout"),
+				"Malformed timeout",
+				fmt.Sprintf("timeout is not a valid duration: %s", err),
+			)
+		}
+	}
+}
+
+func (r *MinionKeyResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.SaltMaster == "" {
+		resp.Diagnostics.AddError(
+			"Salt master is not configured",
+			"The salty_minion_key resource requires salt_master to be set on the provider.",
+		)
+		return
+	}
+
+	r.transport = data.Transport
+	r.saltMaster = data.SaltMaster
+}
+
+func (r *MinionKeyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data MinionKeyResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout := defaultMinionKeyTimeout
+	if !data.Timeout.IsNull() {
+		timeout, _ = time.ParseDuration(data.Timeout.ValueString())
+	}
+
+	err := r.acceptKey(ctx, data.MinionID.ValueString(), timeout)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot accept the minion key",
+			fmt.Sprintf("cannot accept the key of %s on the Salt master %s: %s", data.MinionID.ValueString(), r.saltMaster, err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(data.MinionID.ValueString())
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MinionKeyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MinionKeyResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	keys, err := r.listKeys(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot list the minion keys",
+			fmt.Sprintf("cannot list the minion keys on the Salt master %s: %s", r.saltMaster, err),
+		)
+		return
+	}
+
+	if !slices.Contains(keys.Accepted, data.MinionID.ValueString()) {
+		tflog.Info(ctx, fmt.Sprintf("key of %s is no longer accepted, removing it from state", data.MinionID.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MinionKeyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data MinionKeyResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// only timeout and keep_on_destroy can change, both take effect on the next create or delete
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MinionKeyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data MinionKeyResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.KeepOnDestroy.ValueBool() {
+		tflog.Info(ctx, fmt.Sprintf("keep_on_destroy is set, leaving the key of %s accepted", data.MinionID.ValueString()))
+		return
+	}
+
+	runCommand := fmt.Sprintf("salt-key -y -d %s", saltclient.ShellQuote(data.MinionID.ValueString()))
+	_, err := r.transport.Run(mutating(ctx), r.saltMaster, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the minion key",
+			fmt.Sprintf("cannot delete the key of %s on the Salt master %s: %s", data.MinionID.ValueString(), r.saltMaster, err),
+		)
+	}
+}
+
+// listKeys returns the keys known to the Salt master, by state.
+func (r *MinionKeyResource) listKeys(ctx context.Context) (*saltKeyList, error) {
+	output, err := r.transport.Run(ctx, r.saltMaster, "salt-key --out=json -l all", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := saltKeyList{}
+	if err := json.Unmarshal(saltJSON(output), &keys); err != nil {
+		return nil, fmt.Errorf("cannot parse the salt-key output: %s", err)
+	}
+	return &keys, nil
+}
+
+// acceptKey waits until minionID has submitted its key and accepts it. A key the master
+// already accepted is left as is, a rejected or denied one fails right away.
+func (r *MinionKeyResource) acceptKey(ctx context.Context, minionID string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		keys, err := r.listKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case slices.Contains(keys.Accepted, minionID):
+			tflog.Info(ctx, fmt.Sprintf("key of %s is already accepted", minionID))
+			return nil
+		case slices.Contains(keys.Rejected, minionID), slices.Contains(keys.Denied, minionID):
+			return fmt.Errorf("the key was rejected or denied, delete it with salt-key -d first")
+		case slices.Contains(keys.Pending, minionID):
+			runCommand := fmt.Sprintf("salt-key -y -a %s", saltclient.ShellQuote(minionID))
+			_, err := r.transport.Run(mutating(ctx), r.saltMaster, runCommand, 0)
+			if err != nil {
+				return err
+			}
+			tflog.Info(ctx, fmt.Sprintf("accepted the key of %s", minionID))
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the minion did not submit its key within %s", timeout)
+		}
+
+		tflog.Debug(ctx, fmt.Sprintf("key of %s not submitted yet, retrying", minionID))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"gopkg.in/yaml.v3"
+	"slices"
+	"testing"
+)
+
+func TestGrainArgs(t *testing.T) {
+	tests := map[string]struct {
+		got  []string
+		want []string
+	}{
+		"set":                     {got: setGrainArgs("", "roles", "web"), want: []string{"roles", "web", "force=True"}},
+		"set default delimiter":   {got: setGrainArgs(":", "app:env", "prod"), want: []string{"app:env", "prod", "force=True"}},
+		"set custom delimiter":    {got: setGrainArgs("|", "app|env", "prod"), want: []string{"app|env", "prod", "force=True", "delimiter=|"}},
+		"delete":                  {got: deleteGrainArgs("", "roles"), want: []string{"roles", "None", "destructive=True", "force=True"}},
+		"delete custom delimiter": {got: deleteGrainArgs("|", "app|env"), want: []string{"app|env", "None", "destructive=True", "force=True", "delimiter=|"}},
+		"append":                  {got: appendGrainArgs("", "ports", []string{"8080", "web"}), want: []string{"ports", `["8080","web"]`}},
+		"remove":                  {got: removeGrainArgs("", "ports", "8080"), want: []string{"ports", `"8080"`}},
+		"remove custom delimiter": {got: removeGrainArgs("|", "app|ports", "8080"), want: []string{"app|ports", `"8080"`, "delimiter=|"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if !slices.Equal(test.got, test.want) {
+				t.Errorf("got %q, want %q", test.got, test.want)
+			}
+		})
+	}
+}
+
+func TestGrainListValueEncoding(t *testing.T) {
+	// a value appended must be found again by grains.remove, so Salt has to read both as the
+	// same string whatever the value looks like
+	for _, value := range []string{"8080", "true", "None", "key=val", "a # b", "web"} {
+		t.Run(value, func(t *testing.T) {
+			var appended []any
+			if err := yaml.Unmarshal([]byte(appendGrainArgs("", "roles", []string{value})[1]), &appended); err != nil {
+				t.Fatalf("cannot parse the appended list: %s", err)
+			}
+			var removed any
+			if err := yaml.Unmarshal([]byte(removeGrainArgs("", "roles", value)[1]), &removed); err != nil {
+				t.Fatalf("cannot parse the removed value: %s", err)
+			}
+
+			if len(appended) != 1 || appended[0] != value || removed != value {
+				t.Errorf("%q is appended as %#v and removed as %#v", value, appended, removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &NetworkInfoDataSource{}
+
+func NewNetworkInfoDataSource() datasource.DataSource {
+	return &NetworkInfoDataSource{}
+}
+
+// NetworkInfoDataSource defines the data source implementation.
+type NetworkInfoDataSource struct {
+	transport      Transport
+	uyuni          *UyuniClient
+	minionInstalls *minionInstallCache
+}
+
+// NetworkInfoDataSourceModel describes the data source data model.
+type NetworkInfoDataSourceModel struct {
+	Id               types.String `tfsdk:"id"`
+	Server           types.String `tfsdk:"server"`
+	FQDN             types.String `tfsdk:"fqdn"`
+	Host             types.String `tfsdk:"host"`
+	Domain           types.String `tfsdk:"domain"`
+	IPv4             types.List   `tfsdk:"ipv4"`
+	IPv6             types.List   `tfsdk:"ipv6"`
+	IP4Interfaces    types.Map    `tfsdk:"ip4_interfaces"`
+	IP6Interfaces    types.Map    `tfsdk:"ip6_interfaces"`
+	HwaddrInterfaces types.Map    `tfsdk:"hwaddr_interfaces"`
+}
+
+// SaltNetworkGrainsModel is the grains.item output of the network grains.
+type SaltNetworkGrainsModel struct {
+	Grains struct {
+		FQDN             string              `json:"fqdn"`
+		Host             string              `json:"host"`
+		Domain           string              `json:"domain"`
+		IPv4             []string            `json:"ipv4"`
+		IPv6             []string            `json:"ipv6"`
+		IP4Interfaces    map[string][]string `json:"ip4_interfaces"`
+		IP6Interfaces    map[string][]string `json:"ip6_interfaces"`
+		HwaddrInterfaces map[string]string   `json:"hwaddr_interfaces"`
+	} `json:"local"`
+}
+
+func (d *NetworkInfoDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_network_info"
+}
+
+func (d *NetworkInfoDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	addressesType := types.ListType{ElemType: types.StringType}
+
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Network facts of a Salt Minion, read from its grains",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+			},
+			"fqdn": schema.StringAttribute{
+				MarkdownDescription: "Fully qualified domain name of the minion",
+				Computed:            true,
+			},
+			"host": schema.StringAttribute{
+				MarkdownDescription: "Short host name of the minion",
+				Computed:            true,
+			},
+			"domain": schema.StringAttribute{
+				MarkdownDescription: "DNS domain of the minion, empty when it has none",
+				Computed:            true,
+			},
+			"ipv4": schema.ListAttribute{
+				MarkdownDescription: "IPv4 addresses of all interfaces",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"ipv6": schema.ListAttribute{
+				MarkdownDescription: "IPv6 addresses of all interfaces",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"ip4_interfaces": schema.MapAttribute{
+				MarkdownDescription: "IPv4 addresses per interface name",
+				ElementType:         addressesType,
+				Computed:            true,
+			},
+			"ip6_interfaces": schema.MapAttribute{
+				MarkdownDescription: "IPv6 addresses per interface name",
+				ElementType:         addressesType,
+				Computed:            true,
+			},
+			"hwaddr_interfaces": schema.MapAttribute{
+				MarkdownDescription: "MAC address per interface name",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *NetworkInfoDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.transport = data.Transport
+	d.uyuni = data.Uyuni
+	d.minionInstalls = data.MinionInstalls
+}
+
+func (d *NetworkInfoDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data NetworkInfoDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.Server.ValueString()
+	minion, err := d.minionInstalls.detect(ctx, d.transport, d.uyuni, server, minionID(server))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", server, err),
+		)
+		return
+	}
+
+	runCommand := minion.LocalCommand("grains.item", "fqdn", "host", "domain", "ipv4", "ipv6", "ip4_interfaces", "ip6_interfaces", "hwaddr_interfaces")
+	output, err := d.transport.Run(ctx, server, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the network grains",
+			fmt.Sprintf("cannot read the network grains on the Salt Minion %s: %s", server, err),
+		)
+		return
+	}
+
+	network := SaltNetworkGrainsModel{}
+	err = json.Unmarshal(saltJSON(output), &network)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot parse the network grains",
+			fmt.Sprintf("cannot parse the network grains on the Salt Minion %s: %s", server, err),
+		)
+		return
+	}
+
+	grains := network.Grains
+	data.Id = types.StringValue(server)
+	data.FQDN = types.StringValue(grains.FQDN)
+	data.Host = types.StringValue(grains.Host)
+	data.Domain = types.StringValue(grains.Domain)
+
+	var diags diag.Diagnostics
+	data.IPv4, diags = types.ListValueFrom(ctx, types.StringType, nonNil(grains.IPv4))
+	resp.Diagnostics.Append(diags...)
+	data.IPv6, diags = types.ListValueFrom(ctx, types.StringType, nonNil(grains.IPv6))
+	resp.Diagnostics.Append(diags...)
+	data.IP4Interfaces, diags = types.MapValueFrom(ctx, types.ListType{ElemType: types.StringType}, grains.IP4Interfaces)
+	resp.Diagnostics.Append(diags...)
+	data.IP6Interfaces, diags = types.MapValueFrom(ctx, types.ListType{ElemType: types.StringType}, grains.IP6Interfaces)
+	resp.Diagnostics.Append(diags...)
+	data.HwaddrInterfaces, diags = types.MapValueFrom(ctx, types.StringType, grains.HwaddrInterfaces)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,137 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"golang.org/x/sync/singleflight"
+	"sync"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// Supported values of on_unreachable.
+const (
+	onUnreachableError     = "error"
+	onUnreachableKeepState = "keep_state"
+	onUnreachableRemove    = "remove"
+)
+
+// onUnreachableAttribute is the on_unreachable attribute shared by the grain resources.
+func onUnreachableAttribute() schema.StringAttribute {
+	return schema.StringAttribute{
+		MarkdownDescription: "What a refresh does when the Salt Minion does not resolve or accept SSH connections within 10 seconds: `error` fails the plan, `keep_state` skips the read and keeps the stored values with a warning, e.g. for laptops or VMs which are often powered off, and `remove` drops the resource from the state so the next apply creates it again. A minion found unreachable is not probed again by the other resources of the same Terraform command. Through `ssh_proxy_url` or a `ProxyJump` the proxy only reports a failed connection, which is not told apart from other errors, so the refresh fails as with `error`. Defaults to `error`.",
+		Optional:            true,
+	}
+}
+
+// unreachableProbeTimeout bounds the wait of a refresh for a minion to become reachable. Unlike
+// an apply, a refresh of a resource tolerating an offline minion expects it to be down.
+const unreachableProbeTimeout = 10 * time.Second
+
+// unreachableServers remembers the minions found unreachable during one Terraform command, so
+// the other resources of an offline minion do not wait for it again.
+type unreachableServers struct {
+	mu      sync.Mutex
+	servers map[string]error
+	probes  singleflight.Group
+}
+
+func newUnreachableServers() *unreachableServers {
+	return &unreachableServers{servers: map[string]error{}}
+}
+
+// probe returns the saltclient.UnreachableError of server, nil when it is reachable or failed otherwise.
+// Only the servers found unreachable are remembered.
+func (u *unreachableServers) probe(ctx context.Context, transport Transport, server string) error {
+	u.mu.Lock()
+	err, ok := u.servers[server]
+	u.mu.Unlock()
+	if ok {
+		return err
+	}
+
+	_, err, _ = u.probes.Do(server, func() (any, error) {
+		_, err := transport.Run(saltclient.WithReachabilityTimeout(ctx, unreachableProbeTimeout), server, "true", 0)
+		var unreachable *saltclient.UnreachableError
+		if !errors.As(err, &unreachable) {
+			return nil, nil
+		}
+
+		u.mu.Lock()
+		u.servers[server] = err
+		u.mu.Unlock()
+		return nil, err
+	})
+	return err
+}
+
+// unreachableRead probes server when onUnreachable tolerates an offline minion and, if the
+// minion is unreachable, keeps or removes the state as asked instead of failing the read. It
+// reports whether the read is done. The SOCKS proxy and jump host dials never return an
+// saltclient.UnreachableError, so through them the read goes on and fails.
+func unreachableRead(ctx context.Context, transport Transport, unreachable *unreachableServers, server string, onUnreachable types.String, resp *resource.ReadResponse) bool {
+	if onUnreachable.ValueString() == "" || onUnreachable.ValueString() == onUnreachableError {
+		return false
+	}
+
+	err := unreachable.probe(ctx, transport, server)
+	if err == nil {
+		return false
+	}
+
+	if onUnreachable.ValueString() == onUnreachableRemove {
+		resp.Diagnostics.AddWarning(
+			"Salt Minion unreachable",
+			fmt.Sprintf("the Salt Minion %s is unreachable, removing the resource from the state as on_unreachable is %s: %s", server, onUnreachableRemove, err),
+		)
+		resp.State.RemoveResource(ctx)
+		return true
+	}
+
+	// the response state starts out as the prior state
+	resp.Diagnostics.AddWarning(
+		"Salt Minion unreachable",
+		fmt.Sprintf("the Salt Minion %s is unreachable, keeping the stored values as on_unreachable is %s: %s", server, onUnreachableKeepState, err),
+	)
+	return true
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ resource.ConfigValidator = onUnreachableValidator{}
+
+// onUnreachableValidator checks on_unreachable at plan time.
+type onUnreachableValidator struct{}
+
+func (v onUnreachableValidator) Description(ctx context.Context) string {
+	return "on_unreachable must be error, keep_state or remove"
+}
+
+func (v onUnreachableValidator) MarkdownDescription(ctx context.Context) string {
+	return "`on_unreachable` must be `error`, `keep_state` or `remove`"
+}
+
+func (v onUnreachableValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var onUnreachable types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("on_unreachable"), &onUnreachable)...)
+	if resp.Diagnostics.HasError() || onUnreachable.IsNull() || onUnreachable.IsUnknown() {
+		return
+	}
+
+	switch onUnreachable.ValueString() {
+	case onUnreachableError, onUnreachableKeepState, onUnreachableRemove:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("on_unreachable"),
+			"Unsupported on_unreachable",
+			fmt.Sprintf("on_unreachable must be %s, %s or %s, got %q", onUnreachableError, onUnreachableKeepState, onUnreachableRemove, onUnreachable.ValueString()),
+		)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"terraform-provider-salty/internal/saltclient"
+	"testing"
+	"time"
+)
+
+// probeTransport answers every command with err, counting the commands.
+type probeTransport struct {
+	err   error
+	calls atomic.Int32
+	// waitsLong is set when a command was run with the full reachability timeout.
+	waitsLong atomic.Bool
+}
+
+func (t *probeTransport) Run(ctx context.Context, server string, runCommand string, timeout time.Duration) (string, error) {
+	t.calls.Add(1)
+	if saltclient.ReachabilityTimeoutFrom(ctx, time.Hour) != unreachableProbeTimeout {
+		t.waitsLong.Store(true)
+	}
+	return "", t.err
+}
+
+func TestUnreachableServersProbe(t *testing.T) {
+	tests := map[string]struct {
+		err             error
+		wantUnreachable bool
+		wantCalls       int32
+	}{
+		"reachable":   {err: nil, wantCalls: 2},
+		"other error": {err: errors.New("permission denied"), wantCalls: 2},
+		"unreachable": {err: &saltclient.UnreachableError{Host: "minion", Port: "22", Err: errors.New("connection refused")}, wantUnreachable: true, wantCalls: 1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			transport := &probeTransport{err: test.err}
+			unreachable := newUnreachableServers()
+
+			// only an unreachable minion is remembered, the second probe must not dial it again
+			for range 2 {
+				err := unreachable.probe(context.Background(), transport, "minion")
+				if (err != nil) != test.wantUnreachable {
+					t.Fatalf("probe() = %v, want unreachable %t", err, test.wantUnreachable)
+				}
+			}
+			if got := transport.calls.Load(); got != test.wantCalls {
+				t.Errorf("the transport ran %d commands, want %d", got, test.wantCalls)
+			}
+			if transport.waitsLong.Load() {
+				t.Errorf("the probe waited for ssh_reachability_timeout instead of %s", unreachableProbeTimeout)
+			}
+		})
+	}
+}
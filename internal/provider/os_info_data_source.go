@@ -0,0 +1,177 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &OSInfoDataSource{}
+
+func NewOSInfoDataSource() datasource.DataSource {
+	return &OSInfoDataSource{}
+}
+
+// OSInfoDataSource defines the data source implementation.
+type OSInfoDataSource struct {
+	transport      Transport
+	uyuni          *UyuniClient
+	minionInstalls *minionInstallCache
+}
+
+// OSInfoDataSourceModel describes the data source data model.
+type OSInfoDataSourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	Server        types.String `tfsdk:"server"`
+	OS            types.String `tfsdk:"os"`
+	OSFamily      types.String `tfsdk:"os_family"`
+	OSRelease     types.String `tfsdk:"osrelease"`
+	OSCodename    types.String `tfsdk:"oscodename"`
+	OSArch        types.String `tfsdk:"osarch"`
+	Kernel        types.String `tfsdk:"kernel"`
+	KernelRelease types.String `tfsdk:"kernelrelease"`
+}
+
+// SaltOSGrainsModel is the grains.item output of the operating system grains.
+type SaltOSGrainsModel struct {
+	Grains struct {
+		OS            string `json:"os"`
+		OSFamily      string `json:"os_family"`
+		OSRelease     string `json:"osrelease"`
+		OSCodename    string `json:"oscodename"`
+		OSArch        string `json:"osarch"`
+		Kernel        string `json:"kernel"`
+		KernelRelease string `json:"kernelrelease"`
+	} `json:"local"`
+}
+
+func (d *OSInfoDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_os_info"
+}
+
+func (d *OSInfoDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Operating system of a Salt Minion, read from its grains",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+			},
+			"os": schema.StringAttribute{
+				MarkdownDescription: "Distribution, e.g. `SUSE` or `Ubuntu`",
+				Computed:            true,
+			},
+			"os_family": schema.StringAttribute{
+				MarkdownDescription: "Family of the distribution, e.g. `Suse`, `Debian` or `RedHat`",
+				Computed:            true,
+			},
+			"osrelease": schema.StringAttribute{
+				MarkdownDescription: "Release of the distribution, e.g. `15.6`",
+				Computed:            true,
+			},
+			"oscodename": schema.StringAttribute{
+				MarkdownDescription: "Code name of the release, e.g. `jammy`",
+				Computed:            true,
+			},
+			"osarch": schema.StringAttribute{
+				MarkdownDescription: "Package architecture, e.g. `x86_64` or `amd64`",
+				Computed:            true,
+			},
+			"kernel": schema.StringAttribute{
+				MarkdownDescription: "Kernel name, e.g. `Linux`",
+				Computed:            true,
+			},
+			"kernelrelease": schema.StringAttribute{
+				MarkdownDescription: "Release of the running kernel",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *OSInfoDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.transport = data.Transport
+	d.uyuni = data.Uyuni
+	d.minionInstalls = data.MinionInstalls
+}
+
+func (d *OSInfoDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data OSInfoDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.Server.ValueString()
+	minion, err := d.minionInstalls.detect(ctx, d.transport, d.uyuni, server, minionID(server))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", server, err),
+		)
+		return
+	}
+
+	runCommand := minion.LocalCommand("grains.item", "os", "os_family", "osrelease", "oscodename", "osarch", "kernel", "kernelrelease")
+	output, err := d.transport.Run(ctx, server, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the OS grains",
+			fmt.Sprintf("cannot read the OS grains on the Salt Minion %s: %s", server, err),
+		)
+		return
+	}
+
+	osGrains := SaltOSGrainsModel{}
+	err = json.Unmarshal(saltJSON(output), &osGrains)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot parse the OS grains",
+			fmt.Sprintf("cannot parse the OS grains on the Salt Minion %s: %s", server, err),
+		)
+		return
+	}
+
+	grains := osGrains.Grains
+	data.Id = types.StringValue(server)
+	data.OS = types.StringValue(grains.OS)
+	data.OSFamily = types.StringValue(grains.OSFamily)
+	data.OSRelease = types.StringValue(grains.OSRelease)
+	data.OSCodename = types.StringValue(grains.OSCodename)
+	data.OSArch = types.StringValue(grains.OSArch)
+	data.Kernel = types.StringValue(grains.Kernel)
+	data.KernelRelease = types.StringValue(grains.KernelRelease)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"golang.org/x/sync/errgroup"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// defaultParallelism is used when parallelism is not configured.
+const defaultParallelism = 10
+
+// forEachServer runs fn for every server, at most parallelism of them at the same time, and
+// returns the error of each server which failed.
+func forEachServer(servers []string, parallelism int, fn func(server string) error) map[string]error {
+	var mu sync.Mutex
+	failed := map[string]error{}
+
+	var g errgroup.Group
+	g.SetLimit(max(parallelism, 1))
+	for _, server := range servers {
+		g.Go(func() error {
+			if err := fn(server); err != nil {
+				mu.Lock()
+				failed[server] = err
+				mu.Unlock()
+			}
+			// one failed server must not stop the others
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return failed
+}
+
+// serverErrors renders the errors of forEachServer as one line per server, sorted by server.
+func serverErrors(failed map[string]error) string {
+	lines := make([]string, 0, len(failed))
+	for server, err := range failed {
+		lines = append(lines, fmt.Sprintf("%s: %s", server, err))
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,52 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"errors"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testAccProtoV6ProviderFactories is used to instantiate a provider during acceptance testing.
+// The factory function is called for each Terraform CLI command to create a provider
+// server that the CLI can connect to and interact with.
+var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"salty": providerserver.NewProtocol6WithError(New("test")()),
+}
+
+// testAccServer is the minion the fixtures are written for and recorded against. Recording
+// needs a Host entry for it in ~/.ssh/config, with the address, user and IdentityFile of a test
+// minion.
+const testAccServer = "minion.example.com"
+
+// testAccProviderConfig configures the provider for a minion without Uyuni, connecting as set
+// up in ~/.ssh/config.
+const testAccProviderConfig = `
+provider "salty" {
+  username                = "salt"
+  use_ssh_config          = true
+  ssh_auth_methods        = []
+  wait_for_key_acceptance = false
+}
+`
+
+// testAccFixture replays the commands of the test from testdata/<name>.jsonl. The fixtures in
+// the tree are written by hand after the salt-call output, see testdata/README.md. With
+// SALTY_RECORD_FIXTURE set, the fixture is recorded against testAccServer instead.
+func testAccFixture(t *testing.T, name string) {
+	fixture := filepath.Join("testdata", name+".jsonl")
+	if _, ok := os.LookupEnv(recordFixtureEnv); ok {
+		if err := os.Remove(fixture); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("cannot remove the previous fixture: %s", err)
+		}
+		t.Setenv(recordFixtureEnv, fixture)
+		return
+	}
+	t.Setenv(replayFixtureEnv, fixture)
+}
@@ -0,0 +1,314 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &ReactorResource{}
+
+func NewReactorResource() resource.Resource {
+	return &ReactorResource{}
+}
+
+// ReactorResource defines the resource implementation.
+type ReactorResource struct {
+	transport  Transport
+	saltMaster string
+}
+
+// ReactorResourceModel describes the resource data model.
+type ReactorResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	Name          types.String `tfsdk:"name"`
+	EventTag      types.String `tfsdk:"event_tag"`
+	Content       types.String `tfsdk:"content"`
+	ReactorDir    types.String `tfsdk:"reactor_dir"`
+	ConfigDir     types.String `tfsdk:"config_dir"`
+	RestartMaster types.Bool   `tfsdk:"restart_master"`
+	Path          types.String `tfsdk:"path"`
+}
+
+// reactorConfig is the master.d drop-in file mapping an event tag to a reactor SLS file. JSON
+// is valid YAML, and the master merges the reactor lists of all its drop-in files.
+type reactorConfig struct {
+	Reactor []map[string][]string `json:"reactor"`
+}
+
+func (r *ReactorResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_reactor"
+}
+
+func (r *ReactorResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Reactor of the Salt master, an SLS file run on an event tag, managed over SSH to the `salt_master` together with its `reactor` drop-in in `master.d`",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the reactor SLS file and of its drop-in file, without suffix.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"event_tag": schema.StringAttribute{
+				MarkdownDescription: "Tag of the events the reactor runs on, globs allowed, e.g. `salt/minion/*/start`.",
+				Required:            true,
+			},
+			"content": schema.StringAttribute{
+				MarkdownDescription: "Content of the reactor SLS file.",
+				Required:            true,
+			},
+			"reactor_dir": schema.StringAttribute{
+				MarkdownDescription: "Directory of the reactor SLS files on the master. Defaults to `/srv/reactor`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("/srv/reactor"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"config_dir": schema.StringAttribute{
+				MarkdownDescription: "Drop-in directory of the master configuration. Defaults to `/etc/salt/master.d`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("/etc/salt/master.d"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"restart_master": schema.BoolAttribute{
+				MarkdownDescription: "Restart the salt-master service when the event tag changes or the reactor is removed, so the master reads its reactor configuration again. A changed SLS file is picked up without a restart.",
+				Optional:            true,
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Location of the reactor SLS file on the master.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *ReactorResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.SaltMaster == "" {
+		resp.Diagnostics.AddError(
+			"Salt master is not configured",
+			"The salty_reactor resource requires salt_master to be set on the provider.",
+		)
+		return
+	}
+
+	r.transport = data.Transport
+	r.saltMaster = data.SaltMaster
+}
+
+func (r *ReactorResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data ReactorResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.writeReactor(ctx, &data, true)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot write the reactor",
+			fmt.Sprintf("cannot write the reactor %s on the Salt master %s: %s", data.Name.ValueString(), r.saltMaster, err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(data.Name.ValueString())
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *ReactorResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data ReactorResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	slsPath := reactorSLSPath(data)
+	configPath := reactorConfigPath(data)
+	runCommand := fmt.Sprintf("if [ -f %[1]s ]; then echo present; cat %[2]s 2>/dev/null | tr -d '\\n'; echo; cat %[1]s; else echo absent; fi", saltclient.ShellQuote(slsPath), saltclient.ShellQuote(configPath))
+	output, err := r.transport.Run(tflog.MaskFieldValuesWithFieldKeys(ctx, logFieldOutput), r.saltMaster, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the reactor",
+			fmt.Sprintf("cannot read the reactor %s on the Salt master %s: %s", slsPath, r.saltMaster, err),
+		)
+		return
+	}
+
+	status, rest, _ := strings.Cut(output, "\n")
+	if status != "present" {
+		tflog.Info(ctx, fmt.Sprintf("reactor %s is gone, removing it from state", slsPath))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	config, content, _ := strings.Cut(rest, "\n")
+
+	// a missing or hand-edited drop-in shows up as a changed event tag, so the apply writes it again
+	data.EventTag = types.StringValue(reactorEventTag(config, slsPath))
+	data.Content = types.StringValue(content)
+	data.Path = types.StringValue(slsPath)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *ReactorResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data, state ReactorResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.writeReactor(ctx, &data, !data.EventTag.Equal(state.EventTag))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot write the reactor",
+			fmt.Sprintf("cannot write the reactor %s on the Salt master %s: %s", data.Name.ValueString(), r.saltMaster, err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *ReactorResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data ReactorResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	runCommand := fmt.Sprintf("rm -f %s %s", saltclient.ShellQuote(reactorConfigPath(data)), saltclient.ShellQuote(reactorSLSPath(data)))
+	if data.RestartMaster.ValueBool() {
+		runCommand = fmt.Sprintf("%s && systemctl restart salt-master", runCommand)
+	}
+
+	_, err := r.transport.Run(mutating(ctx), r.saltMaster, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the reactor",
+			fmt.Sprintf("cannot delete the reactor %s on the Salt master %s: %s", data.Name.ValueString(), r.saltMaster, err),
+		)
+	}
+}
+
+// writeReactor atomically replaces the reactor SLS file and its drop-in, restarting the master
+// if requested and the drop-in changed.
+func (r *ReactorResource) writeReactor(ctx context.Context, data *ReactorResourceModel, configChanged bool) error {
+	slsPath := reactorSLSPath(*data)
+	configPath := reactorConfigPath(*data)
+
+	config, err := json.Marshal(reactorConfig{Reactor: []map[string][]string{{data.EventTag.ValueString(): {slsPath}}}})
+	if err != nil {
+		return err
+	}
+
+	runCommand := fmt.Sprintf("mkdir -p %[1]s %[2]s && printf '%%s' %[3]s > %[4]s.tmp && mv %[4]s.tmp %[4]s && printf '%%s' %[5]s > %[6]s.tmp && mv %[6]s.tmp %[6]s",
+		saltclient.ShellQuote(data.ReactorDir.ValueString()), saltclient.ShellQuote(data.ConfigDir.ValueString()),
+		saltclient.ShellQuote(data.Content.ValueString()), saltclient.ShellQuote(slsPath),
+		saltclient.ShellQuote(string(config)), saltclient.ShellQuote(configPath))
+	if configChanged && data.RestartMaster.ValueBool() {
+		runCommand = fmt.Sprintf("%s && systemctl restart salt-master", runCommand)
+	}
+
+	// the SLS file may carry credentials
+	_, err = r.transport.Run(mutating(redactCommand(ctx)), r.saltMaster, runCommand, 0)
+	if err != nil {
+		return err
+	}
+
+	data.Path = types.StringValue(slsPath)
+	return nil
+}
+
+// reactorEventTag returns the event tag mapped to slsPath in the content of a reactor drop-in,
+// empty when there is none.
+func reactorEventTag(config string, slsPath string) string {
+	var parsed reactorConfig
+	if err := json.Unmarshal([]byte(config), &parsed); err != nil {
+		return ""
+	}
+	for _, reaction := range parsed.Reactor {
+		for tag, files := range reaction {
+			if len(files) == 1 && files[0] == slsPath {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+// reactorSLSPath returns the location of the reactor SLS file of data.
+func reactorSLSPath(data ReactorResourceModel) string {
+	return fmt.Sprintf("%s/%s.sls", data.ReactorDir.ValueString(), data.Name.ValueString())
+}
+
+// reactorConfigPath returns the location of the reactor drop-in of data.
+func reactorConfigPath(data ReactorResourceModel) string {
+	return fmt.Sprintf("%s/reactor_%s.conf", data.ConfigDir.ValueString(), data.Name.ValueString())
+}
@@ -0,0 +1,105 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ ephemeral.EphemeralResourceWithConfigure = &RemoteCommandEphemeralResource{}
+
+func NewRemoteCommandEphemeralResource() ephemeral.EphemeralResource {
+	return &RemoteCommandEphemeralResource{}
+}
+
+// RemoteCommandEphemeralResource defines the ephemeral resource implementation.
+type RemoteCommandEphemeralResource struct {
+	transport Transport
+}
+
+// RemoteCommandEphemeralResourceModel describes the ephemeral resource data model.
+type RemoteCommandEphemeralResourceModel struct {
+	Server  types.String `tfsdk:"server"`
+	Command types.String `tfsdk:"command"`
+	Output  types.String `tfsdk:"output"`
+}
+
+func (r *RemoteCommandEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_remote_command"
+}
+
+func (r *RemoteCommandEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Read-only command run on a Salt Minion during apply. The output is never persisted to state or plan.",
+
+		Attributes: map[string]schema.Attribute{
+			"server": schema.StringAttribute{
+				Required: true,
+			},
+			"command": schema.StringAttribute{
+				MarkdownDescription: "Shell command to run. It must not change the minion, as it runs on every plan and apply.",
+				Required:            true,
+			},
+			"output": schema.StringAttribute{
+				MarkdownDescription: "Standard output of the command.",
+				Computed:            true,
+				Sensitive:           true,
+			},
+		},
+	}
+}
+
+func (r *RemoteCommandEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Ephemeral Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.transport = data.Transport
+}
+
+func (r *RemoteCommandEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var data RemoteCommandEphemeralResourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the output is ephemeral so it is never persisted, the log must not keep it either
+	output, err := r.transport.Run(tflog.MaskFieldValuesWithFieldKeys(ctx, logFieldOutput), data.Server.ValueString(), data.Command.ValueString(), 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot run the remote command",
+			fmt.Sprintf("cannot run the remote command on the Salt Minion %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	tflog.Info(ctx, "opened an ephemeral resource")
+
+	data.Output = types.StringValue(output)
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
@@ -0,0 +1,180 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strconv"
+	"strings"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &SaltVersionDataSource{}
+
+func NewSaltVersionDataSource() datasource.DataSource {
+	return &SaltVersionDataSource{}
+}
+
+// SaltVersionDataSource defines the data source implementation.
+type SaltVersionDataSource struct {
+	transport      Transport
+	uyuni          *UyuniClient
+	minionInstalls *minionInstallCache
+}
+
+// SaltVersionDataSourceModel describes the data source data model.
+type SaltVersionDataSourceModel struct {
+	Id      types.String `tfsdk:"id"`
+	Server  types.String `tfsdk:"server"`
+	Version types.String `tfsdk:"version"`
+	Major   types.Int64  `tfsdk:"major"`
+	Minor   types.Int64  `tfsdk:"minor"`
+}
+
+type SaltVersionModel struct {
+	Version string `json:"local"`
+}
+
+func (d *SaltVersionDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_salt_version"
+}
+
+func (d *SaltVersionDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt release installed on a Salt Minion",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+			},
+			"version": schema.StringAttribute{
+				MarkdownDescription: "Salt version reported by `test.version`, e.g. `3006.9`",
+				Computed:            true,
+			},
+			"major": schema.Int64Attribute{
+				MarkdownDescription: "Major release number, e.g. `3006`",
+				Computed:            true,
+			},
+			"minor": schema.Int64Attribute{
+				MarkdownDescription: "Minor release number, e.g. `9`. Zero when the version has no minor part.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *SaltVersionDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.transport = data.Transport
+	d.uyuni = data.Uyuni
+	d.minionInstalls = data.MinionInstalls
+}
+
+func (d *SaltVersionDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data SaltVersionDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.Server.ValueString()
+	minion, err := d.minionInstalls.detect(ctx, d.transport, d.uyuni, server, minionID(server))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", server, err),
+		)
+		return
+	}
+
+	runCommand := minion.LocalCommand("test.version")
+	output, err := d.transport.Run(ctx, server, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the Salt version",
+			fmt.Sprintf("cannot read the Salt version on the Salt Minion %s: %s", server, err),
+		)
+		return
+	}
+
+	saltVersion := SaltVersionModel{}
+	err = json.Unmarshal(saltJSON(output), &saltVersion)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot parse the Salt version",
+			fmt.Sprintf("cannot parse the Salt version on the Salt Minion %s: %s", server, err),
+		)
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Salt version on %s: %s", server, saltVersion.Version))
+
+	major, minor, err := parseSaltVersion(saltVersion.Version)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot parse the Salt version",
+			fmt.Sprintf("cannot parse the Salt version %q on the Salt Minion %s: %s", saltVersion.Version, server, err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(server)
+	data.Version = types.StringValue(saltVersion.Version)
+	data.Major = types.Int64Value(major)
+	data.Minor = types.Int64Value(minor)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// parseSaltVersion splits a Salt version such as 3006.9 or 3007.1rc1 into its numeric parts.
+func parseSaltVersion(version string) (int64, int64, error) {
+	majorPart, minorPart, _ := strings.Cut(version, ".")
+	major, err := strconv.ParseInt(majorPart, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if i := strings.IndexFunc(minorPart, func(c rune) bool { return c < '0' || c > '9' }); i >= 0 {
+		minorPart = minorPart[:i]
+	}
+	if minorPart == "" {
+		return major, 0, nil
+	}
+
+	minor, err := strconv.ParseInt(minorPart, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return major, minor, nil
+}
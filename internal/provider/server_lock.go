@@ -0,0 +1,33 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"sync"
+)
+
+// serverLocks serializes the operations changing the same server, so concurrent salt-call runs
+// do not interleave on one minion while different servers are still handled in parallel.
+type serverLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newServerLocks() *serverLocks {
+	return &serverLocks{locks: map[string]*sync.Mutex{}}
+}
+
+// lock blocks until server is free and returns the function releasing it.
+func (l *serverLocks) lock(server string) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[server]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.locks[server] = lock
+	}
+	l.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// defaultReachabilityTimeout is used when ssh_reachability_timeout is not configured.
+const defaultReachabilityTimeout = 2 * time.Minute
+
+// newSSHExecutor returns executor logging its commands and reporting its connections and
+// commands to measured.
+func newSSHExecutor(executor *saltclient.SSHExecutor, measured *timings) *saltclient.SSHExecutor {
+	executor.OnDial = func(ctx context.Context, server string, elapsed time.Duration) {
+		measured.record(ctx, timingSSHDial, elapsed, map[string]string{logFieldServer: server})
+	}
+	// the command carries grain values and file contents, it is only logged with the values
+	// redacted, TF_LOG=DEBUG shows it
+	executor.OnCommand = func(ctx context.Context, server string, runCommand string) {
+		tflog.Debug(ctx, "running a remote command", map[string]any{logFieldServer: server, logFieldCommand: runCommand})
+	}
+	executor.OnOutput = func(ctx context.Context, server string, output string, elapsed time.Duration) {
+		measured.record(ctx, timingCommand, elapsed, map[string]string{logFieldServer: server})
+		tflog.Trace(ctx, "remote command output", map[string]any{logFieldServer: server, logFieldOutput: output})
+	}
+	return executor
+}
+
+// minionID returns the Salt minion ID of server, its address without a port.
+func minionID(server string) string {
+	host, _ := saltclient.SplitServerAddress(server)
+	return host
+}
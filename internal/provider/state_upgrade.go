@@ -0,0 +1,71 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+// stateUpgrade rewrites the attributes of a state from one schema version to the next.
+type stateUpgrade func(attributes map[string]any) error
+
+// unchangedState is the upgrade of a schema version whose attributes stay as they are.
+func unchangedState(attributes map[string]any) error {
+	return nil
+}
+
+// stateUpgraders returns the state upgraders of a resource whose schema version is
+// len(upgrades). The state of version v goes through upgrades[v:] in order, so each schema
+// change only adds the step from the previous version.
+func stateUpgraders(upgrades ...stateUpgrade) map[int64]resource.StateUpgrader {
+	upgraders := map[int64]resource.StateUpgrader{}
+	for version := range upgrades {
+		pending := upgrades[version:]
+		upgraders[int64(version)] = resource.StateUpgrader{
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				if req.RawState == nil || req.RawState.JSON == nil {
+					resp.Diagnostics.AddError(
+						"Cannot upgrade the resource state",
+						fmt.Sprintf("the schema version %d state is not stored as JSON, please report this issue to the provider developers", version),
+					)
+					return
+				}
+
+				var attributes map[string]any
+				if err := json.Unmarshal(req.RawState.JSON, &attributes); err != nil {
+					resp.Diagnostics.AddError(
+						"Cannot upgrade the resource state",
+						fmt.Sprintf("cannot parse the schema version %d state: %s", version, err),
+					)
+					return
+				}
+
+				for _, upgrade := range pending {
+					if err := upgrade(attributes); err != nil {
+						resp.Diagnostics.AddError(
+							"Cannot upgrade the resource state",
+							fmt.Sprintf("cannot upgrade the schema version %d state: %s", version, err),
+						)
+						return
+					}
+				}
+
+				upgraded, err := json.Marshal(attributes)
+				if err != nil {
+					resp.Diagnostics.AddError(
+						"Cannot upgrade the resource state",
+						fmt.Sprintf("cannot encode the upgraded schema version %d state: %s", version, err),
+					)
+					return
+				}
+				resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+			},
+		}
+	}
+	return upgraders
+}
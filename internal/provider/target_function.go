@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"strings"
+	"unicode"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &TargetFunction{}
+
+func NewTargetFunction() function.Function {
+	return &TargetFunction{}
+}
+
+// TargetFunction builds the compound target matching a grain value.
+type TargetFunction struct{}
+
+func (f *TargetFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "target"
+}
+
+func (f *TargetFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Salt compound target of a grain value",
+		MarkdownDescription: "Returns the Salt compound target matching the minions whose grain `grain_key` holds `value`, e.g. `G@roles:webserver`. Nested grain keys are written with colons.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "grain_key",
+				MarkdownDescription: "Grain to match, e.g. `roles`.",
+			},
+			function.StringParameter{
+				Name:                "value",
+				MarkdownDescription: "Value the grain must hold. Globs like `web*` are allowed.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *TargetFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var grainKey, value string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &grainKey, &value))
+	if resp.Error != nil {
+		return
+	}
+
+	// the compound matcher splits its expression at whitespace
+	for i, argument := range []string{grainKey, value} {
+		if argument == "" || strings.IndexFunc(argument, unicode.IsSpace) >= 0 {
+			resp.Error = function.NewArgumentFuncError(int64(i), fmt.Sprintf("%q must not be empty or contain whitespace", argument))
+			return
+		}
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, fmt.Sprintf("G@%s:%s", grainKey, value)))
+}
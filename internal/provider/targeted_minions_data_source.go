@@ -0,0 +1,178 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &TargetedMinionsDataSource{}
+
+func NewTargetedMinionsDataSource() datasource.DataSource {
+	return &TargetedMinionsDataSource{}
+}
+
+// TargetedMinionsDataSource defines the data source implementation.
+type TargetedMinionsDataSource struct {
+	transport  Transport
+	saltMaster string
+}
+
+// TargetedMinionsDataSourceModel describes the data source data model.
+type TargetedMinionsDataSourceModel struct {
+	Id      types.String `tfsdk:"id"`
+	Target  types.String `tfsdk:"target"`
+	Match   types.String `tfsdk:"match"`
+	Minions types.List   `tfsdk:"minions"`
+}
+
+// saltTargetFlags maps the matchers of the match attribute to the salt command line flags.
+var saltTargetFlags = map[string]string{
+	"glob":        "",
+	"pcre":        "-E",
+	"list":        "-L",
+	"grain":       "-G",
+	"grain_pcre":  "--grain-pcre",
+	"pillar":      "-I",
+	"pillar_pcre": "--pillar-pcre",
+	"nodegroup":   "-N",
+	"ipcidr":      "-S",
+	"compound":    "-C",
+}
+
+func (d *TargetedMinionsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_targeted_minions"
+}
+
+func (d *TargetedMinionsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt minions matching a target, resolved by the `salt_master` without contacting the minions",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"target": schema.StringAttribute{
+				MarkdownDescription: "Target expression, e.g. `roles:webserver` or `G@os:SUSE and web*`.",
+				Required:            true,
+			},
+			"match": schema.StringAttribute{
+				MarkdownDescription: "Matcher used for `target`: `glob`, `pcre`, `list`, `grain`, `grain_pcre`, `pillar`, `pillar_pcre`, `nodegroup`, `ipcidr` or `compound`. Defaults to `glob`.",
+				Optional:            true,
+			},
+			"minions": schema.ListAttribute{
+				MarkdownDescription: "Minion IDs matched by the target, sorted.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *TargetedMinionsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.transport = data.Transport
+	d.saltMaster = data.SaltMaster
+}
+
+func (d *TargetedMinionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TargetedMinionsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if d.saltMaster == "" {
+		resp.Diagnostics.AddError(
+			"Salt master is not configured",
+			"The salty_targeted_minions data source requires salt_master to be set on the provider.",
+		)
+		return
+	}
+
+	match := data.Match.ValueString()
+	if match == "" {
+		match = "glob"
+	}
+	flag, ok := saltTargetFlags[match]
+	if !ok {
+		var matchers []string
+		for matcher := range saltTargetFlags {
+			matchers = append(matchers, matcher)
+		}
+		slices.Sort(matchers)
+		resp.Diagnostics.AddError(
+			"Unsupported matcher",
+			fmt.Sprintf("The matcher %s is not supported, use one of %s.", match, strings.Join(matchers, ", ")),
+		)
+		return
+	}
+
+	// --preview-target matches against the master's cache, so minions which are down are
+	// listed as well and nothing runs on them
+	runCommand := fmt.Sprintf("salt --preview-target --out=json %s %s", flag, saltclient.ShellQuote(data.Target.ValueString()))
+	output, err := d.transport.Run(ctx, d.saltMaster, runCommand, 0)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot resolve the target",
+			fmt.Sprintf("cannot resolve the target %s on the Salt master %s: %s", data.Target.ValueString(), d.saltMaster, err),
+		)
+		return
+	}
+
+	minions := []string{}
+	if strings.TrimSpace(output) != "" {
+		if err := json.Unmarshal([]byte(output), &minions); err != nil {
+			resp.Diagnostics.AddError(
+				"Cannot parse the targeted minions",
+				fmt.Sprintf("cannot parse the minions matched by %s on the Salt master %s: %s", data.Target.ValueString(), d.saltMaster, err),
+			)
+			return
+		}
+	}
+	slices.Sort(minions)
+
+	tflog.Info(ctx, fmt.Sprintf("target %s matches %v", data.Target.ValueString(), minions))
+
+	listVal, diags := types.ListValueFrom(ctx, types.StringType, minions)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Minions = listVal
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", match, data.Target.ValueString()))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,259 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// otlpExportInterval is how often the measured timings are sent to the otlp_endpoint.
+const otlpExportInterval = 10 * time.Second
+
+// timingMetric is a duration measured to diagnose slow applies, logged as field in unit and
+// exported as the OTLP metric name.
+type timingMetric struct {
+	field string
+	name  string
+	unit  time.Duration
+}
+
+var (
+	timingSSHDial    = timingMetric{field: "ssh_dial_ms", name: "salty.ssh.dial.duration", unit: time.Millisecond}
+	timingCommand    = timingMetric{field: "command_ms", name: "salty.command.duration", unit: time.Millisecond}
+	timingWaitMinion = timingMetric{field: "wait_minion_s", name: "salty.minion.wait.duration", unit: time.Second}
+	timingUyuniCall  = timingMetric{field: "uyuni_call_ms", name: "salty.uyuni.call.duration", unit: time.Millisecond}
+)
+
+// unitName returns the UCUM unit of the metric.
+func (m timingMetric) unitName() string {
+	if m.unit == time.Second {
+		return "s"
+	}
+	return "ms"
+}
+
+// timings logs the measured durations through tflog and, with otlp_endpoint, exports them as
+// OTLP metrics. A nil timings measures nothing.
+type timings struct {
+	// ctx logs the measurements taken outside of a request, such as the Uyuni API calls.
+	ctx      context.Context
+	exporter *otlpExporter
+}
+
+// record logs elapsed as m together with attributes, e.g. the server, and queues it for the
+// export.
+func (t *timings) record(ctx context.Context, m timingMetric, elapsed time.Duration, attributes map[string]string) {
+	if t == nil {
+		return
+	}
+
+	value := float64(elapsed) / float64(m.unit)
+	fields := map[string]any{m.field: value}
+	for key, attribute := range attributes {
+		fields[key] = attribute
+	}
+	tflog.Debug(ctx, "measured a timing", fields)
+
+	if t.exporter != nil {
+		t.exporter.add(otlpMeasurement{metric: m, value: value, at: time.Now(), attributes: attributes})
+	}
+}
+
+// recordDetached records a measurement taken without a request context.
+func (t *timings) recordDetached(m timingMetric, elapsed time.Duration, attributes map[string]string) {
+	if t == nil {
+		return
+	}
+	t.record(t.ctx, m, elapsed, attributes)
+}
+
+// otlpMeasurement is a timing waiting for the export.
+type otlpMeasurement struct {
+	metric     timingMetric
+	value      float64
+	at         time.Time
+	attributes map[string]string
+}
+
+// otlpExporter sends the timings as gauges to an OTLP/HTTP collector with the JSON encoding,
+// every otlpExportInterval and once more when the provider exits.
+type otlpExporter struct {
+	url    string
+	ctx    context.Context
+	client *http.Client
+
+	mu      sync.Mutex
+	pending []otlpMeasurement
+}
+
+// otlpExporters are flushed by FlushMetrics, each configured provider has its own.
+var otlpExporters struct {
+	mu        sync.Mutex
+	exporters []*otlpExporter
+}
+
+// newOTLPExporter returns an exporter to the collector at endpoint, e.g.
+// http://localhost:4318, logging export failures with ctx.
+func newOTLPExporter(ctx context.Context, endpoint string) *otlpExporter {
+	e := &otlpExporter{
+		url:    strings.TrimRight(endpoint, "/") + "/v1/metrics",
+		ctx:    ctx,
+		client: &http.Client{Timeout: otlpExportInterval},
+	}
+
+	otlpExporters.mu.Lock()
+	otlpExporters.exporters = append(otlpExporters.exporters, e)
+	otlpExporters.mu.Unlock()
+
+	go func() {
+		for range time.Tick(otlpExportInterval) {
+			e.flush(context.Background())
+		}
+	}()
+	return e
+}
+
+// FlushMetrics sends the timings not exported yet, called once the provider server stops.
+func FlushMetrics(ctx context.Context) {
+	otlpExporters.mu.Lock()
+	exporters := otlpExporters.exporters
+	otlpExporters.mu.Unlock()
+
+	for _, e := range exporters {
+		e.flush(ctx)
+	}
+}
+
+func (e *otlpExporter) add(measurement otlpMeasurement) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, measurement)
+}
+
+// flush sends the pending timings. A failed export is only logged, the timings are dropped
+// rather than failing or slowing down the apply.
+func (e *otlpExporter) flush(ctx context.Context) {
+	e.mu.Lock()
+	pending := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(otlpMetricsRequest(pending))
+	if err == nil {
+		err = e.post(ctx, payload)
+	}
+	if err != nil {
+		tflog.Warn(e.ctx, fmt.Sprintf("cannot export %d timings to %s: %s", len(pending), e.url, err))
+	}
+}
+
+func (e *otlpExporter) post(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("the collector returned %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
+
+// otlpKeyValue is an attribute in the OTLP JSON encoding.
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpDataPoint struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     float64        `json:"asDouble"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpMetric struct {
+	Name  string `json:"name"`
+	Unit  string `json:"unit"`
+	Gauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	} `json:"gauge"`
+}
+
+type otlpScopeMetrics struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Metrics []*otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpExportRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+// otlpAttributes returns attributes in the OTLP JSON encoding.
+func otlpAttributes(attributes map[string]string) []otlpKeyValue {
+	var encoded []otlpKeyValue
+	for key, value := range attributes {
+		kv := otlpKeyValue{Key: key}
+		kv.Value.StringValue = value
+		encoded = append(encoded, kv)
+	}
+	return encoded
+}
+
+// otlpMetricsRequest builds the export request of measurements, a gauge per metric.
+func otlpMetricsRequest(measurements []otlpMeasurement) otlpExportRequest {
+	var metrics []*otlpMetric
+	byName := map[string]*otlpMetric{}
+	for _, measurement := range measurements {
+		metric, ok := byName[measurement.metric.name]
+		if !ok {
+			metric = &otlpMetric{Name: measurement.metric.name, Unit: measurement.metric.unitName()}
+			byName[measurement.metric.name] = metric
+			metrics = append(metrics, metric)
+		}
+		metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, otlpDataPoint{
+			TimeUnixNano: strconv.FormatInt(measurement.at.UnixNano(), 10),
+			AsDouble:     measurement.value,
+			Attributes:   otlpAttributes(measurement.attributes),
+		})
+	}
+
+	scope := otlpScopeMetrics{Metrics: metrics}
+	scope.Scope.Name = "terraform-provider-salty"
+	resource := otlpResourceMetrics{ScopeMetrics: []otlpScopeMetrics{scope}}
+	resource.Resource.Attributes = otlpAttributes(map[string]string{"service.name": "terraform-provider-salty"})
+	return otlpExportRequest{ResourceMetrics: []otlpResourceMetrics{resource}}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+// topFileEntry is a single target of a top file environment.
+type topFileEntry struct {
+	Match  string
+	States []string
+}
+
+// topFile is a parsed top.sls. The YAML node tree is edited in place, so comments and the
+// order of entries not managed by the provider survive a round trip.
+type topFile struct {
+	doc yaml.Node
+}
+
+func parseTopFile(content string) (*topFile, error) {
+	t := &topFile{}
+	if err := yaml.Unmarshal([]byte(content), &t.doc); err != nil {
+		return nil, fmt.Errorf("cannot parse the top file, templated top files are not supported: %w", err)
+	}
+
+	if t.doc.Kind == 0 {
+		// empty file
+		t.doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if len(t.doc.Content) != 1 || t.doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("cannot parse the top file: expected a mapping of environments")
+	}
+
+	return t, nil
+}
+
+func (t *topFile) String() (string, error) {
+	out, err := yaml.Marshal(&t.doc)
+	if err != nil {
+		return "", fmt.Errorf("cannot render the top file: %w", err)
+	}
+	return string(out), nil
+}
+
+// mappingValue returns the value node of key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKey removes key from a mapping node.
+func removeMappingKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// Get returns the entry for target in saltenv.
+func (t *topFile) Get(saltenv, target string) (topFileEntry, bool, error) {
+	env := mappingValue(t.doc.Content[0], saltenv)
+	if env == nil || env.Kind != yaml.MappingNode {
+		return topFileEntry{}, false, nil
+	}
+
+	node := mappingValue(env, target)
+	if node == nil {
+		return topFileEntry{}, false, nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		return topFileEntry{}, true, fmt.Errorf("target %s in environment %s is not a list of states", target, saltenv)
+	}
+
+	entry := topFileEntry{States: []string{}}
+	for _, item := range node.Content {
+		switch item.Kind {
+		case yaml.ScalarNode:
+			entry.States = append(entry.States, item.Value)
+		case yaml.MappingNode:
+			if match := mappingValue(item, "match"); match != nil {
+				entry.Match = match.Value
+			}
+		}
+	}
+
+	return entry, true, nil
+}
+
+// Set adds or replaces the entry for target in saltenv.
+func (t *topFile) Set(saltenv, target string, entry topFileEntry) {
+	root := t.doc.Content[0]
+	env := mappingValue(root, saltenv)
+	if env == nil || env.Kind != yaml.MappingNode {
+		removeMappingKey(root, saltenv)
+		env = &yaml.Node{Kind: yaml.MappingNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: saltenv}, env)
+	}
+
+	states := &yaml.Node{Kind: yaml.SequenceNode}
+	if entry.Match != "" {
+		states.Content = append(states.Content, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "match"},
+			{Kind: yaml.ScalarNode, Value: entry.Match},
+		}})
+	}
+	for _, state := range entry.States {
+		states.Content = append(states.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: state})
+	}
+
+	if node := mappingValue(env, target); node != nil {
+		*node = *states
+		return
+	}
+	env.Content = append(env.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: target, Style: yaml.SingleQuotedStyle}, states)
+}
+
+// Add adds the entry for target in saltenv, which must not be in the top file yet.
+func (t *topFile) Add(saltenv, target string, entry topFileEntry) error {
+	_, found, err := t.Get(saltenv, target)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("target %s already exists in environment %s, remove it from the top file or manage it with a single resource", target, saltenv)
+	}
+
+	t.Set(saltenv, target, entry)
+	return nil
+}
+
+// Remove deletes the entry for target in saltenv, and the environment once it is empty.
+func (t *topFile) Remove(saltenv, target string) {
+	root := t.doc.Content[0]
+	env := mappingValue(root, saltenv)
+	if env == nil || env.Kind != yaml.MappingNode {
+		return
+	}
+
+	removeMappingKey(env, target)
+	if len(env.Content) == 0 {
+		removeMappingKey(root, saltenv)
+	}
+}
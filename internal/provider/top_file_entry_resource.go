@@ -0,0 +1,344 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &TopFileEntryResource{}
+var _ resource.ResourceWithUpgradeState = &TopFileEntryResource{}
+var _ resource.ResourceWithModifyPlan = &TopFileEntryResource{}
+
+func NewTopFileEntryResource() resource.Resource {
+	return &TopFileEntryResource{}
+}
+
+// TopFileEntryResource defines the resource implementation.
+type TopFileEntryResource struct {
+	transport      Transport
+	serverLocks    *serverLocks
+	saltMaster     string
+	defaultSaltenv string
+}
+
+// TopFileEntryResourceModel describes the resource data model.
+type TopFileEntryResourceModel struct {
+	Id      types.String `tfsdk:"id"`
+	TopFile types.String `tfsdk:"top_file"`
+	Saltenv types.String `tfsdk:"saltenv"`
+	Target  types.String `tfsdk:"target"`
+	Match   types.String `tfsdk:"match"`
+	States  types.List   `tfsdk:"states"`
+}
+
+func (r *TopFileEntryResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_top_file_entry"
+}
+
+func (r *TopFileEntryResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Entry of the Salt master's top file, managed over SSH to the `salt_master`",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"top_file": schema.StringAttribute{
+				MarkdownDescription: "Location of the top file on the master. Defaults to `/srv/salt/top.sls`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("/srv/salt/top.sls"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"saltenv": schema.StringAttribute{
+				MarkdownDescription: "Salt environment of the entry. Defaults to the provider's `default_saltenv`, `base` when that is not set either.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"target": schema.StringAttribute{
+				MarkdownDescription: "Match expression selecting the minions, e.g. `roles:webserver`.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"match": schema.StringAttribute{
+				MarkdownDescription: "Matcher used for `target`, e.g. `grain` or `compound`. Salt defaults to `glob`.",
+				Optional:            true,
+			},
+			"states": schema.ListAttribute{
+				MarkdownDescription: "States applied to the targeted minions.",
+				ElementType:         types.StringType,
+				Required:            true,
+			},
+		},
+	}
+}
+
+// UpgradeState migrates states written with earlier schema versions.
+func (r *TopFileEntryResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return stateUpgraders(
+		// 0 -> 1: schema versioning introduced, attributes unchanged
+		unchangedState,
+	)
+}
+
+func (r *TopFileEntryResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.SaltMaster == "" {
+		resp.Diagnostics.AddError(
+			"Salt master is not configured",
+			"The salty_top_file_entry resource requires salt_master to be set on the provider.",
+		)
+		return
+	}
+
+	r.transport = data.Transport
+	r.serverLocks = data.ServerLocks
+	r.saltMaster = data.SaltMaster
+	r.defaultSaltenv = data.DefaultSaltenv
+}
+
+// ModifyPlan fills in the saltenv of new entries, which is inherited from the provider.
+func (r *TopFileEntryResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var saltenv types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("saltenv"), &saltenv)...)
+	if resp.Diagnostics.HasError() || !saltenv.IsUnknown() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("saltenv"), cmp.Or(r.defaultSaltenv, "base"))...)
+}
+
+func (r *TopFileEntryResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data TopFileEntryResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.editTopFile(ctx, data.TopFile.ValueString(), func(top *topFile) error {
+		return top.Add(data.Saltenv.ValueString(), data.Target.ValueString(), topFileEntryFromModel(data))
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot add the top file entry",
+			fmt.Sprintf("cannot add the top file entry to %s on the Salt master %s: %s", data.TopFile.ValueString(), r.saltMaster, err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Saltenv.ValueString(), data.Target.ValueString()))
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *TopFileEntryResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data TopFileEntryResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	top, _, err := r.readTopFile(ctx, data.TopFile.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the top file",
+			fmt.Sprintf("cannot read the top file %s on the Salt master %s: %s", data.TopFile.ValueString(), r.saltMaster, err),
+		)
+		return
+	}
+
+	entry, found, err := top.Get(data.Saltenv.ValueString(), data.Target.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the top file entry",
+			fmt.Sprintf("cannot read the top file entry in %s on the Salt master %s: %s", data.TopFile.ValueString(), r.saltMaster, err),
+		)
+		return
+	}
+	if !found {
+		tflog.Info(ctx, fmt.Sprintf("target %s is gone from the top file, removing it from state", data.Target.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	states, diags := types.ListValueFrom(ctx, types.StringType, entry.States)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.States = states
+	if entry.Match != "" || !data.Match.IsNull() {
+		data.Match = types.StringValue(entry.Match)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *TopFileEntryResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data TopFileEntryResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.editTopFile(ctx, data.TopFile.ValueString(), func(top *topFile) error {
+		top.Set(data.Saltenv.ValueString(), data.Target.ValueString(), topFileEntryFromModel(data))
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot update the top file entry",
+			fmt.Sprintf("cannot update the top file entry in %s on the Salt master %s: %s", data.TopFile.ValueString(), r.saltMaster, err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *TopFileEntryResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data TopFileEntryResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.editTopFile(ctx, data.TopFile.ValueString(), func(top *topFile) error {
+		top.Remove(data.Saltenv.ValueString(), data.Target.ValueString())
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the top file entry",
+			fmt.Sprintf("cannot delete the top file entry from %s on the Salt master %s: %s", data.TopFile.ValueString(), r.saltMaster, err),
+		)
+	}
+}
+
+func topFileEntryFromModel(data TopFileEntryResourceModel) topFileEntry {
+	entry := topFileEntry{Match: data.Match.ValueString()}
+	for _, state := range data.States.Elements() {
+		if str, ok := state.(types.String); ok {
+			entry.States = append(entry.States, str.ValueString())
+		}
+	}
+	return entry
+}
+
+// readTopFile fetches and parses the top file, together with the checksum of its content.
+func (r *TopFileEntryResource) readTopFile(ctx context.Context, topFilePath string) (*topFile, string, error) {
+	runCommand := fmt.Sprintf("cat %[1]s 2>/dev/null | sha256sum | cut -d' ' -f1; cat %[1]s 2>/dev/null || true", saltclient.ShellQuote(topFilePath))
+	output, err := r.transport.Run(ctx, r.saltMaster, runCommand, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	checksum, content, _ := strings.Cut(output, "\n")
+	top, err := parseTopFile(content)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return top, checksum, nil
+}
+
+// editTopFile applies edit to the top file and writes it back, unless the file was changed by
+// someone else in the meantime. The entries of one top file are edited one at a time, so the
+// resources of a single apply do not fail each other's checksum check.
+func (r *TopFileEntryResource) editTopFile(ctx context.Context, topFilePath string, edit func(top *topFile) error) error {
+	defer r.serverLocks.lock(r.saltMaster + ":" + topFilePath)()
+
+	top, checksum, err := r.readTopFile(ctx, topFilePath)
+	if err != nil {
+		return err
+	}
+
+	if err := edit(top); err != nil {
+		return err
+	}
+
+	content, err := top.String()
+	if err != nil {
+		return err
+	}
+
+	path := saltclient.ShellQuote(topFilePath)
+	runCommand := fmt.Sprintf("if [ \"$(cat %[1]s 2>/dev/null | sha256sum | cut -d' ' -f1)\" != %[2]s ]; then echo 'top file changed concurrently' >&2; exit 3; fi; printf '%%s' %[3]s > %[1]s.tmp && mv %[1]s.tmp %[1]s",
+		path, saltclient.ShellQuote(checksum), saltclient.ShellQuote(content))
+	_, err = r.transport.Run(mutating(ctx), r.saltMaster, runCommand, 0)
+	if err != nil {
+		return fmt.Errorf("%s, the top file may have been changed concurrently, retry the apply", err)
+	}
+
+	return nil
+}
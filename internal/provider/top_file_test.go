@@ -0,0 +1,158 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testTopFile = `# managed in part by Terraform
+base:
+  # every minion
+  '*':
+    - common
+  'web*':
+    - match: glob
+    - nginx
+    - php
+dev:
+  'db*':
+    - postgres
+`
+
+func mustParseTopFile(t *testing.T, content string) *topFile {
+	t.Helper()
+	top, err := parseTopFile(content)
+	if err != nil {
+		t.Fatalf("cannot parse the top file: %s", err)
+	}
+	return top
+}
+
+func mustRenderTopFile(t *testing.T, top *topFile) string {
+	t.Helper()
+	content, err := top.String()
+	if err != nil {
+		t.Fatalf("cannot render the top file: %s", err)
+	}
+	return content
+}
+
+func TestParseTopFile(t *testing.T) {
+	top := mustParseTopFile(t, testTopFile)
+
+	entry, found, err := top.Get("base", "web*")
+	if err != nil || !found {
+		t.Fatalf("Get(base, web*) = %v, %v", found, err)
+	}
+	if entry.Match != "glob" || !slices.Equal(entry.States, []string{"nginx", "php"}) {
+		t.Errorf("Get(base, web*) = %+v", entry)
+	}
+
+	if _, found, _ := top.Get("prod", "web*"); found {
+		t.Errorf("Get(prod, web*) found an entry in a missing environment")
+	}
+	if _, found, _ := top.Get("dev", "web*"); found {
+		t.Errorf("Get(dev, web*) found an entry of another environment")
+	}
+
+	top = mustParseTopFile(t, "")
+	if _, found, _ := top.Get("base", "*"); found {
+		t.Errorf("Get(base, *) found an entry in an empty top file")
+	}
+
+	for _, content := range []string{"- base", "base: [", "{% for env in envs %}"} {
+		if _, err := parseTopFile(content); err == nil {
+			t.Errorf("parseTopFile(%q) succeeded, want an error", content)
+		}
+	}
+
+	top = mustParseTopFile(t, "base:\n  '*': common\n")
+	if _, _, err := top.Get("base", "*"); err == nil {
+		t.Errorf("Get of a target which is not a list of states succeeded, want an error")
+	}
+}
+
+func TestTopFileSet(t *testing.T) {
+	top := mustParseTopFile(t, testTopFile)
+
+	top.Set("base", "web*", topFileEntry{States: []string{"apache"}})
+	top.Set("prod", "app*", topFileEntry{Match: "pcre", States: []string{"app"}})
+
+	entry, _, _ := top.Get("base", "web*")
+	if entry.Match != "" || !slices.Equal(entry.States, []string{"apache"}) {
+		t.Errorf("the replaced entry is %+v", entry)
+	}
+	entry, _, _ = top.Get("prod", "app*")
+	if entry.Match != "pcre" || !slices.Equal(entry.States, []string{"app"}) {
+		t.Errorf("the added entry is %+v", entry)
+	}
+
+	// the rendered file parses back to the same entries
+	top = mustParseTopFile(t, mustRenderTopFile(t, top))
+	if entry, found, _ := top.Get("prod", "app*"); !found || entry.Match != "pcre" {
+		t.Errorf("the added entry is lost in a round trip: %+v", entry)
+	}
+}
+
+func TestTopFileAdd(t *testing.T) {
+	top := mustParseTopFile(t, testTopFile)
+
+	if err := top.Add("base", "web*", topFileEntry{States: []string{"apache"}}); err == nil {
+		t.Errorf("Add of an existing target succeeded, want a conflict")
+	}
+	if entry, _, _ := top.Get("base", "web*"); !slices.Equal(entry.States, []string{"nginx", "php"}) {
+		t.Errorf("a conflicting Add changed the entry to %+v", entry)
+	}
+
+	// the same target in another environment is another entry
+	if err := top.Add("dev", "web*", topFileEntry{States: []string{"apache"}}); err != nil {
+		t.Errorf("Add(dev, web*) = %s", err)
+	}
+
+	top = mustParseTopFile(t, "base:\n  '*': common\n")
+	if err := top.Add("base", "*", topFileEntry{States: []string{"common"}}); err == nil {
+		t.Errorf("Add over a target which is not a list of states succeeded, want an error")
+	}
+}
+
+func TestTopFileRemove(t *testing.T) {
+	top := mustParseTopFile(t, testTopFile)
+
+	top.Remove("base", "web*")
+	top.Remove("dev", "db*")
+	top.Remove("prod", "app*")
+
+	if _, found, _ := top.Get("base", "web*"); found {
+		t.Errorf("Remove(base, web*) left the entry")
+	}
+	if _, found, _ := top.Get("base", "*"); !found {
+		t.Errorf("Remove(base, web*) removed another entry")
+	}
+
+	content := mustRenderTopFile(t, top)
+	if strings.Contains(content, "dev:") {
+		t.Errorf("the environment emptied by Remove is kept:\n%s", content)
+	}
+}
+
+func TestTopFileComments(t *testing.T) {
+	top := mustParseTopFile(t, testTopFile)
+
+	top.Set("base", "web*", topFileEntry{States: []string{"apache"}})
+	top.Set("prod", "app*", topFileEntry{States: []string{"app"}})
+
+	content := mustRenderTopFile(t, top)
+	for _, comment := range []string{"# managed in part by Terraform", "# every minion"} {
+		if !strings.Contains(content, comment) {
+			t.Errorf("the comment %q is lost:\n%s", comment, content)
+		}
+	}
+	// the entries not managed here keep their order
+	if strings.Index(content, "'*'") > strings.Index(content, "'web*'") || strings.Index(content, "base:") > strings.Index(content, "dev:") {
+		t.Errorf("the order of the entries changed:\n%s", content)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Transport runs shell commands on a server. Resources and data sources only talk to the
+// servers through the Transport in providerData, so an alternative executor such as salt-api or
+// a test double can be injected there. A zero timeout applies the command_timeout.
+type Transport = saltclient.Transport
+
+// Ensure the implementations satisfy the interface.
+var _ Transport = &saltclient.SSHExecutor{}
+var _ Transport = &auditTransport{}
+var _ Transport = &dryRunTransport{}
+var _ Transport = &recordingTransport{}
+var _ Transport = &replayTransport{}
+
+type mutatingKey struct{}
+
+// mutating marks the command run with ctx as one changing the server, which dry_run skips.
+func mutating(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mutatingKey{}, true)
+}
+
+// isMutating reports whether the command run with ctx changes the server.
+func isMutating(ctx context.Context) bool {
+	marked, _ := ctx.Value(mutatingKey{}).(bool)
+	return marked
+}
@@ -0,0 +1,167 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Environment variables switching the Transport to fixtures, for acceptance tests running
+// without a live Salt or Uyuni environment.
+const (
+	// recordFixtureEnv names the file the commands run over SSH and their results are appended to.
+	recordFixtureEnv = "SALTY_RECORD_FIXTURE"
+	// replayFixtureEnv names the recorded file the results are served from instead of SSH.
+	replayFixtureEnv = "SALTY_REPLAY_FIXTURE"
+)
+
+// fixtureExchange is a command and its result, a JSON line of a fixture.
+type fixtureExchange struct {
+	Server  string `json:"server"`
+	Command string `json:"command"`
+	Output  string `json:"output"`
+	Error   string `json:"error,omitempty"`
+	// ExitStatus is the exit status of a failed command, zero when it did not finish.
+	ExitStatus int `json:"exit_status,omitempty"`
+}
+
+// fixtureError replays a recorded failure, with its exit status for stateApplyError.
+type fixtureError struct {
+	message    string
+	exitStatus int
+}
+
+func (e *fixtureError) Error() string {
+	return e.message
+}
+
+func (e *fixtureError) ExitStatus() int {
+	return e.exitStatus
+}
+
+// recordingTransport runs the commands through next and appends every exchange to path. The
+// fixture grows over all Terraform commands of a test, as each configures the provider anew.
+type recordingTransport struct {
+	next Transport
+	path string
+
+	mu sync.Mutex
+}
+
+func (t *recordingTransport) Run(ctx context.Context, server string, runCommand string, timeout time.Duration) (string, error) {
+	output, err := t.next.Run(ctx, server, runCommand, timeout)
+
+	exchange := fixtureExchange{Server: server, Command: runCommand, Output: output}
+	if err != nil {
+		exchange.Error = err.Error()
+		var exitErr interface{ ExitStatus() int }
+		if errors.As(err, &exitErr) {
+			exchange.ExitStatus = exitErr.ExitStatus()
+		}
+	}
+
+	if recordErr := t.write(exchange); recordErr != nil {
+		return output, fmt.Errorf("cannot record the command in the fixture %s: %s", t.path, recordErr)
+	}
+
+	return output, err
+}
+
+// write appends exchange to the fixture.
+func (t *recordingTransport) write(exchange fixtureExchange) error {
+	line, err := json.Marshal(exchange)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	file, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// replayTransport serves the commands from a recorded fixture. A command run several times gets
+// its recorded results in order, so reads before and after a change see different values.
+type replayTransport struct {
+	path string
+
+	mu        sync.Mutex
+	exchanges map[string][]fixtureExchange
+}
+
+var (
+	replaysMu sync.Mutex
+	replays   = map[string]*replayTransport{}
+)
+
+// newReplayTransport returns the replay of the fixture at path, loading it on first use. The
+// replay is shared by the provider instances of a test process, so it carries on where the
+// previous Terraform command of the test stopped.
+func newReplayTransport(path string) (*replayTransport, error) {
+	replaysMu.Lock()
+	defer replaysMu.Unlock()
+
+	if t, ok := replays[path]; ok {
+		return t, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	t := &replayTransport{path: path, exchanges: map[string][]fixtureExchange{}}
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var exchange fixtureExchange
+		if err := decoder.Decode(&exchange); err != nil {
+			return nil, fmt.Errorf("malformed fixture: %s", err)
+		}
+		key := fixtureKey(exchange.Server, exchange.Command)
+		t.exchanges[key] = append(t.exchanges[key], exchange)
+	}
+
+	replays[path] = t
+	return t, nil
+}
+
+func (t *replayTransport) Run(ctx context.Context, server string, runCommand string, timeout time.Duration) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := fixtureKey(server, runCommand)
+	recorded := t.exchanges[key]
+	if len(recorded) == 0 {
+		return "", fmt.Errorf("the command %s on %s is not recorded in the fixture %s, record it again with %s", runCommand, server, t.path, recordFixtureEnv)
+	}
+	exchange := recorded[0]
+	// the last result keeps answering, e.g. reads repeated by a refresh
+	if len(recorded) > 1 {
+		t.exchanges[key] = recorded[1:]
+	}
+
+	if exchange.Error != "" {
+		return exchange.Output, &fixtureError{message: exchange.Error, exitStatus: exchange.ExitStatus}
+	}
+	return exchange.Output, nil
+}
+
+func fixtureKey(server string, runCommand string) string {
+	return server + "\x00" + runCommand
+}
@@ -0,0 +1,441 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &UserResource{}
+
+func NewUserResource() resource.Resource {
+	return &UserResource{}
+}
+
+// UserResource defines the resource implementation.
+type UserResource struct {
+	minionConnection
+}
+
+// UserResourceModel describes the resource data model.
+type UserResourceModel struct {
+	Id                  types.String `tfsdk:"id"`
+	Server              types.String `tfsdk:"server"`
+	UyuniSystemName     types.String `tfsdk:"uyuni_system_name"`
+	Name                types.String `tfsdk:"name"`
+	Uid                 types.Int64  `tfsdk:"uid"`
+	Home                types.String `tfsdk:"home"`
+	Shell               types.String `tfsdk:"shell"`
+	Groups              types.Set    `tfsdk:"groups"`
+	SSHAuthorizedKeys   types.Set    `tfsdk:"ssh_authorized_keys"`
+	RemoveHomeOnDestroy types.Bool   `tfsdk:"remove_home_on_destroy"`
+}
+
+// saltUserInfo is the user.info output, empty for a missing user.
+type saltUserInfo struct {
+	Name   string   `json:"name"`
+	Uid    int64    `json:"uid"`
+	Home   string   `json:"home"`
+	Shell  string   `json:"shell"`
+	Groups []string `json:"groups"`
+}
+
+func (r *UserResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_user"
+}
+
+func (r *UserResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Local user of a minion with its SSH authorized keys, set with the `user.present` and `ssh_auth.present` states through salt-call. Covers accounts which must exist before the first highstate, e.g. break-glass users.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": uyuniSystemNameAttribute("for the salt-key acceptance checks"),
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Login name of the user.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uid": schema.Int64Attribute{
+				MarkdownDescription: "User ID. Defaults to the next free one picked by the minion.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"home": schema.StringAttribute{
+				MarkdownDescription: "Home directory. Defaults to the system default, usually `/home/<name>`.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"shell": schema.StringAttribute{
+				MarkdownDescription: "Login shell. Defaults to the system default.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"groups": schema.SetAttribute{
+				MarkdownDescription: "Supplementary groups of the user, e.g. `[\"wheel\"]`. The user is removed from the groups not listed.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"ssh_authorized_keys": schema.SetAttribute{
+				MarkdownDescription: "Public keys allowed to log in as the user, as lines of `authorized_keys`, e.g. `ssh-ed25519 AAAA... admin@example.com`. Keys added outside of Terraform are left alone.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"remove_home_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Delete the home directory together with the user when the resource is destroyed. Defaults to `false`.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *UserResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+}
+
+func (r *UserResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data UserResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	err := r.writeUser(ctx, &data, nil)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot create the user",
+			fmt.Sprintf("cannot create the user %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.Server.ValueString(), data.Name.ValueString()))
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UserResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data UserResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	info, err := r.userInfo(ctx, minion, data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the user",
+			fmt.Sprintf("cannot read the user %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+	if info.Name == "" {
+		tflog.Info(ctx, fmt.Sprintf("user %s is gone from %s, removing it from state", data.Name.ValueString(), data.Server.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	data.Uid = types.Int64Value(info.Uid)
+	data.Home = types.StringValue(info.Home)
+	data.Shell = types.StringValue(info.Shell)
+
+	// only the configured groups are tracked, the primary group is listed by user.info too
+	if !data.Groups.IsNull() {
+		var groups []attr.Value
+		for _, group := range setStrings(data.Groups) {
+			if slices.Contains(info.Groups, group) {
+				groups = append(groups, types.StringValue(group))
+			}
+		}
+		setVal, diags := types.SetValue(types.StringType, groups)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Groups = setVal
+	}
+
+	if !data.SSHAuthorizedKeys.IsNull() {
+		runCommand := minion.Command("ssh.auth_keys", saltclient.StringArg(data.Name.ValueString()))
+		output, err := r.transport.Run(ctx, data.Server.ValueString(), runCommand, 0)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Cannot read the SSH authorized keys",
+				fmt.Sprintf("cannot read the SSH authorized keys of the user %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+			)
+			return
+		}
+
+		// the keys of a user without an authorized_keys file are an empty dictionary
+		var authKeys struct {
+			Local map[string]any `json:"local"`
+		}
+		if err := json.Unmarshal(saltJSON(output), &authKeys); err != nil || authKeys.Local == nil {
+			resp.Diagnostics.AddError(
+				"Cannot read the SSH authorized keys",
+				fmt.Sprintf("cannot parse the SSH authorized keys of the user %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), output),
+			)
+			return
+		}
+
+		var keys []attr.Value
+		for _, key := range setStrings(data.SSHAuthorizedKeys) {
+			if _, ok := authKeys.Local[sshKeyBlob(key)]; ok {
+				keys = append(keys, types.StringValue(key))
+			}
+		}
+		setVal, diags := types.SetValue(types.StringType, keys)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.SSHAuthorizedKeys = setVal
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data, state UserResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	var removedKeys []string
+	for _, key := range setStrings(state.SSHAuthorizedKeys) {
+		if !slices.Contains(setStrings(data.SSHAuthorizedKeys), key) {
+			removedKeys = append(removedKeys, key)
+		}
+	}
+
+	err := r.writeUser(ctx, &data, removedKeys)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot update the user",
+			fmt.Sprintf("cannot update the user %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UserResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data UserResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.serverLocks.lock(data.Server.ValueString())()
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to detect the Salt Minion installation",
+			fmt.Sprintf("failed to detect the Salt Minion installation on %s: %s", data.Server.ValueString(), err),
+		)
+		return
+	}
+
+	args := []string{"name=" + saltclient.StringArg(data.Name.ValueString())}
+	if data.RemoveHomeOnDestroy.ValueBool() {
+		args = append(args, "purge=True")
+	}
+	err = r.runState(ctx, minion, data.Server.ValueString(), "user.absent", args...)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot delete the user",
+			fmt.Sprintf("cannot delete the user %s on the Salt Minion %s: %s", data.Name.ValueString(), data.Server.ValueString(), err),
+		)
+	}
+}
+
+// writeUser brings the user of data and its SSH authorized keys to the planned state, removing
+// the keys in removedKeys, and fills in the computed attributes.
+func (r *UserResource) writeUser(ctx context.Context, data *UserResourceModel, removedKeys []string) error {
+	err := r.waitMinionIsUp(ctx, nil, uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return fmt.Errorf("failed to wait for the minion to be up: %s", err)
+	}
+
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, data.Server.ValueString(), uyuniSystemName(data.Server.ValueString(), data.UyuniSystemName))
+	if err != nil {
+		return err
+	}
+
+	args := []string{"name=" + saltclient.StringArg(data.Name.ValueString())}
+	if !data.Uid.IsNull() && !data.Uid.IsUnknown() {
+		args = append(args, fmt.Sprintf("uid=%d", data.Uid.ValueInt64()))
+	}
+	if !data.Home.IsNull() && !data.Home.IsUnknown() {
+		args = append(args, "home="+saltclient.StringArg(data.Home.ValueString()))
+	}
+	if !data.Shell.IsNull() && !data.Shell.IsUnknown() {
+		args = append(args, "shell="+saltclient.StringArg(data.Shell.ValueString()))
+	}
+	if !data.Groups.IsNull() {
+		groups, _ := json.Marshal(nonNil(setStrings(data.Groups)))
+		args = append(args, "groups="+string(groups))
+	}
+	err = r.runState(ctx, minion, data.Server.ValueString(), "user.present", args...)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range setStrings(data.SSHAuthorizedKeys) {
+		err := r.runState(ctx, minion, data.Server.ValueString(), "ssh_auth.present", "name="+saltclient.StringArg(key), "user="+saltclient.StringArg(data.Name.ValueString()))
+		if err != nil {
+			return fmt.Errorf("cannot authorize the SSH key %s: %s", sshKeyBlob(key), err)
+		}
+	}
+	for _, key := range removedKeys {
+		err := r.runState(ctx, minion, data.Server.ValueString(), "ssh_auth.absent", "name="+saltclient.StringArg(key), "user="+saltclient.StringArg(data.Name.ValueString()))
+		if err != nil {
+			return fmt.Errorf("cannot remove the SSH key %s: %s", sshKeyBlob(key), err)
+		}
+	}
+
+	info, err := r.userInfo(ctx, minion, *data)
+	if err != nil {
+		return err
+	}
+	data.Uid = types.Int64Value(info.Uid)
+	data.Home = types.StringValue(info.Home)
+	data.Shell = types.StringValue(info.Shell)
+	return nil
+}
+
+// runState runs the state function with args through state.single on server.
+func (r *UserResource) runState(ctx context.Context, minion saltMinionInstall, server string, function string, args ...string) error {
+	output, err := r.transport.Run(mutating(ctx), server, minion.Command("state.single", append([]string{function}, args...)...), 0)
+	if err != nil {
+		return err
+	}
+
+	summary := parseHighstate(output)
+	if summary == nil || summary.Failed > 0 {
+		return fmt.Errorf("%s failed: %s", function, output)
+	}
+	return nil
+}
+
+// userInfo returns the user.info of the user of data, with an empty name for a missing user.
+func (r *UserResource) userInfo(ctx context.Context, minion saltMinionInstall, data UserResourceModel) (saltUserInfo, error) {
+	var info struct {
+		Local *saltUserInfo `json:"local"`
+	}
+
+	output, err := r.transport.Run(ctx, data.Server.ValueString(), minion.Command("user.info", saltclient.StringArg(data.Name.ValueString())), 0)
+	if err != nil {
+		return saltUserInfo{}, err
+	}
+	// a missing user is an empty dictionary, which leaves the name empty
+	if err := json.Unmarshal(saltJSON(output), &info); err != nil || info.Local == nil {
+		return saltUserInfo{}, fmt.Errorf("cannot parse the user.info output: %s", output)
+	}
+	return *info.Local, nil
+}
+
+// sshKeyBlob returns the base64 key of an authorized_keys line, which ssh.auth_keys is keyed by.
+func sshKeyBlob(key string) string {
+	fields := strings.Fields(key)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "ssh-") || strings.HasPrefix(field, "ecdsa-") || strings.HasPrefix(field, "sk-") {
+			if i+1 < len(fields) {
+				return fields[i+1]
+			}
+		}
+	}
+	return key
+}
@@ -0,0 +1,299 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &UyuniActivationKeyResource{}
+
+func NewUyuniActivationKeyResource() resource.Resource {
+	return &UyuniActivationKeyResource{}
+}
+
+// UyuniActivationKeyResource defines the resource implementation.
+type UyuniActivationKeyResource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniActivationKeyResourceModel describes the resource data model.
+type UyuniActivationKeyResourceModel struct {
+	Id               types.String `tfsdk:"id"`
+	Key              types.String `tfsdk:"key"`
+	Description      types.String `tfsdk:"description"`
+	BaseChannel      types.String `tfsdk:"base_channel"`
+	Entitlements     types.List   `tfsdk:"entitlements"`
+	Packages         types.List   `tfsdk:"packages"`
+	ServerGroupIds   types.List   `tfsdk:"server_group_ids"`
+	UniversalDefault types.Bool   `tfsdk:"universal_default"`
+}
+
+func (r *UyuniActivationKeyResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_activation_key"
+}
+
+func (r *UyuniActivationKeyResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Uyuni activation key used to bootstrap systems",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Activation key as prefixed by Uyuni with the organization ID, e.g. `1-webserver`. Use it when bootstrapping systems.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"key": schema.StringAttribute{
+				MarkdownDescription: "Key to create, without the organization prefix.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"description": schema.StringAttribute{
+				Required: true,
+			},
+			"base_channel": schema.StringAttribute{
+				MarkdownDescription: "Label of the base channel of the bootstrapped systems. Defaults to the organization's default base channel.",
+				Optional:            true,
+			},
+			"entitlements": schema.ListAttribute{
+				MarkdownDescription: "Add-on entitlements, e.g. `monitoring_entitled`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"packages": schema.ListAttribute{
+				MarkdownDescription: "Names of the packages installed on the bootstrapped systems.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"server_group_ids": schema.ListAttribute{
+				MarkdownDescription: "IDs of the system groups the bootstrapped systems join.",
+				ElementType:         types.Int64Type,
+				Optional:            true,
+			},
+			"universal_default": schema.BoolAttribute{
+				MarkdownDescription: "Make the key the organization's default for systems registered without a key. Defaults to `false`.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *UyuniActivationKeyResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.Uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_activation_key resource requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	r.uyuni = data.Uyuni
+}
+
+func (r *UyuniActivationKeyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data UyuniActivationKeyResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	desired, diags := activationKeyFromModel(ctx, data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	key, err := r.uyuni.CreateActivationKey(ctx, desired)
+	if key != "" {
+		// keep a partially configured key in state, so it is completed instead of orphaned
+		data.Id = types.StringValue(key)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot create the activation key",
+			fmt.Sprintf("cannot create the activation key %s in Uyuni %s: %s", data.Key.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("created the activation key %s", key))
+}
+
+func (r *UyuniActivationKeyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data UyuniActivationKeyResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	activationKey, err := r.uyuni.GetActivationKey(ctx, data.Id.ValueString())
+	if saltclient.IsUyuniNotFound(err) {
+		tflog.Info(ctx, fmt.Sprintf("activation key %s is gone from Uyuni, removing it from state", data.Id.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the activation key",
+			fmt.Sprintf("cannot read the activation key %s in Uyuni %s: %s", data.Id.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	data.Description = types.StringValue(activationKey.Description)
+	if activationKey.BaseChannelLabel != "" || !data.BaseChannel.IsNull() {
+		data.BaseChannel = types.StringValue(activationKey.BaseChannelLabel)
+	}
+	if activationKey.UniversalDefault || !data.UniversalDefault.IsNull() {
+		data.UniversalDefault = types.BoolValue(activationKey.UniversalDefault)
+	}
+
+	var diags diag.Diagnostics
+	data.Entitlements, diags = reconcileList(ctx, data.Entitlements, types.StringType, activationKey.Entitlements)
+	resp.Diagnostics.Append(diags...)
+	data.Packages, diags = reconcileList(ctx, data.Packages, types.StringType, activationKey.PackageNames)
+	resp.Diagnostics.Append(diags...)
+	var groupIds []int64
+	for _, id := range activationKey.ServerGroupIDs {
+		groupIds = append(groupIds, int64(id))
+	}
+	data.ServerGroupIds, diags = reconcileList(ctx, data.ServerGroupIds, types.Int64Type, groupIds)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniActivationKeyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data UyuniActivationKeyResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	desired, diags := activationKeyFromModel(ctx, data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.uyuni.UpdateActivationKey(ctx, data.Id.ValueString(), desired)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot update the activation key",
+			fmt.Sprintf("cannot update the activation key %s in Uyuni %s: %s", data.Id.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniActivationKeyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data UyuniActivationKeyResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.uyuni.DeleteActivationKey(ctx, data.Id.ValueString())
+	if err != nil && !saltclient.IsUyuniNotFound(err) {
+		resp.Diagnostics.AddError(
+			"Cannot delete the activation key",
+			fmt.Sprintf("cannot delete the activation key %s in Uyuni %s: %s", data.Id.ValueString(), r.uyuni.BaseURL, err),
+		)
+	}
+}
+
+func activationKeyFromModel(ctx context.Context, data UyuniActivationKeyResourceModel) (saltclient.UyuniActivationKey, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	activationKey := saltclient.UyuniActivationKey{
+		Key:              data.Key.ValueString(),
+		Description:      data.Description.ValueString(),
+		BaseChannelLabel: data.BaseChannel.ValueString(),
+		UniversalDefault: data.UniversalDefault.ValueBool(),
+	}
+
+	diags.Append(data.Entitlements.ElementsAs(ctx, &activationKey.Entitlements, false)...)
+	diags.Append(data.Packages.ElementsAs(ctx, &activationKey.PackageNames, false)...)
+	var groupIds []int64
+	diags.Append(data.ServerGroupIds.ElementsAs(ctx, &groupIds, false)...)
+	for _, id := range groupIds {
+		activationKey.ServerGroupIDs = append(activationKey.ServerGroupIDs, int(id))
+	}
+
+	return activationKey, diags
+}
+
+// reconcileList returns values as a list. The prior list is kept when it holds the same values
+// in another order, and stays null when it was null and there are no values.
+func reconcileList[T cmp.Ordered](ctx context.Context, prior types.List, elementType attr.Type, values []T) (types.List, diag.Diagnostics) {
+	if len(values) == 0 && prior.IsNull() {
+		return prior, nil
+	}
+
+	var priorValues []T
+	if diags := prior.ElementsAs(ctx, &priorValues, false); !diags.HasError() {
+		sortedPrior, sortedValues := slices.Clone(priorValues), slices.Clone(values)
+		slices.Sort(sortedPrior)
+		slices.Sort(sortedValues)
+		if slices.Equal(sortedPrior, sortedValues) {
+			return prior, nil
+		}
+	}
+
+	if values == nil {
+		values = []T{}
+	}
+	return types.ListValueFrom(ctx, elementType, values)
+}
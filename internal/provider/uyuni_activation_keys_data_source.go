@@ -0,0 +1,208 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &UyuniActivationKeysDataSource{}
+
+func NewUyuniActivationKeysDataSource() datasource.DataSource {
+	return &UyuniActivationKeysDataSource{}
+}
+
+// UyuniActivationKeysDataSource defines the data source implementation.
+type UyuniActivationKeysDataSource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniActivationKeysDataSourceModel describes the data source data model.
+type UyuniActivationKeysDataSourceModel struct {
+	Id             types.String              `tfsdk:"id"`
+	ActivationKeys []UyuniActivationKeyModel `tfsdk:"activation_keys"`
+}
+
+// UyuniActivationKeyModel describes one activation key of the data source.
+type UyuniActivationKeyModel struct {
+	Key              types.String `tfsdk:"key"`
+	Description      types.String `tfsdk:"description"`
+	BaseChannel      types.String `tfsdk:"base_channel"`
+	ChildChannels    types.List   `tfsdk:"child_channels"`
+	Entitlements     types.List   `tfsdk:"entitlements"`
+	Packages         types.List   `tfsdk:"packages"`
+	ServerGroupIds   types.List   `tfsdk:"server_group_ids"`
+	UniversalDefault types.Bool   `tfsdk:"universal_default"`
+	UsageLimit       types.Int64  `tfsdk:"usage_limit"`
+	ContactMethod    types.String `tfsdk:"contact_method"`
+}
+
+func (d *UyuniActivationKeysDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_activation_keys"
+}
+
+func (d *UyuniActivationKeysDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Activation keys of the Uyuni organization",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"activation_keys": schema.ListNestedAttribute{
+				MarkdownDescription: "Activation keys, sorted by key.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"key": schema.StringAttribute{
+							MarkdownDescription: "Activation key including the organization prefix, e.g. `1-webserver`.",
+							Computed:            true,
+						},
+						"description": schema.StringAttribute{
+							Computed: true,
+						},
+						"base_channel": schema.StringAttribute{
+							MarkdownDescription: "Label of the base channel, empty for the organization's default.",
+							Computed:            true,
+						},
+						"child_channels": schema.ListAttribute{
+							MarkdownDescription: "Labels of the child channels.",
+							ElementType:         types.StringType,
+							Computed:            true,
+						},
+						"entitlements": schema.ListAttribute{
+							MarkdownDescription: "Add-on entitlements.",
+							ElementType:         types.StringType,
+							Computed:            true,
+						},
+						"packages": schema.ListAttribute{
+							MarkdownDescription: "Names of the packages installed on bootstrap.",
+							ElementType:         types.StringType,
+							Computed:            true,
+						},
+						"server_group_ids": schema.ListAttribute{
+							MarkdownDescription: "IDs of the system groups joined on bootstrap.",
+							ElementType:         types.Int64Type,
+							Computed:            true,
+						},
+						"universal_default": schema.BoolAttribute{
+							MarkdownDescription: "Whether the key is the organization's default.",
+							Computed:            true,
+						},
+						"usage_limit": schema.Int64Attribute{
+							MarkdownDescription: "Number of systems the key may register, zero when unlimited.",
+							Computed:            true,
+						},
+						"contact_method": schema.StringAttribute{
+							MarkdownDescription: "How Uyuni contacts the registered systems, e.g. `default` or `ssh-push`.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *UyuniActivationKeysDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.uyuni = data.Uyuni
+}
+
+func (d *UyuniActivationKeysDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data UyuniActivationKeysDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if d.uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_activation_keys data source requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	keys, err := d.uyuni.ListActivationKeys(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot list the activation keys in Uyuni",
+			fmt.Sprintf("cannot list the activation keys in Uyuni %s: %s", d.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	slices.SortFunc(keys, func(a, b saltclient.UyuniActivationKey) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	data.ActivationKeys = []UyuniActivationKeyModel{}
+	for _, key := range keys {
+		var groupIds []int64
+		for _, id := range key.ServerGroupIDs {
+			groupIds = append(groupIds, int64(id))
+		}
+
+		childChannels, diags := types.ListValueFrom(ctx, types.StringType, nonNil(key.ChildChannelLabels))
+		resp.Diagnostics.Append(diags...)
+		entitlements, diags := types.ListValueFrom(ctx, types.StringType, nonNil(key.Entitlements))
+		resp.Diagnostics.Append(diags...)
+		packages, diags := types.ListValueFrom(ctx, types.StringType, nonNil(key.PackageNames))
+		resp.Diagnostics.Append(diags...)
+		serverGroupIds, diags := types.ListValueFrom(ctx, types.Int64Type, nonNil(groupIds))
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		data.ActivationKeys = append(data.ActivationKeys, UyuniActivationKeyModel{
+			Key:              types.StringValue(key.Key),
+			Description:      types.StringValue(key.Description),
+			BaseChannel:      types.StringValue(key.BaseChannelLabel),
+			ChildChannels:    childChannels,
+			Entitlements:     entitlements,
+			Packages:         packages,
+			ServerGroupIds:   serverGroupIds,
+			UniversalDefault: types.BoolValue(key.UniversalDefault),
+			UsageLimit:       types.Int64Value(int64(key.UsageLimit)),
+			ContactMethod:    types.StringValue(key.ContactMethod),
+		})
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("%d activation keys in Uyuni", len(data.ActivationKeys)))
+
+	data.Id = types.StringValue(d.uyuni.BaseURL)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,376 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &UyuniBootstrapResource{}
+var _ resource.ResourceWithValidateConfig = &UyuniBootstrapResource{}
+
+// defaultBootstrapTimeout bounds the bootstrap and the onboarding when timeout is not set.
+const defaultBootstrapTimeout = 30 * time.Minute
+
+func NewUyuniBootstrapResource() resource.Resource {
+	return &UyuniBootstrapResource{}
+}
+
+// UyuniBootstrapResource defines the resource implementation.
+type UyuniBootstrapResource struct {
+	uyuni      *UyuniClient
+	saltEvents *saltEventBus
+}
+
+// UyuniBootstrapResourceModel describes the resource data model.
+type UyuniBootstrapResourceModel struct {
+	Id                      types.String `tfsdk:"id"`
+	Host                    types.String `tfsdk:"host"`
+	SSHPort                 types.Int64  `tfsdk:"ssh_port"`
+	SSHUser                 types.String `tfsdk:"ssh_user"`
+	SSHPassword             types.String `tfsdk:"ssh_password"`
+	SSHPrivateKey           types.String `tfsdk:"ssh_private_key"`
+	SSHPrivateKeyPassphrase types.String `tfsdk:"ssh_private_key_passphrase"`
+	ActivationKey           types.String `tfsdk:"activation_key"`
+	SaltSSH                 types.Bool   `tfsdk:"salt_ssh"`
+	UyuniSystemName         types.String `tfsdk:"uyuni_system_name"`
+	Timeout                 types.String `tfsdk:"timeout"`
+	KeepOnDestroy           types.Bool   `tfsdk:"keep_on_destroy"`
+	SystemID                types.Int64  `tfsdk:"system_id"`
+}
+
+func (r *UyuniBootstrapResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_bootstrap"
+}
+
+func (r *UyuniBootstrapResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "System onboarded by Uyuni with `system.bootstrap`: Uyuni connects to the host over SSH, installs the Salt Minion, registers it with the activation key and accepts its salt-key. Waits until the system is registered, let the grain and state resources of the host depend on it.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"host": schema.StringAttribute{
+				MarkdownDescription: "Hostname or IP address Uyuni connects to.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"ssh_port": schema.Int64Attribute{
+				MarkdownDescription: "SSH port of the host. Defaults to `22`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(22),
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"ssh_user": schema.StringAttribute{
+				MarkdownDescription: "User Uyuni logs in as, it needs root privileges. Defaults to `root`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("root"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"ssh_password": schema.StringAttribute{
+				MarkdownDescription: "Password of `ssh_user`. Exactly one of `ssh_password` and `ssh_private_key` is required. Write-only, it is only used to bootstrap and never stored in the state. Requires Terraform 1.11 or later.",
+				Optional:            true,
+				Sensitive:           true,
+				WriteOnly:           true,
+			},
+			"ssh_private_key": schema.StringAttribute{
+				MarkdownDescription: "Private key of `ssh_user` in PEM format. Write-only, it is only used to bootstrap and never stored in the state. Requires Terraform 1.11 or later.",
+				Optional:            true,
+				Sensitive:           true,
+				WriteOnly:           true,
+			},
+			"ssh_private_key_passphrase": schema.StringAttribute{
+				MarkdownDescription: "Passphrase of an encrypted `ssh_private_key`. Write-only, like the key. Requires Terraform 1.11 or later.",
+				Optional:            true,
+				Sensitive:           true,
+				WriteOnly:           true,
+			},
+			"activation_key": schema.StringAttribute{
+				MarkdownDescription: "Activation key the system registers with, including the organization prefix, e.g. `1-web`.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"salt_ssh": schema.BoolAttribute{
+				MarkdownDescription: "Manage the system agentless over Salt SSH instead of installing a Salt Minion. Defaults to `false`.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": schema.StringAttribute{
+				MarkdownDescription: "Name the system registers under in Uyuni, awaited after the bootstrap. Set it when the minion ID differs from `host`. Defaults to the host part of `host`.",
+				Optional:            true,
+			},
+			"timeout": schema.StringAttribute{
+				MarkdownDescription: "Maximum duration of the bootstrap and the wait for the onboarding, e.g. `1h` for slow package repositories. Defaults to `30m`.",
+				Optional:            true,
+			},
+			"keep_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Leave the system registered in Uyuni when the resource is destroyed. By default the system is deleted from Uyuni, with the Salt Minion cleaned up as far as it is reachable.",
+				Optional:            true,
+			},
+			"system_id": schema.Int64Attribute{
+				MarkdownDescription: "ID of the registered system in Uyuni.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *UyuniBootstrapResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data UyuniBootstrapResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.SSHPassword.IsUnknown() || data.SSHPrivateKey.IsUnknown() {
+		return
+	}
+	if data.SSHPassword.IsNull() == data.SSHPrivateKey.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("ssh_password"),
+			"Ambiguous SSH credentials",
+			"Exactly one of ssh_password and ssh_private_key must be set.",
+		)
+	}
+	if !data.SSHPrivateKeyPassphrase.IsNull() && data.SSHPrivateKey.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("ssh_private_key_passphrase"),
+			"Passphrase without a private key",
+			"ssh_private_key_passphrase requires ssh_private_key to be set.",
+		)
+	}
+
+	if !data.Timeout.IsNull() && !data.Timeout.IsUnknown() {
+		if _, err := time.ParseDuration(data.Timeout.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("timeout"),
+				"Malformed timeout",
+				fmt.Sprintf("timeout is not a valid duration: %s", err),
+			)
+		}
+	}
+}
+
+func (r *UyuniBootstrapResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.Uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_bootstrap resource requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	r.uyuni = data.Uyuni
+	r.saltEvents = data.SaltEvents
+}
+
+func (r *UyuniBootstrapResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data UyuniBootstrapResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the credentials are write-only, they are in the configuration but never in the plan
+	var config UyuniBootstrapResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	host := data.Host.ValueString()
+	systemName := uyuniSystemName(host, data.UyuniSystemName)
+	timeout := defaultBootstrapTimeout
+	if !data.Timeout.IsNull() {
+		timeout, _ = time.ParseDuration(data.Timeout.ValueString())
+	}
+	deadline := time.Now().Add(timeout)
+
+	err := r.uyuni.BootstrapSystem(ctx, saltclient.UyuniBootstrapRequest{
+		Host:                    host,
+		SSHPort:                 int(data.SSHPort.ValueInt64()),
+		SSHUser:                 data.SSHUser.ValueString(),
+		SSHPassword:             config.SSHPassword.ValueString(),
+		SSHPrivateKey:           config.SSHPrivateKey.ValueString(),
+		SSHPrivateKeyPassphrase: config.SSHPrivateKeyPassphrase.ValueString(),
+		ActivationKey:           data.ActivationKey.ValueString(),
+		SaltSSH:                 data.SaltSSH.ValueBool(),
+	}, timeout)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot bootstrap the system",
+			fmt.Sprintf("cannot bootstrap %s with Uyuni %s: %s", host, r.uyuni.BaseURL, err),
+		)
+		return
+	}
+	tflog.Info(ctx, "bootstrapped the system", map[string]any{logFieldServer: host})
+
+	// the system exists from here on, it is saved even when the onboarding fails, so Terraform
+	// taints it instead of losing track of it
+	data.Id = types.StringValue(systemName)
+	data.SystemID = types.Int64Null()
+
+	waitCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	systemID, err := r.waitRegistered(waitCtx, systemName)
+	if err == nil {
+		data.SystemID = types.Int64Value(int64(systemID))
+		if !data.SaltSSH.ValueBool() {
+			// Salt SSH systems have no salt-key to accept
+			err = waitMinionIsUp(waitCtx, r.uyuni, r.saltEvents, systemName)
+		}
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot wait for the onboarding",
+			fmt.Sprintf("%s was bootstrapped, but did not finish the onboarding as %s in Uyuni %s: %s", host, systemName, r.uyuni.BaseURL, err),
+		)
+	}
+}
+
+func (r *UyuniBootstrapResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data UyuniBootstrapResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	systemName := uyuniSystemName(data.Host.ValueString(), data.UyuniSystemName)
+	systemID, err := r.uyuni.SystemID(ctx, systemName)
+	if errors.Is(err, saltclient.ErrUyuniSystemNotRegistered) {
+		tflog.Info(ctx, fmt.Sprintf("system %s is gone from Uyuni, removing it from state", systemName))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the system",
+			fmt.Sprintf("cannot read the system %s in Uyuni %s: %s", systemName, r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	data.SystemID = types.Int64Value(int64(systemID))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniBootstrapResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state UyuniBootstrapResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the credentials and waits only matter for the bootstrap, which is done
+	data.Id = state.Id
+	data.SystemID = state.SystemID
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniBootstrapResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data UyuniBootstrapResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() || data.KeepOnDestroy.ValueBool() {
+		return
+	}
+
+	systemName := uyuniSystemName(data.Host.ValueString(), data.UyuniSystemName)
+	err := r.uyuni.DeleteSystem(ctx, systemName)
+	if err != nil && !errors.Is(err, saltclient.ErrUyuniSystemNotRegistered) {
+		resp.Diagnostics.AddError(
+			"Cannot delete the system",
+			fmt.Sprintf("cannot delete the system %s from Uyuni %s: %s", systemName, r.uyuni.BaseURL, err),
+		)
+	}
+}
+
+// waitRegistered polls Uyuni until systemName is registered and returns its system ID, or
+// fails once ctx is done.
+func (r *UyuniBootstrapResource) waitRegistered(ctx context.Context, systemName string) (int, error) {
+	for {
+		systemID, err := r.uyuni.SystemID(ctx, systemName)
+		if err == nil {
+			return systemID, nil
+		}
+		if !errors.Is(err, saltclient.ErrUyuniSystemNotRegistered) {
+			return 0, err
+		}
+
+		tflog.Debug(ctx, fmt.Sprintf("system %s not registered yet, retrying", systemName))
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("the system was not registered: %w", ctx.Err())
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
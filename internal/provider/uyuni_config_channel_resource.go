@@ -0,0 +1,259 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// defaultConfigDeployTimeout bounds the wait for a configuration deployment without deploy_timeout.
+const defaultConfigDeployTimeout = 10 * time.Minute
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &UyuniConfigChannelResource{}
+var _ resource.ResourceWithValidateConfig = &UyuniConfigChannelResource{}
+
+func NewUyuniConfigChannelResource() resource.Resource {
+	return &UyuniConfigChannelResource{}
+}
+
+// UyuniConfigChannelResource defines the resource implementation.
+type UyuniConfigChannelResource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniConfigChannelResourceModel describes the resource data model.
+type UyuniConfigChannelResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	SystemName    types.String `tfsdk:"system_name"`
+	Channel       types.String `tfsdk:"channel"`
+	Deploy        types.Bool   `tfsdk:"deploy"`
+	DeployTimeout types.String `tfsdk:"deploy_timeout"`
+}
+
+func (r *UyuniConfigChannelResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_config_channel"
+}
+
+func (r *UyuniConfigChannelResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Uyuni configuration channel assigned to a system, optionally with its files deployed",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"system_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the system the channel is assigned to.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"channel": schema.StringAttribute{
+				MarkdownDescription: "Label of the configuration channel. It is assigned with the lowest priority.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"deploy": schema.BoolAttribute{
+				MarkdownDescription: "Deploy the configuration files of the system once the channel is assigned and wait until the deployment finished. Defaults to `false`.",
+				Optional:            true,
+			},
+			"deploy_timeout": schema.StringAttribute{
+				MarkdownDescription: "Maximum duration of the deployment, e.g. `30m`. Defaults to `10m`.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *UyuniConfigChannelResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data UyuniConfigChannelResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.DeployTimeout.IsNull() && !data.DeployTimeout.IsUnknown() {
+		if _, err := time.ParseDuration(data.DeployTimeout.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("deploy_timeout"),
+				"Malformed deploy timeout",
+				fmt.Sprintf("deploy_timeout is not a valid duration: %s", err),
+			)
+		}
+	}
+}
+
+func (r *UyuniConfigChannelResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.Uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_config_channel resource requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	r.uyuni = data.Uyuni
+}
+
+func (r *UyuniConfigChannelResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data UyuniConfigChannelResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.uyuni.SetConfigChannelAssigned(ctx, data.SystemName.ValueString(), data.Channel.ValueString(), true)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot assign the configuration channel",
+			fmt.Sprintf("cannot assign the configuration channel %s to the system %s in Uyuni %s: %s", data.Channel.ValueString(), data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s/%s", data.SystemName.ValueString(), data.Channel.ValueString()))
+	tflog.Info(ctx, fmt.Sprintf("assigned the configuration channel %s to the system %s", data.Channel.ValueString(), data.SystemName.ValueString()))
+
+	// the assignment exists, so keep it in state even if the deployment fails
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Deploy.ValueBool() {
+		r.deploy(ctx, data, &resp.Diagnostics)
+	}
+}
+
+func (r *UyuniConfigChannelResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data UyuniConfigChannelResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	channels, err := r.uyuni.GetConfigChannels(ctx, data.SystemName.ValueString())
+	if saltclient.IsUyuniNotFound(err) {
+		tflog.Info(ctx, fmt.Sprintf("system %s is gone from Uyuni, removing the configuration channel from state", data.SystemName.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the configuration channels",
+			fmt.Sprintf("cannot read the configuration channels of the system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+	if !slices.Contains(channels, data.Channel.ValueString()) {
+		tflog.Info(ctx, fmt.Sprintf("configuration channel %s is no longer assigned to the system %s, removing it from state", data.Channel.ValueString(), data.SystemName.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniConfigChannelResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state UyuniConfigChannelResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// only the deploy settings can change in place, deploy once they are enabled
+	if data.Deploy.ValueBool() && !state.Deploy.ValueBool() {
+		r.deploy(ctx, data, &resp.Diagnostics)
+	}
+}
+
+func (r *UyuniConfigChannelResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data UyuniConfigChannelResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.uyuni.SetConfigChannelAssigned(ctx, data.SystemName.ValueString(), data.Channel.ValueString(), false)
+	if err != nil && !saltclient.IsUyuniNotFound(err) {
+		resp.Diagnostics.AddError(
+			"Cannot unassign the configuration channel",
+			fmt.Sprintf("cannot unassign the configuration channel %s from the system %s in Uyuni %s: %s", data.Channel.ValueString(), data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+	}
+}
+
+// deploy deploys the configuration files of the system and waits up to deploy_timeout for it.
+func (r *UyuniConfigChannelResource) deploy(ctx context.Context, data UyuniConfigChannelResourceModel, diags *diag.Diagnostics) {
+	timeout := defaultConfigDeployTimeout
+	if !data.DeployTimeout.IsNull() {
+		// validated in ValidateConfig
+		timeout, _ = time.ParseDuration(data.DeployTimeout.ValueString())
+	}
+
+	deployCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := r.uyuni.DeployConfigChannels(deployCtx, data.SystemName.ValueString())
+	if err != nil {
+		diags.AddError(
+			"Cannot deploy the configuration files",
+			fmt.Sprintf("cannot deploy the configuration files of the system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("deployed the configuration files of the system %s", data.SystemName.ValueString()))
+}
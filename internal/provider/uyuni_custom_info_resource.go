@@ -0,0 +1,243 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &UyuniCustomInfoResource{}
+
+func NewUyuniCustomInfoResource() resource.Resource {
+	return &UyuniCustomInfoResource{}
+}
+
+// UyuniCustomInfoResource defines the resource implementation.
+type UyuniCustomInfoResource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniCustomInfoResourceModel describes the resource data model.
+type UyuniCustomInfoResourceModel struct {
+	Id         types.String `tfsdk:"id"`
+	SystemName types.String `tfsdk:"system_name"`
+	Values     types.Map    `tfsdk:"values"`
+}
+
+func (r *UyuniCustomInfoResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_custom_info"
+}
+
+func (r *UyuniCustomInfoResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Custom system information of a system in Uyuni, kept in the Uyuni database and its reports instead of the minion's grains",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"system_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the system the values are set on.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"values": schema.MapAttribute{
+				MarkdownDescription: "Custom values by key label. Keys Uyuni does not know yet are created, values of keys not listed here are left alone.",
+				ElementType:         types.StringType,
+				Required:            true,
+			},
+		},
+	}
+}
+
+func (r *UyuniCustomInfoResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.Uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_custom_info resource requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	r.uyuni = data.Uyuni
+}
+
+func (r *UyuniCustomInfoResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data UyuniCustomInfoResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	values := map[string]string{}
+	resp.Diagnostics.Append(data.Values.ElementsAs(ctx, &values, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.uyuni.SetCustomValues(ctx, data.SystemName.ValueString(), values)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot set the custom values",
+			fmt.Sprintf("cannot set the custom values of system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(data.SystemName.ValueString())
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniCustomInfoResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data UyuniCustomInfoResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	live, err := r.uyuni.GetCustomValues(ctx, data.SystemName.ValueString())
+	if saltclient.IsUyuniNotFound(err) {
+		tflog.Info(ctx, fmt.Sprintf("system %s is gone from Uyuni, removing it from state", data.SystemName.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the custom values",
+			fmt.Sprintf("cannot read the custom values of system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	// only the keys of this resource are tracked, others may be managed elsewhere
+	values := map[string]string{}
+	for key := range data.Values.Elements() {
+		if value, ok := live[key]; ok {
+			values[key] = value
+		}
+	}
+
+	if len(values) == 0 {
+		tflog.Info(ctx, fmt.Sprintf("custom values of system %s are gone, removing them from state", data.SystemName.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	mapVal, diags := types.MapValueFrom(ctx, types.StringType, values)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Values = mapVal
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniCustomInfoResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state UyuniCustomInfoResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	values := map[string]string{}
+	resp.Diagnostics.Append(data.Values.ElementsAs(ctx, &values, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.uyuni.SetCustomValues(ctx, data.SystemName.ValueString(), values)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot set the custom values",
+			fmt.Sprintf("cannot set the custom values of system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	var removed []string
+	for key := range state.Values.Elements() {
+		if _, ok := values[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	if len(removed) > 0 {
+		err := r.uyuni.DeleteCustomValues(ctx, data.SystemName.ValueString(), removed)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Cannot delete the custom values",
+				fmt.Sprintf("cannot delete the custom values %s of system %s in Uyuni %s: %s", removed, data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+			)
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniCustomInfoResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data UyuniCustomInfoResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var keys []string
+	for key := range data.Values.Elements() {
+		keys = append(keys, key)
+	}
+
+	err := r.uyuni.DeleteCustomValues(ctx, data.SystemName.ValueString(), keys)
+	if err != nil && !saltclient.IsUyuniNotFound(err) {
+		resp.Diagnostics.AddError(
+			"Cannot delete the custom values",
+			fmt.Sprintf("cannot delete the custom values of system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+	}
+}
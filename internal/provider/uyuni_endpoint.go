@@ -0,0 +1,72 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// uyuniEndpointModel describes the uyuni block of a resource registered in another Uyuni server
+// than the provider's, e.g. a regional server behind a Hub.
+type uyuniEndpointModel struct {
+	BaseURL  types.String `tfsdk:"base_url"`
+	Username types.String `tfsdk:"username"`
+	Password types.String `tfsdk:"password"`
+}
+
+// uyuniEndpointAttribute is the schema of the uyuni block.
+func uyuniEndpointAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		MarkdownDescription: "Uyuni server the minion is registered in, when it is not the one configured on the provider. The other Uyuni settings of the provider still apply. Defaults to the provider's Uyuni server.",
+		Optional:            true,
+		Attributes: map[string]schema.Attribute{
+			"base_url": schema.StringAttribute{
+				MarkdownDescription: "Base URL of the Uyuni API, e.g. `https://uyuni-eu.example.com/rhn/manager/api`.",
+				Required:            true,
+			},
+			"username": schema.StringAttribute{
+				Required: true,
+			},
+			"password": schema.StringAttribute{
+				Required:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+// UyuniClient is the client of the Uyuni HTTP API together with the provider's measurements.
+type UyuniClient struct {
+	saltclient.UyuniClient
+	// Timings measures the API calls and the waits for the minions, nil for no measurements.
+	Timings *timings
+}
+
+// newUyuniClient returns client reporting its API calls to measured.
+func newUyuniClient(client saltclient.UyuniClient, measured *timings) *UyuniClient {
+	client.OnCall = func(method string, elapsed time.Duration) {
+		measured.recordDetached(timingUyuniCall, elapsed, map[string]string{"uyuni_method": method})
+	}
+	return &UyuniClient{UyuniClient: client, Timings: measured}
+}
+
+// withEndpoint returns the client for endpoint, which shares the remaining settings of c, or c
+// itself when endpoint is nil.
+func (c *UyuniClient) withEndpoint(endpoint *uyuniEndpointModel) *UyuniClient {
+	if endpoint == nil {
+		return c
+	}
+
+	client := UyuniClient{UyuniClient: saltclient.UyuniClient{RetryAttempts: defaultUyuniRetryAttempts, HTTPTimeout: defaultUyuniHTTPTimeout}}
+	if c != nil {
+		client = *c
+	}
+	client.BaseURL = endpoint.BaseURL.ValueString()
+	client.Username = endpoint.Username.ValueString()
+	client.Password = endpoint.Password.ValueString()
+	return &client
+}
@@ -0,0 +1,292 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"reflect"
+	"slices"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &UyuniFormulaResource{}
+var _ resource.ResourceWithValidateConfig = &UyuniFormulaResource{}
+
+func NewUyuniFormulaResource() resource.Resource {
+	return &UyuniFormulaResource{}
+}
+
+// UyuniFormulaResource defines the resource implementation.
+type UyuniFormulaResource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniFormulaResourceModel describes the resource data model.
+type UyuniFormulaResourceModel struct {
+	Id         types.String `tfsdk:"id"`
+	SystemName types.String `tfsdk:"system_name"`
+	GroupName  types.String `tfsdk:"group_name"`
+	Formula    types.String `tfsdk:"formula"`
+	Data       types.String `tfsdk:"data"`
+}
+
+func (r *UyuniFormulaResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_formula"
+}
+
+func (r *UyuniFormulaResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt formula enabled in Uyuni for a system or a system group, together with its form data",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"system_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the system the formula is enabled for. Conflicts with `group_name`.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"group_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the system group the formula is enabled for. Conflicts with `system_name`.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"formula": schema.StringAttribute{
+				MarkdownDescription: "Name of the formula, e.g. `locale`.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"data": schema.StringAttribute{
+				MarkdownDescription: "Form data of the formula as a JSON object, e.g. `jsonencode({ timezone = { name = \"UTC\" } })`. The form data is left alone when unset.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *UyuniFormulaResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data UyuniFormulaResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.SystemName.IsUnknown() || data.GroupName.IsUnknown() {
+		return
+	}
+	if data.SystemName.IsNull() == data.GroupName.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("system_name"),
+			"Invalid formula target",
+			"Exactly one of system_name and group_name must be set.",
+		)
+	}
+
+	if !data.Data.IsNull() && !data.Data.IsUnknown() {
+		var content map[string]any
+		if err := json.Unmarshal([]byte(data.Data.ValueString()), &content); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("data"),
+				"Invalid formula data",
+				fmt.Sprintf("data must be a JSON object: %s", err),
+			)
+		}
+	}
+}
+
+func (r *UyuniFormulaResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.Uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_formula resource requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	r.uyuni = data.Uyuni
+}
+
+func (r *UyuniFormulaResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data UyuniFormulaResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	target := formulaTargetFromModel(data)
+	err := r.uyuni.SetFormulaEnabled(ctx, target, data.Formula.ValueString(), true)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot enable the formula",
+			fmt.Sprintf("cannot enable the formula %s for the %s in Uyuni %s: %s", data.Formula.ValueString(), target, r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("system/%s/%s", data.SystemName.ValueString(), data.Formula.ValueString()))
+	if target.GroupName != "" {
+		data.Id = types.StringValue(fmt.Sprintf("group/%s/%s", data.GroupName.ValueString(), data.Formula.ValueString()))
+	}
+	if !r.setData(ctx, target, data, &resp.Diagnostics) {
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("enabled the formula %s for the %s", data.Formula.ValueString(), target))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniFormulaResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data UyuniFormulaResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	target := formulaTargetFromModel(data)
+	formulas, err := r.uyuni.GetFormulas(ctx, target)
+	if saltclient.IsUyuniNotFound(err) {
+		tflog.Info(ctx, fmt.Sprintf("the %s is gone from Uyuni, removing the formula from state", target))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the formulas",
+			fmt.Sprintf("cannot read the formulas of the %s in Uyuni %s: %s", target, r.uyuni.BaseURL, err),
+		)
+		return
+	}
+	if !slices.Contains(formulas, data.Formula.ValueString()) {
+		tflog.Info(ctx, fmt.Sprintf("formula %s is no longer enabled for the %s, removing it from state", data.Formula.ValueString(), target))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	if !data.Data.IsNull() {
+		content, err := r.uyuni.GetFormulaData(ctx, target, data.Formula.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Cannot read the formula data",
+				fmt.Sprintf("cannot read the data of the formula %s for the %s in Uyuni %s: %s", data.Formula.ValueString(), target, r.uyuni.BaseURL, err),
+			)
+			return
+		}
+		// keep the configured formatting unless the content really changed
+		if !jsonEqual([]byte(data.Data.ValueString()), content) {
+			data.Data = types.StringValue(string(content))
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniFormulaResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data UyuniFormulaResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !r.setData(ctx, formulaTargetFromModel(data), data, &resp.Diagnostics) {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniFormulaResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data UyuniFormulaResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	target := formulaTargetFromModel(data)
+	err := r.uyuni.SetFormulaEnabled(ctx, target, data.Formula.ValueString(), false)
+	if err != nil && !saltclient.IsUyuniNotFound(err) {
+		resp.Diagnostics.AddError(
+			"Cannot disable the formula",
+			fmt.Sprintf("cannot disable the formula %s for the %s in Uyuni %s: %s", data.Formula.ValueString(), target, r.uyuni.BaseURL, err),
+		)
+	}
+}
+
+// setData writes the configured form data, if any, and reports whether it succeeded.
+func (r *UyuniFormulaResource) setData(ctx context.Context, target saltclient.UyuniFormulaTarget, data UyuniFormulaResourceModel, diags *diag.Diagnostics) bool {
+	if data.Data.IsNull() {
+		return true
+	}
+
+	err := r.uyuni.SetFormulaData(ctx, target, data.Formula.ValueString(), json.RawMessage(data.Data.ValueString()))
+	if err != nil {
+		diags.AddError(
+			"Cannot set the formula data",
+			fmt.Sprintf("cannot set the data of the formula %s for the %s in Uyuni %s: %s", data.Formula.ValueString(), target, r.uyuni.BaseURL, err),
+		)
+		return false
+	}
+	return true
+}
+
+func formulaTargetFromModel(data UyuniFormulaResourceModel) saltclient.UyuniFormulaTarget {
+	return saltclient.UyuniFormulaTarget{SystemName: data.SystemName.ValueString(), GroupName: data.GroupName.ValueString()}
+}
+
+// jsonEqual reports whether a and b are the same JSON value, regardless of formatting and key order.
+func jsonEqual(a []byte, b []byte) bool {
+	var valueA, valueB any
+	if json.Unmarshal(a, &valueA) != nil || json.Unmarshal(b, &valueB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(valueA, valueB)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &UyuniPendingKeysDataSource{}
+
+func NewUyuniPendingKeysDataSource() datasource.DataSource {
+	return &UyuniPendingKeysDataSource{}
+}
+
+// UyuniPendingKeysDataSource defines the data source implementation.
+type UyuniPendingKeysDataSource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniPendingKeysDataSourceModel describes the data source data model.
+type UyuniPendingKeysDataSourceModel struct {
+	Id   types.String `tfsdk:"id"`
+	Keys types.List   `tfsdk:"keys"`
+}
+
+func (d *UyuniPendingKeysDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_pending_keys"
+}
+
+func (d *UyuniPendingKeysDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Salt minion keys waiting for acceptance in Uyuni",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"keys": schema.ListAttribute{
+				MarkdownDescription: "Minion IDs whose salt-key is pending acceptance",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *UyuniPendingKeysDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.uyuni = data.Uyuni
+}
+
+func (d *UyuniPendingKeysDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data UyuniPendingKeysDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if d.uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_pending_keys data source requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	pending, err := d.uyuni.ListPendingKeys(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot list the pending salt-keys in Uyuni",
+			fmt.Sprintf("cannot list the pending salt-keys in Uyuni %s: %s", d.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("pending salt-keys: %v", pending))
+
+	keys := []attr.Value{}
+	for _, key := range pending {
+		keys = append(keys, types.StringValue(key))
+	}
+
+	listVal, diags := types.ListValue(types.StringType, keys)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Keys = listVal
+	data.Id = types.StringValue(d.uyuni.BaseURL)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,238 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"slices"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &UyuniSystemEntitlementResource{}
+var _ resource.ResourceWithValidateConfig = &UyuniSystemEntitlementResource{}
+
+func NewUyuniSystemEntitlementResource() resource.Resource {
+	return &UyuniSystemEntitlementResource{}
+}
+
+// UyuniSystemEntitlementResource defines the resource implementation.
+type UyuniSystemEntitlementResource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniSystemEntitlementResourceModel describes the resource data model.
+type UyuniSystemEntitlementResourceModel struct {
+	Id           types.String `tfsdk:"id"`
+	SystemName   types.String `tfsdk:"system_name"`
+	Entitlements types.Set    `tfsdk:"entitlements"`
+}
+
+func (r *UyuniSystemEntitlementResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_system_entitlement"
+}
+
+func (r *UyuniSystemEntitlementResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Add-on entitlements of a system in Uyuni, e.g. monitoring or virtualization host. The resource manages the whole add-on list of the system, entitlements added outside of Terraform show up as drift.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"system_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the system the entitlements are added to.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"entitlements": schema.SetAttribute{
+				MarkdownDescription: "Labels of the add-on entitlements, e.g. `monitoring_entitled`, `virtualization_host` or `ansible_control_node`. The base entitlement given on registration, such as `salt_entitled`, is not managed here.",
+				ElementType:         types.StringType,
+				Required:            true,
+			},
+		},
+	}
+}
+
+func (r *UyuniSystemEntitlementResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data UyuniSystemEntitlementResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.Entitlements.IsUnknown() {
+		return
+	}
+
+	for _, entitlement := range setStrings(data.Entitlements) {
+		if slices.Contains(saltclient.UyuniBaseEntitlements, entitlement) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("entitlements"),
+				"Base entitlement",
+				fmt.Sprintf("%s is a base entitlement Uyuni gives on registration, only add-on entitlements can be managed. Base entitlements: %s", entitlement, strings.Join(saltclient.UyuniBaseEntitlements, ", ")),
+			)
+		}
+	}
+}
+
+func (r *UyuniSystemEntitlementResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	if data.Uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_system_entitlement resource requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	r.uyuni = data.Uyuni
+}
+
+func (r *UyuniSystemEntitlementResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data UyuniSystemEntitlementResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// add-ons the system already has become managed by this resource
+	err := r.reconcile(ctx, data.SystemName.ValueString(), setStrings(data.Entitlements))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot change the entitlements",
+			fmt.Sprintf("cannot change the entitlements of system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	data.Id = types.StringValue(data.SystemName.ValueString())
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniSystemEntitlementResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data UyuniSystemEntitlementResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	live, err := r.uyuni.GetAddOnEntitlements(ctx, data.SystemName.ValueString())
+	if saltclient.IsUyuniNotFound(err) {
+		tflog.Info(ctx, fmt.Sprintf("system %s is gone from Uyuni, removing it from state", data.SystemName.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot read the entitlements",
+			fmt.Sprintf("cannot read the entitlements of system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	setVal, diags := types.SetValueFrom(ctx, types.StringType, live)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Entitlements = setVal
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniSystemEntitlementResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data UyuniSystemEntitlementResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.reconcile(ctx, data.SystemName.ValueString(), setStrings(data.Entitlements))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot change the entitlements",
+			fmt.Sprintf("cannot change the entitlements of system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniSystemEntitlementResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data UyuniSystemEntitlementResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.uyuni.ChangeEntitlements(ctx, data.SystemName.ValueString(), nil, setStrings(data.Entitlements))
+	if err != nil && !saltclient.IsUyuniNotFound(err) {
+		resp.Diagnostics.AddError(
+			"Cannot remove the entitlements",
+			fmt.Sprintf("cannot remove the entitlements of system %s in Uyuni %s: %s", data.SystemName.ValueString(), r.uyuni.BaseURL, err),
+		)
+	}
+}
+
+// reconcile adds the entitlements of desired the system lacks and removes its other add-ons.
+func (r *UyuniSystemEntitlementResource) reconcile(ctx context.Context, systemName string, desired []string) error {
+	live, err := r.uyuni.GetAddOnEntitlements(ctx, systemName)
+	if err != nil {
+		return err
+	}
+
+	var add, remove []string
+	for _, entitlement := range desired {
+		if !slices.Contains(live, entitlement) {
+			add = append(add, entitlement)
+		}
+	}
+	for _, entitlement := range live {
+		if !slices.Contains(desired, entitlement) {
+			remove = append(remove, entitlement)
+		}
+	}
+	return r.uyuni.ChangeEntitlements(ctx, systemName, add, remove)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"strconv"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &UyuniSystemIDDataSource{}
+
+func NewUyuniSystemIDDataSource() datasource.DataSource {
+	return &UyuniSystemIDDataSource{}
+}
+
+// UyuniSystemIDDataSource defines the data source implementation.
+type UyuniSystemIDDataSource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniSystemIDDataSourceModel describes the data source data model.
+type UyuniSystemIDDataSourceModel struct {
+	Id         types.String `tfsdk:"id"`
+	SystemName types.String `tfsdk:"system_name"`
+	SystemID   types.Int64  `tfsdk:"system_id"`
+}
+
+func (d *UyuniSystemIDDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_system_id"
+}
+
+func (d *UyuniSystemIDDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Numeric Uyuni system ID of a system profile",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"system_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the system profile in Uyuni.",
+				Required:            true,
+			},
+			"system_id": schema.Int64Attribute{
+				MarkdownDescription: "ID of the system in Uyuni.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *UyuniSystemIDDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.uyuni = data.Uyuni
+}
+
+func (d *UyuniSystemIDDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data UyuniSystemIDDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if d.uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_system_id data source requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	systemID, err := d.uyuni.SystemID(ctx, data.SystemName.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot resolve the Uyuni system ID",
+			fmt.Sprintf("cannot resolve the ID of system %s in Uyuni %s: %s", data.SystemName.ValueString(), d.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	data.SystemID = types.Int64Value(int64(systemID))
+	data.Id = types.StringValue(strconv.Itoa(systemID))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,307 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+	"time"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &UyuniSystemRebootResource{}
+var _ resource.ResourceWithValidateConfig = &UyuniSystemRebootResource{}
+
+// defaultRebootTimeout bounds the wait for a rebooted system when timeout is not set.
+const defaultRebootTimeout = 15 * time.Minute
+
+// Supported values of method.
+const (
+	rebootMethodUyuni = "uyuni"
+	rebootMethodSSH   = "ssh"
+)
+
+// bootIDPath changes its content on every boot of a Linux system.
+const bootIDPath = "/proc/sys/kernel/random/boot_id"
+
+func NewUyuniSystemRebootResource() resource.Resource {
+	return &UyuniSystemRebootResource{}
+}
+
+// UyuniSystemRebootResource defines the resource implementation.
+type UyuniSystemRebootResource struct {
+	minionConnection
+}
+
+// UyuniSystemRebootResourceModel describes the resource data model.
+type UyuniSystemRebootResourceModel struct {
+	Id              types.String `tfsdk:"id"`
+	Server          types.String `tfsdk:"server"`
+	UyuniSystemName types.String `tfsdk:"uyuni_system_name"`
+	Method          types.String `tfsdk:"method"`
+	Triggers        types.Map    `tfsdk:"triggers"`
+	Timeout         types.String `tfsdk:"timeout"`
+	RebootedAt      types.String `tfsdk:"rebooted_at"`
+}
+
+func (r *UyuniSystemRebootResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_system_reboot"
+}
+
+func (r *UyuniSystemRebootResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Reboot of a system, e.g. after a kernel or Salt Minion upgrade driven by other resources, which waits until the system is back and its salt-key is still accepted. Let the resources needing the rebooted system depend on it.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"server": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"uyuni_system_name": uyuniSystemNameAttribute("to schedule the reboot and for the salt-key acceptance checks"),
+			"method": schema.StringAttribute{
+				MarkdownDescription: "How the reboot is done: `uyuni` schedules a reboot action and waits until Uyuni completes it as the system checked back in, `ssh` runs `system.reboot` with `salt-call` and waits until the server is reachable with a new boot ID. Defaults to `uyuni` when Uyuni is configured on the provider, `ssh` otherwise.",
+				Optional:            true,
+			},
+			"triggers": schema.MapAttribute{
+				MarkdownDescription: "Arbitrary values, e.g. the installed kernel version. The system is rebooted again whenever any of them changes.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"timeout": schema.StringAttribute{
+				MarkdownDescription: "Maximum duration of the wait for the system to come back, e.g. `30m` for slowly booting hardware. Defaults to `15m`.",
+				Optional:            true,
+			},
+			"rebooted_at": schema.StringAttribute{
+				MarkdownDescription: "Time of the last completed reboot, in RFC 3339 format.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *UyuniSystemRebootResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data UyuniSystemRebootResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Method.IsNull() && !data.Method.IsUnknown() {
+		switch data.Method.ValueString() {
+		case rebootMethodUyuni, rebootMethodSSH:
+		default:
+			resp.Diagnostics.AddAttributeError(
+				path.Root("method"),
+				"Unsupported reboot method",
+				fmt.Sprintf("method must be %s or %s, got %q", rebootMethodUyuni, rebootMethodSSH, data.Method.ValueString()),
+			)
+		}
+	}
+
+	if !data.Timeout.IsNull() && !data.Timeout.IsUnknown() {
+		if _, err := time.ParseDuration(data.Timeout.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("timeout"),
+				"Malformed timeout",
+				fmt.Sprintf("timeout is not a valid duration: %s", err),
+			)
+		}
+	}
+}
+
+func (r *UyuniSystemRebootResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.minionConnection.configure(data)
+}
+
+func (r *UyuniSystemRebootResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, nil)
+
+	var data UyuniSystemRebootResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.reboot(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue(data.Server.ValueString())
+
+	tflog.Info(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniSystemRebootResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// a reboot leaves nothing behind to read back
+}
+
+func (r *UyuniSystemRebootResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	var data, state UyuniSystemRebootResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Triggers.Equal(state.Triggers) {
+		data.RebootedAt = state.RebootedAt
+	} else {
+		r.reboot(ctx, &data, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UyuniSystemRebootResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, dryRun := recordDryRun(ctx)
+	defer dryRun.report(ctx, &resp.Diagnostics, &resp.State, &req.State)
+
+	// a reboot cannot be undone
+}
+
+// reboot reboots the system of data, waits until it is back and checks its salt-key is still
+// accepted.
+func (r *UyuniSystemRebootResource) reboot(ctx context.Context, data *UyuniSystemRebootResourceModel, diags *diag.Diagnostics) {
+	server := data.Server.ValueString()
+	systemName := uyuniSystemName(server, data.UyuniSystemName)
+	defer r.serverLocks.lock(server)()
+
+	timeout := defaultRebootTimeout
+	if !data.Timeout.IsNull() {
+		timeout, _ = time.ParseDuration(data.Timeout.ValueString())
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := data.Method.ValueString()
+	if method == "" {
+		method = rebootMethodSSH
+		if r.uyuni != nil {
+			method = rebootMethodUyuni
+		}
+	}
+
+	var err error
+	switch method {
+	case rebootMethodUyuni:
+		if r.uyuni == nil {
+			diags.AddAttributeError(
+				path.Root("method"),
+				"Uyuni is not configured",
+				"The uyuni reboot method requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+			)
+			return
+		}
+		err = r.uyuni.RebootSystem(waitCtx, systemName)
+	default:
+		err = r.rebootOverSSH(waitCtx, server, systemName)
+	}
+	if err != nil {
+		diags.AddError(
+			"Cannot reboot the system",
+			fmt.Sprintf("cannot reboot the system %s: %s", server, err),
+		)
+		return
+	}
+	tflog.Info(ctx, "rebooted the system", map[string]any{logFieldServer: server})
+
+	err = r.waitMinionIsUp(waitCtx, nil, systemName)
+	if err != nil {
+		diags.AddError(
+			"failed to wait for the minion to be up",
+			fmt.Sprintf("the system %s rebooted, but its salt-key is not accepted: %s", server, err),
+		)
+		return
+	}
+
+	data.RebootedAt = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+}
+
+// rebootOverSSH runs system.reboot on server in the background, so the SSH session ends cleanly,
+// and waits until the server is reachable again with a new boot ID or ctx is done.
+func (r *UyuniSystemRebootResource) rebootOverSSH(ctx context.Context, server string, systemName string) error {
+	minion, err := r.minionInstalls.detect(ctx, r.transport, r.uyuni, server, systemName)
+	if err != nil {
+		return err
+	}
+
+	bootID, err := r.transport.Run(ctx, server, fmt.Sprintf("cat %s", bootIDPath), 0)
+	if err != nil {
+		return err
+	}
+
+	runCommand := fmt.Sprintf("nohup sh -c %s >/dev/null 2>&1 & echo scheduled", saltclient.ShellQuote("sleep 2; "+minion.Command("system.reboot")))
+	output, err := r.transport.Run(mutating(ctx), server, runCommand, 0)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(output) != "scheduled" {
+		// a dry run does not reboot, there is nothing to wait for
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the server did not come back: %w", ctx.Err())
+		case <-time.After(10 * time.Second):
+		}
+
+		// the connection fails while the server is down
+		current, err := r.transport.Run(ctx, server, fmt.Sprintf("cat %s", bootIDPath), 0)
+		if err == nil && strings.TrimSpace(current) != strings.TrimSpace(bootID) {
+			return nil
+		}
+		tflog.Debug(ctx, "server not back yet", map[string]any{logFieldServer: server})
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"regexp"
+	"slices"
+	"strings"
+	"terraform-provider-salty/internal/saltclient"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &UyuniSystemsDataSource{}
+
+func NewUyuniSystemsDataSource() datasource.DataSource {
+	return &UyuniSystemsDataSource{}
+}
+
+// UyuniSystemsDataSource defines the data source implementation.
+type UyuniSystemsDataSource struct {
+	uyuni *UyuniClient
+}
+
+// UyuniSystemsDataSourceModel describes the data source data model.
+type UyuniSystemsDataSourceModel struct {
+	Id        types.String       `tfsdk:"id"`
+	NameRegex types.String       `tfsdk:"name_regex"`
+	Systems   []UyuniSystemModel `tfsdk:"systems"`
+}
+
+// UyuniSystemModel describes one system of the data source.
+type UyuniSystemModel struct {
+	Name        types.String `tfsdk:"name"`
+	Id          types.Int64  `tfsdk:"id"`
+	LastCheckin types.String `tfsdk:"last_checkin"`
+}
+
+func (d *UyuniSystemsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uyuni_systems"
+}
+
+func (d *UyuniSystemsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Systems registered in Uyuni",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"name_regex": schema.StringAttribute{
+				MarkdownDescription: "Regular expression the system names must match, e.g. `^web-`. All systems are listed by default.",
+				Optional:            true,
+			},
+			"systems": schema.ListNestedAttribute{
+				MarkdownDescription: "Registered systems, sorted by name.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Profile name of the system, usually its minion ID.",
+							Computed:            true,
+						},
+						"id": schema.Int64Attribute{
+							MarkdownDescription: "Uyuni system ID.",
+							Computed:            true,
+						},
+						"last_checkin": schema.StringAttribute{
+							MarkdownDescription: "Last check-in of the system as reported by Uyuni.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *UyuniSystemsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.uyuni = data.Uyuni
+}
+
+func (d *UyuniSystemsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data UyuniSystemsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if d.uyuni == nil {
+		resp.Diagnostics.AddError(
+			"Uyuni is not configured",
+			"The salty_uyuni_systems data source requires uyuni_base_url, uyuni_username and uyuni_password to be set on the provider.",
+		)
+		return
+	}
+
+	var nameRegex *regexp.Regexp
+	if data.NameRegex.ValueString() != "" {
+		var err error
+		nameRegex, err = regexp.Compile(data.NameRegex.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("name_regex"),
+				"Invalid name_regex",
+				fmt.Sprintf("name_regex is not a valid regular expression: %s", err),
+			)
+			return
+		}
+	}
+
+	systems, err := d.uyuni.ListSystems(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Cannot list the systems in Uyuni",
+			fmt.Sprintf("cannot list the systems in Uyuni %s: %s", d.uyuni.BaseURL, err),
+		)
+		return
+	}
+
+	slices.SortFunc(systems, func(a, b saltclient.UyuniSystem) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	data.Systems = []UyuniSystemModel{}
+	for _, system := range systems {
+		if nameRegex != nil && !nameRegex.MatchString(system.Name) {
+			continue
+		}
+		data.Systems = append(data.Systems, UyuniSystemModel{
+			Name:        types.StringValue(system.Name),
+			Id:          types.Int64Value(int64(system.ID)),
+			LastCheckin: types.StringValue(system.LastCheckin),
+		})
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("%d of %d Uyuni systems match", len(data.Systems), len(systems)))
+
+	data.Id = types.StringValue(d.uyuni.BaseURL)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// UnreachableError reports a server which did not become resolvable or reachable in time, as
+// freshly provisioned VMs often are for a minute or two.
+type UnreachableError struct {
+	Host    string
+	Port    string
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *UnreachableError) Error() string {
+	var dnsErr *net.DNSError
+	if errors.As(e.Err, &dnsErr) {
+		return fmt.Sprintf("host %s is not yet resolvable after %s, check its DNS record or raise ssh_reachability_timeout: %s", e.Host, e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("host %s is not yet reachable on port %s after %s, check that it is up and its SSH port is open or raise ssh_reachability_timeout: %s", e.Host, e.Port, e.Timeout, e.Err)
+}
+
+func (e *UnreachableError) Unwrap() error {
+	return e.Err
+}
+
+type reachabilityTimeoutKey struct{}
+
+// WithReachabilityTimeout returns ctx waiting up to timeout for the servers to become reachable,
+// instead of the ReachabilityTimeout of the SSHExecutor.
+func WithReachabilityTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, reachabilityTimeoutKey{}, timeout)
+}
+
+// ReachabilityTimeoutFrom returns the timeout set by WithReachabilityTimeout, or fallback.
+func ReachabilityTimeoutFrom(ctx context.Context, fallback time.Duration) time.Duration {
+	if timeout, ok := ctx.Value(reachabilityTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return fallback
+}
+
+// dialReachable opens a TCP connection to host, retrying with backoff while its name does not
+// resolve or its port does not accept connections yet, up to timeout or until ctx is done.
+func dialReachable(ctx context.Context, host string, port string, timeout time.Duration) (net.Conn, error) {
+	deadline := time.Now().Add(timeout)
+	backoff := time.Second
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !isNotYetReachable(err) {
+			return nil, err
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return nil, &UnreachableError{Host: host, Port: port, Timeout: timeout, Err: err}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 15*time.Second)
+	}
+}
+
+// isNotYetReachable reports whether err is expected from a server that is still booting.
+func isNotYetReachable(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsNotFound || dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH)
+}
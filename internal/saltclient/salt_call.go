@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MinionInstall describes where a minion keeps its salt-call binary and configuration.
+type MinionInstall struct {
+	SaltCall   string
+	ConfDir    string
+	GrainsFile string
+	Service    string
+	// Local runs salt-call with --local, for standalone minions without a master.
+	Local bool
+}
+
+// CallFlags are passed to every salt-call run, so its output is plain JSON whatever the
+// output, color and log settings of the minion are.
+const CallFlags = "--out=json --no-color --log-level=quiet"
+
+// Invocation returns the salt-call binary together with the --local flag of masterless mode.
+func (m MinionInstall) Invocation() string {
+	if m.Local {
+		return m.SaltCall + " --local"
+	}
+	return m.SaltCall
+}
+
+// Command returns the salt-call invocation of function with the positional args.
+func (m MinionInstall) Command(function string, args ...string) string {
+	return callCommand(m.Invocation(), function, args)
+}
+
+// LocalCommand is Command for functions run with --local, without contacting the master.
+func (m MinionInstall) LocalCommand(function string, args ...string) string {
+	return callCommand(m.SaltCall+" --local", function, args)
+}
+
+func callCommand(saltCall string, function string, args []string) string {
+	runCommand := fmt.Sprintf("%s %s %s", saltCall, CallFlags, function)
+	if len(args) > 0 {
+		runCommand = fmt.Sprintf("%s %s", runCommand, Args(args...))
+	}
+	return runCommand
+}
+
+// StateApplyCommand returns the state.apply run. The JSON output is printed once finished and
+// kept in /var/log/state.apply.tf.log with everything else. The run is refused by the minion
+// while another state run is in progress.
+// An empty saltenv leaves the environment to the minion configuration, pillar overrides the
+// pillar data of this run only.
+func (m MinionInstall) StateApplyCommand(saltenv string, pillar map[string]string) string {
+	stateApply := "state.apply"
+	if saltenv != "" {
+		stateApply = fmt.Sprintf("%s saltenv=%s", stateApply, ShellQuote(saltenv))
+	}
+	if len(pillar) > 0 {
+		// encoding/json sorts the keys, the command stays the same for the same pillar
+		content, _ := json.Marshal(pillar)
+		stateApply = fmt.Sprintf("%s %s", stateApply, ShellQuote("pillar="+string(content)))
+	}
+	return fmt.Sprintf("%s %s --retcode-passthrough --out=json --no-color > /var/log/state.apply.tf.json 2>> /var/log/state.apply.tf.log; retcode=$?; "+
+		"cat /var/log/state.apply.tf.json >> /var/log/state.apply.tf.log; cat /var/log/state.apply.tf.json; exit $retcode", m.Invocation(), stateApply)
+}
+
+// Args renders the positional arguments of a salt-call function. Each argument becomes
+// exactly one shell word with its value verbatim, so spaces, quotes and shell metacharacters
+// reach salt-call unchanged. Salt then YAML-parses each argument and takes one looking like
+// name=value as a keyword argument, string values are passed through StringArg for that.
+func Args(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ShellQuote quotes s for a POSIX shell.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// StringArg renders s as a salt-call argument Salt reads back as exactly the string s. A plain
+// "80", "true" or "None" would arrive as a number, a boolean or None, and "key=val" as a keyword
+// argument. s is passed as a double-quoted YAML scalar instead, with # escaped as Salt keeps an
+// argument containing it verbatim, quotes included, when it parses to a string.
+func StringArg(s string) string {
+	content, _ := json.Marshal(s)
+	return strings.ReplaceAll(string(content), "#", `\x23`)
+}
@@ -0,0 +1,213 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"gopkg.in/yaml.v3"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]struct {
+		arg  string
+		want string
+	}{
+		"empty":        {arg: "", want: `''`},
+		"plain":        {arg: "roles", want: `'roles'`},
+		"space":        {arg: "web server", want: `'web server'`},
+		"single quote": {arg: "it's", want: `'it'\''s'`},
+		"double quote": {arg: `say "hi"`, want: `'say "hi"'`},
+		"dollar":       {arg: "$HOME", want: `'$HOME'`},
+		"newline":      {arg: "a\nb", want: "'a\nb'"},
+		"key=value":    {arg: "delimiter=|", want: `'delimiter=|'`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ShellQuote(test.arg); got != test.want {
+				t.Errorf("ShellQuote(%q) = %s, want %s", test.arg, got, test.want)
+			}
+		})
+	}
+}
+
+func TestArgs(t *testing.T) {
+	tests := map[string][]string{
+		"empty":        {""},
+		"space":        {"roles", "web server"},
+		"single quote": {"it's"},
+		"double quote": {`"quoted"`, `say "hi"`},
+		"dollar":       {"$HOME", "$(id)", "`id`"},
+		"newline":      {"a\nb", "trailing\n"},
+		"key=value":    {"roles", "web", "delimiter=|"},
+		"json":         {`["web","db"]`, `{"a": 1}`},
+	}
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			// every argument must reach the command as one word with its value verbatim
+			output, err := exec.Command("sh", "-c", `printf '%s\0' `+Args(args...)).Output()
+			if err != nil {
+				t.Fatalf("sh failed: %s", err)
+			}
+			got := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+			if !slices.Equal(got, args) {
+				t.Errorf("Args(%q) reached the command as %q", args, got)
+			}
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	install := MinionInstall{SaltCall: "/usr/bin/salt-call"}
+
+	tests := map[string]struct {
+		install  MinionInstall
+		function string
+		args     []string
+		want     string
+	}{
+		"no args": {
+			install:  install,
+			function: "grains.items",
+			want:     "/usr/bin/salt-call --out=json --no-color --log-level=quiet grains.items",
+		},
+		"quoted args": {
+			install:  install,
+			function: "grains.append",
+			args:     []string{"roles", "web server", "delimiter=|"},
+			want:     "/usr/bin/salt-call --out=json --no-color --log-level=quiet grains.append 'roles' 'web server' 'delimiter=|'",
+		},
+		"masterless": {
+			install:  MinionInstall{SaltCall: "/usr/bin/salt-call", Local: true},
+			function: "grains.get",
+			args:     []string{"it's"},
+			want:     `/usr/bin/salt-call --local --out=json --no-color --log-level=quiet grains.get 'it'\''s'`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := test.install.Command(test.function, test.args...); got != test.want {
+				t.Errorf("Command() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStateApplyCommand(t *testing.T) {
+	install := MinionInstall{SaltCall: "/usr/bin/salt-call", Local: true}
+
+	tests := map[string]struct {
+		saltenv string
+		pillar  map[string]string
+		want    string
+	}{
+		"default": {want: "/usr/bin/salt-call --local state.apply --retcode-passthrough"},
+		"saltenv": {saltenv: "prod", want: "/usr/bin/salt-call --local state.apply saltenv='prod' --retcode-passthrough"},
+		"pillar":  {pillar: map[string]string{"b": "2", "a": "it's"}, want: `state.apply 'pillar={"a":"it'\''s","b":"2"}' --retcode-passthrough`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := install.StateApplyCommand(test.saltenv, test.pillar); !strings.Contains(got, test.want) {
+				t.Errorf("StateApplyCommand() = %s, want it to contain %s", got, test.want)
+			}
+		})
+	}
+}
+
+// saltKwarg is the pattern Salt takes a keyword argument by, from salt.utils.args.
+var saltKwarg = regexp.MustCompile(`^([^\d\W][\w.-]*)=(?:[^=]|$)`)
+
+// saltParse parses a command-line argument like salt.utils.args.parse_input and yamlify_arg,
+// reporting whether it is a keyword argument.
+func saltParse(t *testing.T, arg string) (any, bool) {
+	if saltKwarg.MatchString(arg) {
+		return nil, true
+	}
+	if arg == "None" {
+		return nil, false
+	}
+	var parsed any
+	if err := yaml.Unmarshal([]byte(arg), &parsed); err != nil {
+		t.Fatalf("Salt cannot parse %s: %s", arg, err)
+	}
+	if _, ok := parsed.(string); ok && strings.Contains(arg, "#") {
+		return arg, false
+	}
+	return parsed, false
+}
+
+func TestStringArg(t *testing.T) {
+	tests := map[string]string{
+		"empty":       "",
+		"plain":       "production",
+		"number":      "80",
+		"float":       "1.5",
+		"boolean":     "true",
+		"yes":         "yes",
+		"none":        "None",
+		"null":        "null",
+		"key=value":   "key=val",
+		"environment": "FOO=bar /bin/x",
+		"comment":     "a # b",
+		"list":        "[web, db]",
+		"dict":        "{a: 1}",
+		"quotes":      `it's "quoted"`,
+		"newline":     "a\nb",
+		"unicode":     "café ☕",
+		"html":        "<a href='x'>&</a>",
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			// the plain value is misread by Salt for most of the cases, the encoded one never
+			got, kwarg := saltParse(t, StringArg(value))
+			if kwarg {
+				t.Fatalf("StringArg(%q) = %s is taken as a keyword argument", value, StringArg(value))
+			}
+			if got != value {
+				t.Errorf("StringArg(%q) = %s reaches Salt as %#v", value, StringArg(value), got)
+			}
+
+			// and it still passes the shell as one word
+			output, err := exec.Command("sh", "-c", `printf '%s' `+Args(StringArg(value))).Output()
+			if err != nil {
+				t.Fatalf("sh failed: %s", err)
+			}
+			if string(output) != StringArg(value) {
+				t.Errorf("StringArg(%q) reached the command as %s", value, output)
+			}
+		})
+	}
+}
+
+func TestSaltParse(t *testing.T) {
+	// the cases StringArg guards against, so saltParse is known to catch them
+	tests := map[string]struct {
+		arg   string
+		want  any
+		kwarg bool
+	}{
+		"number":  {arg: "80", want: 80},
+		"boolean": {arg: "true", want: true},
+		"none":    {arg: "None", want: nil},
+		"kwarg":   {arg: "key=val", kwarg: true},
+		"comment": {arg: `"a # b"`, want: `"a # b"`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, kwarg := saltParse(t, test.arg)
+			if kwarg != test.kwarg || got != test.want {
+				t.Errorf("saltParse(%s) = %#v, %t, want %#v, %t", test.arg, got, kwarg, test.want, test.kwarg)
+			}
+		})
+	}
+}
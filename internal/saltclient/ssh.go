@@ -0,0 +1,388 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kevinburke/ssh_config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/net/proxy"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sshKeepaliveInterval is how often an idle or busy connection is probed, so NAT gateways and
+// firewalls do not drop it during a long state.apply.
+const sshKeepaliveInterval = 30 * time.Second
+
+// SSHExecutor is the Transport running commands on the servers over SSH. One connection per
+// server is kept open and every command runs in its own session on it.
+type SSHExecutor struct {
+	Username   string
+	PrivateKey string
+	// PrivateKeyPassphrase is empty for unencrypted keys.
+	PrivateKeyPassphrase string
+	Password             string
+	// AuthMethods lists the authentication methods in the order they are tried.
+	AuthMethods []string
+	Become      Become
+	// SSHConfig is the operator's ~/.ssh/config, nil unless use_ssh_config is enabled.
+	SSHConfig *ssh_config.Config
+	// ProxyDialer reaches the servers through ssh_proxy_url, nil for direct connections.
+	ProxyDialer proxy.Dialer
+	// CommandTimeout bounds the commands run without an explicit timeout, zero for no bound.
+	CommandTimeout time.Duration
+	// Algorithms restricts the ciphers, MACs and key exchanges, empty lists use the Go defaults.
+	Algorithms ssh.Config
+	// ReachabilityTimeout is how long a server may take to resolve and accept connections, see
+	// WithReachabilityTimeout for overriding it per command. Zero tries once.
+	ReachabilityTimeout time.Duration
+	// OnDial is called with how long connecting to server took, nil for none.
+	OnDial func(ctx context.Context, server string, elapsed time.Duration)
+	// OnCommand is called before runCommand runs on server, nil for none.
+	OnCommand func(ctx context.Context, server string, runCommand string)
+	// OnOutput is called with the output of a command once it finished on server, nil for none.
+	OnOutput func(ctx context.Context, server string, output string, elapsed time.Duration)
+
+	mu          sync.Mutex
+	clients     map[string]*ssh.Client
+	agentClient agent.ExtendedAgent
+}
+
+// Run executes runCommand on server and returns its stdout. A zero timeout applies
+// CommandTimeout, a negative one waits indefinitely.
+func (e *SSHExecutor) Run(ctx context.Context, server string, runCommand string, timeout time.Duration) (string, error) {
+	if timeout == 0 {
+		timeout = e.CommandTimeout
+	}
+
+	client, err := e.client(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		// the cached connection may have died since its last use, retry on a fresh one
+		e.drop(server, client)
+		client, err = e.client(ctx, server)
+		if err != nil {
+			return "", err
+		}
+		session, err = client.NewSession()
+		if err != nil {
+			return "", fmt.Errorf("cannot create session with the Salt Minion %s: %s", server, err)
+		}
+	}
+	defer session.Close()
+
+	if e.OnCommand != nil {
+		e.OnCommand(ctx, server, runCommand)
+	}
+	start := time.Now()
+	cmdOutput, err := sessionOutput(ctx, session, e.Become.Wrap(runCommand), timeout)
+	if e.OnOutput != nil {
+		e.OnOutput(ctx, server, string(cmdOutput), time.Since(start))
+	}
+
+	if errors.Is(err, ErrCommandTimeout) {
+		// the session is closed by now, so a wedged salt-call does not keep running on our side
+		return "", fmt.Errorf("cannot run the command on Salt Minion %s: %w after %s, raise command_timeout if it legitimately takes longer", server, err, timeout)
+	}
+	if err != nil {
+		// the output of a command exiting non-zero may still be needed, e.g. a state.apply summary
+		return string(cmdOutput), fmt.Errorf("cannot run the command on Salt Minion %s: %w", server, err)
+	}
+
+	return string(cmdOutput), nil
+}
+
+// client returns the open connection to server, dialing it on first use.
+func (e *SSHExecutor) client(ctx context.Context, server string) (*ssh.Client, error) {
+	e.mu.Lock()
+	cached, ok := e.clients[server]
+	e.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	// dial without holding the lock, so connections to different servers open in parallel
+	start := time.Now()
+	client, err := e.dial(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	if e.OnDial != nil {
+		e.OnDial(ctx, server, time.Since(start))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if existing, ok := e.clients[server]; ok {
+		_ = client.Close()
+		return existing, nil
+	}
+	if e.clients == nil {
+		e.clients = map[string]*ssh.Client{}
+	}
+	e.clients[server] = client
+	go e.keepalive(server, client)
+
+	return client, nil
+}
+
+// dial opens a connection to server, through the jump hosts of its SSH config entry if any.
+func (e *SSHExecutor) dial(ctx context.Context, server string) (*ssh.Client, error) {
+	host := e.hostConfig(server)
+
+	var jump *ssh.Client
+	for _, hop := range host.ProxyJump {
+		next, err := e.dialThrough(ctx, jump, hop)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to the Salt Minion %s through the jump host %s: %s", server, hop.HostName, err)
+		}
+		jump = next
+	}
+
+	client, err := e.dialThrough(ctx, jump, host)
+	var unreachable *UnreachableError
+	if errors.As(err, &unreachable) {
+		return nil, fmt.Errorf("cannot connect to the Salt Minion %s: %w", server, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the Salt Minion %s with the auth methods %s: %s", server, strings.Join(e.AuthMethods, ", "), err)
+	}
+	return client, nil
+}
+
+// dialThrough opens a connection to host tunneled through jump or, when jump is nil, directly
+// or through the SOCKS proxy. The jump connection is closed together with the returned one.
+func (e *SSHExecutor) dialThrough(ctx context.Context, jump *ssh.Client, host sshHostConfig) (*ssh.Client, error) {
+	config, err := e.clientConfig(host)
+	if err != nil {
+		return nil, err
+	}
+
+	address := net.JoinHostPort(host.HostName, host.Port)
+	if jump == nil {
+		var conn net.Conn
+		if e.ProxyDialer == nil {
+			conn, err = dialReachable(ctx, host.HostName, host.Port, ReachabilityTimeoutFrom(ctx, e.ReachabilityTimeout))
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			conn, err = dialProxy(ctx, e.ProxyDialer, address)
+			if err != nil {
+				return nil, fmt.Errorf("cannot connect through the SSH proxy: %s", err)
+			}
+		}
+		clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return ssh.NewClient(clientConn, chans, reqs), nil
+	}
+
+	conn, err := jump.DialContext(ctx, "tcp", address)
+	if err != nil {
+		_ = jump.Close()
+		return nil, err
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		_ = jump.Close()
+		return nil, err
+	}
+
+	client := ssh.NewClient(clientConn, chans, reqs)
+	go func() {
+		_ = client.Wait()
+		_ = jump.Close()
+	}()
+	return client, nil
+}
+
+// dialProxy connects to address through the SOCKS proxy, giving up once ctx is done.
+func dialProxy(ctx context.Context, dialer proxy.Dialer, address string) (net.Conn, error) {
+	if contextDialer, ok := dialer.(proxy.ContextDialer); ok {
+		return contextDialer.DialContext(ctx, "tcp", address)
+	}
+	return dialer.Dial("tcp", address)
+}
+
+// clientConfig returns the SSH client configuration for host.
+func (e *SSHExecutor) clientConfig(host sshHostConfig) (*ssh.ClientConfig, error) {
+	auth, err := e.authMethods(host.IdentityFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		Config:          e.Algorithms,
+		User:            host.User,
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}, nil
+}
+
+// Supported AuthMethods.
+const (
+	SSHAuthKey      = "key"
+	SSHAuthAgent    = "agent"
+	SSHAuthPassword = "password"
+)
+
+// authMethods returns the configured auth methods in order, preceded by identityFile when the
+// SSH config of the host names one.
+func (e *SSHExecutor) authMethods(identityFile string) ([]ssh.AuthMethod, error) {
+	var auth []ssh.AuthMethod
+
+	if identityFile != "" {
+		signer, err := readIdentityFile(identityFile, e.PrivateKeyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	for _, method := range e.AuthMethods {
+		switch method {
+		case SSHAuthKey:
+			signer, err := ParsePrivateKey(e.PrivateKey, e.PrivateKeyPassphrase)
+			if err != nil {
+				return nil, fmt.Errorf("malformed private key: %s, please report this issue to the provider developers", err)
+			}
+			auth = append(auth, ssh.PublicKeys(signer))
+		case SSHAuthAgent:
+			sshAgent, err := e.agent()
+			if err != nil {
+				return nil, err
+			}
+			auth = append(auth, ssh.PublicKeysCallback(sshAgent.Signers))
+		case SSHAuthPassword:
+			auth = append(auth, ssh.Password(e.Password))
+		}
+	}
+
+	return auth, nil
+}
+
+// agent returns the client of the SSH agent at SSH_AUTH_SOCK, connecting on first use. The
+// connection stays open, as the agent signs during every handshake.
+func (e *SSHExecutor) agent() (agent.ExtendedAgent, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.agentClient == nil {
+		conn, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to the SSH agent: %s", err)
+		}
+		e.agentClient = agent.NewClient(conn)
+	}
+	return e.agentClient, nil
+}
+
+// drop closes client and forgets it, unless it was already replaced.
+func (e *SSHExecutor) drop(server string, client *ssh.Client) {
+	e.mu.Lock()
+	if e.clients[server] == client {
+		delete(e.clients, server)
+	}
+	e.mu.Unlock()
+
+	_ = client.Close()
+}
+
+// keepalive probes client until the connection fails or is closed.
+func (e *SSHExecutor) keepalive(server string, client *ssh.Client) {
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			e.drop(server, client)
+			return
+		case <-ticker.C:
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			if err != nil {
+				e.drop(server, client)
+				return
+			}
+		}
+	}
+}
+
+// ParsePrivateKey parses key, decrypting it with passphrase when one is given.
+func ParsePrivateKey(key string, passphrase string) (ssh.Signer, error) {
+	if passphrase != "" {
+		return ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
+	}
+	return ssh.ParsePrivateKey([]byte(key))
+}
+
+// Become describes the privilege escalation wrapped around remote commands.
+type Become struct {
+	Enabled bool
+	Method  string
+	User    string
+}
+
+// Wrap returns runCommand wrapped in the configured privilege escalation, if enabled.
+func (b Become) Wrap(runCommand string) string {
+	if !b.Enabled {
+		return runCommand
+	}
+
+	user := b.User
+	if user == "" {
+		user = "root"
+	}
+
+	// -n makes sudo/doas fail instead of waiting for a password prompt nobody answers
+	return fmt.Sprintf("%s -n -u %s sh -c %s", b.Method, ShellQuote(user), ShellQuote(runCommand))
+}
+
+// sessionOutput runs the command on the session and returns its stdout. The session is closed
+// when ctx is cancelled or, for a positive timeout, once the timeout passes.
+func sessionOutput(ctx context.Context, session *ssh.Session, runCommand string, timeout time.Duration) ([]byte, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrCommandTimeout)
+		defer cancel()
+	}
+
+	type result struct {
+		output []byte
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, err := session.Output(runCommand)
+		done <- result{output, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.output, res.err
+	case <-ctx.Done():
+		_ = session.Close()
+		return nil, context.Cause(ctx)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"fmt"
+	"github.com/kevinburke/ssh_config"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sshHostConfig holds the connection settings of one host.
+type sshHostConfig struct {
+	HostName     string
+	User         string
+	Port         string
+	IdentityFile string
+	ProxyJump    []sshHostConfig
+}
+
+// LoadSSHConfig reads the operator's ~/.ssh/config. A missing file is an empty config.
+func LoadSSHConfig() (*ssh_config.Config, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(home, ".ssh", "config"))
+	if os.IsNotExist(err) {
+		return &ssh_config.Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ssh_config.Decode(f)
+}
+
+// SplitServerAddress splits a server address into host and port. The port is empty unless given
+// as host:port or [ipv6]:port, bare IPv6 literals are accepted with or without brackets.
+func SplitServerAddress(server string) (string, string) {
+	if host, port, err := net.SplitHostPort(server); err == nil {
+		return host, port
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(server, "["), "]"), ""
+}
+
+// hostConfig returns the settings for server, taken from the SSH config when enabled and the
+// provider configuration otherwise. A port in the server address always wins.
+func (e *SSHExecutor) hostConfig(server string) sshHostConfig {
+	name, port := SplitServerAddress(server)
+	host := sshHostConfig{HostName: name, User: e.Username, Port: "22"}
+
+	if e.SSHConfig != nil {
+		host = e.lookupSSHConfig(name, host)
+
+		proxyJump, _ := e.SSHConfig.Get(name, "ProxyJump")
+		if proxyJump != "" && proxyJump != "none" {
+			for _, hop := range strings.Split(proxyJump, ",") {
+				host.ProxyJump = append(host.ProxyJump, e.parseJumpHost(strings.TrimSpace(hop)))
+			}
+		}
+	}
+
+	if port != "" {
+		host.Port = port
+	}
+	return host
+}
+
+// lookupSSHConfig overrides the settings in host with the ones of alias in the SSH config.
+func (e *SSHExecutor) lookupSSHConfig(alias string, host sshHostConfig) sshHostConfig {
+	if hostName, _ := e.SSHConfig.Get(alias, "HostName"); hostName != "" {
+		host.HostName = hostName
+	}
+	if user, _ := e.SSHConfig.Get(alias, "User"); user != "" {
+		host.User = user
+	}
+	if port, _ := e.SSHConfig.Get(alias, "Port"); port != "" {
+		host.Port = port
+	}
+	if identityFile, _ := e.SSHConfig.Get(alias, "IdentityFile"); identityFile != "" {
+		host.IdentityFile = identityFile
+	}
+	return host
+}
+
+// parseJumpHost parses a [user@]host[:port] ProxyJump entry, which may itself be an alias in the
+// SSH config.
+func (e *SSHExecutor) parseJumpHost(hop string) sshHostConfig {
+	user, hostPort, found := strings.Cut(hop, "@")
+	if !found {
+		user, hostPort = "", hop
+	}
+	name, port := SplitServerAddress(hostPort)
+
+	host := e.lookupSSHConfig(name, sshHostConfig{HostName: name, User: e.Username, Port: "22"})
+	if user != "" {
+		host.User = user
+	}
+	if port != "" {
+		host.Port = port
+	}
+	return host
+}
+
+// readIdentityFile parses the private key at path, decrypting it with passphrase if needed.
+func readIdentityFile(path string, passphrase string) (ssh.Signer, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the IdentityFile %s: %s", path, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if _, missing := err.(*ssh.PassphraseMissingError); missing && passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the IdentityFile %s: %s", path, err)
+	}
+	return signer, nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"github.com/kevinburke/ssh_config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHostConfig(t *testing.T) {
+	config, err := ssh_config.Decode(strings.NewReader(`
+Host minion
+  HostName minion.example.com
+  User admin
+  Port 2222
+  IdentityFile ~/.ssh/minion
+  ProxyJump bastion,jump@gateway:2200
+
+Host bastion
+  HostName bastion.example.com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		config *ssh_config.Config
+		server string
+		want   sshHostConfig
+	}{
+		"without ssh config": {
+			server: "minion",
+			want:   sshHostConfig{HostName: "minion", User: "salt", Port: "22"},
+		},
+		"port in the address": {
+			server: "[2001:db8::1]:2022",
+			want:   sshHostConfig{HostName: "2001:db8::1", User: "salt", Port: "2022"},
+		},
+		"alias with jump hosts": {
+			config: config,
+			server: "minion",
+			want: sshHostConfig{
+				HostName: "minion.example.com", User: "admin", Port: "2222", IdentityFile: "~/.ssh/minion",
+				ProxyJump: []sshHostConfig{
+					{HostName: "bastion.example.com", User: "salt", Port: "22"},
+					{HostName: "gateway", User: "jump", Port: "2200"},
+				},
+			},
+		},
+		"address wins over the ssh config": {
+			config: config,
+			server: "minion:22",
+			want: sshHostConfig{
+				HostName: "minion.example.com", User: "admin", Port: "22", IdentityFile: "~/.ssh/minion",
+				ProxyJump: []sshHostConfig{
+					{HostName: "bastion.example.com", User: "salt", Port: "22"},
+					{HostName: "gateway", User: "jump", Port: "2200"},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			executor := &SSHExecutor{Username: "salt", SSHConfig: test.config}
+			if got := executor.hostConfig(test.server); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("hostConfig(%s) = %+v, want %+v", test.server, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSSHServer accepts password logins and answers every command with its text instead of
+// running it. The command "exit N" fails with status N, "sleep" runs until the session closes.
+type fakeSSHServer struct {
+	address     string
+	connections atomic.Int32
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeSSHServer(t *testing.T) *fakeSSHServer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() != "salt" || string(password) != "secret" {
+				return nil, errors.New("access denied")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	server := &fakeSSHServer{address: listener.Addr().String()}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	s.connections.Add(1)
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.session(channel, requests)
+	}
+}
+
+func (s *fakeSSHServer) session(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		if req.Type != "exec" {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			_ = req.Reply(false, nil)
+			return
+		}
+		_ = req.Reply(true, nil)
+
+		s.mu.Lock()
+		s.commands = append(s.commands, payload.Command)
+		s.mu.Unlock()
+
+		var status uint32
+		switch {
+		case payload.Command == "sleep":
+			// the client closing the session ends the loop over requests
+			for range requests {
+			}
+			return
+		case strings.HasPrefix(payload.Command, "exit "):
+			_, _ = fmt.Sscanf(payload.Command, "exit %d", &status)
+		default:
+			_, _ = fmt.Fprintf(channel, "ran %s", payload.Command)
+		}
+		_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+		return
+	}
+}
+
+func newTestExecutor() *SSHExecutor {
+	return &SSHExecutor{
+		Username:    "salt",
+		Password:    "secret",
+		AuthMethods: []string{SSHAuthPassword},
+	}
+}
+
+func TestSSHExecutorRun(t *testing.T) {
+	server := newFakeSSHServer(t)
+	executor := newTestExecutor()
+
+	var dials, commands, outputs atomic.Int32
+	executor.OnDial = func(ctx context.Context, server string, elapsed time.Duration) { dials.Add(1) }
+	executor.OnCommand = func(ctx context.Context, server string, runCommand string) { commands.Add(1) }
+	executor.OnOutput = func(ctx context.Context, server string, output string, elapsed time.Duration) { outputs.Add(1) }
+
+	for _, runCommand := range []string{"true", "grains.items"} {
+		output, err := executor.Run(context.Background(), server.address, runCommand, 0)
+		if err != nil {
+			t.Fatalf("Run(%s) failed: %s", runCommand, err)
+		}
+		if want := "ran " + runCommand; output != want {
+			t.Errorf("Run(%s) = %q, want %q", runCommand, output, want)
+		}
+	}
+
+	// both commands run on the one connection kept open
+	if got := server.connections.Load(); got != 1 {
+		t.Errorf("%d connections were opened, want 1", got)
+	}
+	if dials.Load() != 1 || commands.Load() != 2 || outputs.Load() != 2 {
+		t.Errorf("OnDial, OnCommand and OnOutput were called %d, %d and %d times, want 1, 2 and 2", dials.Load(), commands.Load(), outputs.Load())
+	}
+}
+
+func TestSSHExecutorErrors(t *testing.T) {
+	server := newFakeSSHServer(t)
+	executor := newTestExecutor()
+
+	_, err := executor.Run(context.Background(), server.address, "exit 127", 0)
+	var exitErr interface{ ExitStatus() int }
+	if !errors.As(err, &exitErr) || exitErr.ExitStatus() != 127 {
+		t.Errorf("Run(exit 127) = %v, want exit status 127", err)
+	}
+
+	_, err = executor.Run(context.Background(), server.address, "sleep", 100*time.Millisecond)
+	if !errors.Is(err, ErrCommandTimeout) {
+		t.Errorf("Run(sleep) = %v, want ErrCommandTimeout", err)
+	}
+
+	executor = newTestExecutor()
+	executor.Password = "wrong"
+	if _, err := executor.Run(context.Background(), server.address, "true", 0); err == nil || !strings.Contains(err.Error(), "auth methods password") {
+		t.Errorf("Run with a wrong password = %v, want an authentication error", err)
+	}
+}
+
+func TestSSHExecutorBecome(t *testing.T) {
+	server := newFakeSSHServer(t)
+	executor := newTestExecutor()
+	executor.Become = Become{Enabled: true, Method: "doas"}
+
+	if _, err := executor.Run(context.Background(), server.address, "id -u", 0); err != nil {
+		t.Fatal(err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if want := `doas -n -u 'root' sh -c 'id -u'`; len(server.commands) != 1 || server.commands[0] != want {
+		t.Errorf("the server got %q, want %q", server.commands, want)
+	}
+}
+
+func TestSSHExecutorUnreachable(t *testing.T) {
+	// a port nothing listens on any more
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	executor := newTestExecutor()
+	executor.ReachabilityTimeout = time.Hour
+	// the per command timeout wins over the hour of the executor
+	ctx := WithReachabilityTimeout(context.Background(), 0)
+
+	_, err = executor.Run(ctx, address, "true", 0)
+	var unreachable *UnreachableError
+	if !errors.As(err, &unreachable) {
+		t.Fatalf("Run on a closed port = %v, want an UnreachableError", err)
+	}
+	if host, port, _ := net.SplitHostPort(address); unreachable.Host != host || unreachable.Port != port {
+		t.Errorf("UnreachableError is for %s:%s, want %s", unreachable.Host, unreachable.Port, address)
+	}
+}
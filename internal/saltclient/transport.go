@@ -0,0 +1,23 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package saltclient runs salt-call commands on the minions over SSH and talks to the Uyuni
+// HTTP API. It holds no Terraform types, so tools other than the provider can reuse it.
+package saltclient
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Transport runs shell commands on a server, e.g. over SSH or through salt-api.
+type Transport interface {
+	// Run executes runCommand on server and returns its stdout. A zero timeout applies the
+	// default of the implementation, a negative one waits indefinitely. ErrCommandTimeout is
+	// returned once the timeout passes.
+	Run(ctx context.Context, server string, runCommand string, timeout time.Duration) (string, error)
+}
+
+// ErrCommandTimeout is returned when a remote command does not finish within its deadline.
+var ErrCommandTimeout = errors.New("command timed out")
@@ -0,0 +1,443 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// UyuniClient holds the connection settings for the Uyuni HTTP API.
+type UyuniClient struct {
+	BaseURL       string
+	Username      string
+	Password      string
+	RetryAttempts int
+	// ProxyURL overrides the HTTPS_PROXY/NO_PROXY environment when set.
+	ProxyURL string
+	// ClientCertificate is presented to an mTLS-terminating proxy in front of the API, nil if none.
+	ClientCertificate *tls.Certificate
+	// RootCAs verify the certificate of the API, nil for the system roots. The certificate is
+	// only left unverified when neither RootCAs nor ClientCertificate is set, as with the
+	// self-signed certificate of a default Uyuni install.
+	RootCAs *x509.CertPool
+	// HTTPTimeout bounds every API request, retries included.
+	HTTPTimeout time.Duration
+	// OnCall is called with the name of each API method and how long it took, nil for none.
+	OnCall func(method string, elapsed time.Duration)
+}
+
+// retryTransport retries requests failing with transient errors, e.g. while Uyuni is restarting.
+type retryTransport struct {
+	next     http.RoundTripper
+	attempts int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	// a method changing data may have been applied before a reset connection or a gateway
+	// timeout, only requests Uyuni never got are sent again
+	idempotent := req.Method == http.MethodGet || strings.HasSuffix(req.URL.Path, "/auth/login")
+
+	for attempt := 1; ; attempt++ {
+		resp, err = t.next.RoundTrip(req)
+		if attempt >= t.attempts || !isTransientUyuniError(resp, err, idempotent) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Duration(attempt) * 2 * time.Second):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body for retry: %w", err)
+			}
+			req.Body = body
+		}
+	}
+}
+
+// isTransientUyuniError reports whether the request is worth retrying. Requests which are not
+// idempotent are only retried when they did not reach Uyuni: a refused connection or a 503 of
+// Uyuni or its proxy while it is restarting.
+func isTransientUyuniError(resp *http.Response, err error, idempotent bool) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED) || (idempotent && errors.Is(err, syscall.ECONNRESET))
+	}
+
+	switch resp.StatusCode {
+	case http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// uyuniTransportKey are the settings an HTTP transport for the Uyuni API is built from.
+type uyuniTransportKey struct {
+	proxyURL          string
+	clientCertificate *tls.Certificate
+	rootCAs           *x509.CertPool
+}
+
+// uyuniTransports holds the transports built so far. Each login gets a new cookie jar but
+// reuses the transport, and with it the idle connections, of the earlier ones. A client copied
+// for another BaseURL or user keeps its proxy and TLS settings and so its transport.
+var uyuniTransports = struct {
+	sync.Mutex
+	byKey map[uyuniTransportKey]*http.Transport
+}{byKey: map[uyuniTransportKey]*http.Transport{}}
+
+// transport returns the HTTP transport used for Uyuni API requests, shared by the clients with
+// the same proxy and TLS settings.
+func (c *UyuniClient) transport() (*http.Transport, error) {
+	key := uyuniTransportKey{proxyURL: c.ProxyURL, clientCertificate: c.ClientCertificate, rootCAs: c.RootCAs}
+
+	uyuniTransports.Lock()
+	defer uyuniTransports.Unlock()
+	if transport, ok := uyuniTransports.byKey[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := c.newTransport()
+	if err != nil {
+		return nil, err
+	}
+	uyuniTransports.byKey[key] = transport
+	return transport, nil
+}
+
+// newTransport builds the HTTP transport of the proxy and TLS settings of the client.
+func (c *UyuniClient) newTransport() (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if c.ProxyURL != "" {
+		proxyURL, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy URL %s: %w", c.ProxyURL, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	// the client certificate must only be presented to the server it is meant for
+	tlsConfig := &tls.Config{
+		RootCAs:            c.RootCAs,
+		InsecureSkipVerify: c.RootCAs == nil && c.ClientCertificate == nil,
+	}
+	if c.ClientCertificate != nil {
+		tlsConfig.Certificates = []tls.Certificate{*c.ClientCertificate}
+	}
+
+	return &http.Transport{
+		Proxy:           proxy,
+		TLSClientConfig: tlsConfig,
+	}, nil
+}
+
+// login logs in to the Uyuni API and returns an HTTP client carrying the session cookie.
+func (c *UyuniClient) login(ctx context.Context) (*http.Client, error) {
+	// Create HTTP client with cookie jar
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	client := &http.Client{
+		Jar:     jar,
+		Timeout: c.HTTPTimeout,
+	}
+
+	// Login payload
+	loginPayload := map[string]string{
+		"login":    c.Username,
+		"password": c.Password,
+	}
+	payloadBytes, err := json.Marshal(loginPayload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal login payload: %w", err)
+	}
+
+	// Perform login request
+	loginURL := fmt.Sprintf("%s/auth/login", strings.TrimRight(c.BaseURL, "/"))
+	req, err := http.NewRequestWithContext(ctx, "POST", loginURL, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	transport, err := c.transport()
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = &retryTransport{
+		next:     transport,
+		attempts: c.RetryAttempts,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("login request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("login failed: %s", string(body))
+	}
+
+	return client, nil
+}
+
+// CheckLogin logs in to the Uyuni API to check the credentials.
+func (c *UyuniClient) CheckLogin(ctx context.Context) error {
+	_, err := c.login(ctx)
+	return err
+}
+
+// get calls a read-only Uyuni API method and decodes its result into result.
+func (c *UyuniClient) get(ctx context.Context, client *http.Client, method string, result any) error {
+	return c.call(ctx, client, "GET", method, nil, result)
+}
+
+// post calls a Uyuni API method changing data with params sent as JSON, and decodes its result
+// into result unless it is nil.
+func (c *UyuniClient) post(ctx context.Context, client *http.Client, method string, params any, result any) error {
+	return c.call(ctx, client, "POST", method, params, result)
+}
+
+// call sends one request to the Uyuni API and unwraps the result from its response envelope.
+func (c *UyuniClient) call(ctx context.Context, client *http.Client, httpMethod string, method string, params any, result any) error {
+	var body io.Reader
+	if params != nil {
+		payload, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s params: %w", method, err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	methodURL := fmt.Sprintf("%s/%s", strings.TrimRight(c.BaseURL, "/"), method)
+	req, err := http.NewRequestWithContext(ctx, httpMethod, methodURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	// the query of a method may carry the names of systems or keys, it is left out
+	methodName, _, _ := strings.Cut(method, "?")
+	if c.OnCall != nil {
+		c.OnCall(methodName, time.Since(start))
+	}
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to fetch %s: %s", method, string(body))
+	}
+
+	// Parse the result
+	var envelope struct {
+		Success bool            `json:"success"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", method, err)
+	}
+
+	if !envelope.Success {
+		return &uyuniAPIError{method: method, message: envelope.Message}
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Result, result); err != nil {
+		return fmt.Errorf("failed to parse %s result: %w", method, err)
+	}
+
+	return nil
+}
+
+// uyuniAPIError is an error reported by a Uyuni API method itself.
+type uyuniAPIError struct {
+	method  string
+	message string
+}
+
+func (e *uyuniAPIError) Error() string {
+	return fmt.Sprintf("%s returned an error: %s", e.method, e.message)
+}
+
+// IsUyuniNotFound reports whether err is Uyuni saying the requested object does not exist.
+func IsUyuniNotFound(err error) bool {
+	var apiErr *uyuniAPIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	message := strings.ToLower(apiErr.message)
+	return strings.Contains(message, "not found") || strings.Contains(message, "could not find") || strings.Contains(message, "no such")
+}
+
+// CheckServerAccepted logs in and checks if a server is in the accepted list.
+func (c *UyuniClient) CheckServerAccepted(ctx context.Context, serverName string) (bool, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var accepted []string
+	if err := c.get(ctx, client, "saltkey/acceptedList", &accepted); err != nil {
+		return false, err
+	}
+
+	// Check if the server is in the list
+	for _, s := range accepted {
+		if s == serverName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListPendingKeys logs in and returns the minion IDs whose salt-key is waiting for acceptance.
+func (c *UyuniClient) ListPendingKeys(ctx context.Context) ([]string, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	if err := c.get(ctx, client, "saltkey/pendingList", &pending); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// UyuniSystem is a system registered in Uyuni, as returned by system.listSystems.
+type UyuniSystem struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	LastCheckin string `json:"last_checkin"`
+}
+
+// ListSystems logs in and returns the systems registered in Uyuni.
+func (c *UyuniClient) ListSystems(ctx context.Context) ([]UyuniSystem, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var systems []UyuniSystem
+	if err := c.get(ctx, client, "system/listSystems", &systems); err != nil {
+		return nil, err
+	}
+
+	return systems, nil
+}
+
+// SystemID logs in and resolves a system profile name to its Uyuni system ID.
+func (c *UyuniClient) SystemID(ctx context.Context, systemName string) (int, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.GetSystemID(ctx, client, systemName)
+}
+
+// ErrUyuniSystemNotRegistered is returned by GetSystemID for a name without a system profile.
+var ErrUyuniSystemNotRegistered = errors.New("not registered in Uyuni")
+
+// GetSystemID resolves a system profile name to its Uyuni system ID.
+func (c *UyuniClient) GetSystemID(ctx context.Context, client *http.Client, systemName string) (int, error) {
+	var systems []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := c.get(ctx, client, fmt.Sprintf("system/getId?name=%s", url.QueryEscape(systemName)), &systems); err != nil {
+		return 0, err
+	}
+
+	if len(systems) == 0 {
+		return 0, fmt.Errorf("system %s is %w", systemName, ErrUyuniSystemNotRegistered)
+	}
+	if len(systems) > 1 {
+		return 0, fmt.Errorf("system name %s is ambiguous, %d systems registered in Uyuni", systemName, len(systems))
+	}
+
+	return systems[0].ID, nil
+}
+
+// ErrUyuniGrainNotStored is returned by ReadGrain when Uyuni keeps no custom value for the grain.
+var ErrUyuniGrainNotStored = errors.New("grain not stored in Uyuni")
+
+// ReadGrain reads a grain from the custom system information Uyuni keeps for the system.
+// The value is returned in the same JSON shape as `salt-call grains.get --out=json`.
+func (c *UyuniClient) ReadGrain(ctx context.Context, systemName, grainKey string) (string, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return "", err
+	}
+
+	var customValues map[string]string
+	if err := c.get(ctx, client, fmt.Sprintf("system/getCustomValues?sid=%d", systemID), &customValues); err != nil {
+		return "", err
+	}
+
+	value, ok := customValues[grainKey]
+	if !ok {
+		return "", fmt.Errorf("system %s has no custom value %s: %w", systemName, grainKey, ErrUyuniGrainNotStored)
+	}
+
+	// list and structured grains are stored as JSON, anything else is a plain string
+	grain := json.RawMessage(value)
+	if !json.Valid(grain) {
+		grain, err = json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode custom value %s: %w", grainKey, err)
+		}
+	}
+
+	result, err := json.Marshal(map[string]json.RawMessage{"local": grain})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode grain %s: %w", grainKey, err)
+	}
+
+	return string(result), nil
+}
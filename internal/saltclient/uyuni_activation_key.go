@@ -0,0 +1,246 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// UyuniActivationKey is an activation key with the settings managed by the provider.
+type UyuniActivationKey struct {
+	Key                string
+	Description        string
+	BaseChannelLabel   string
+	ChildChannelLabels []string
+	Entitlements       []string
+	PackageNames       []string
+	ServerGroupIDs     []int
+	UniversalDefault   bool
+	UsageLimit         int
+	ContactMethod      string
+}
+
+// uyuniActivationKeyDetails is an activation key as returned by activationkey.getDetails.
+type uyuniActivationKeyDetails struct {
+	Key                string   `json:"key"`
+	Description        string   `json:"description"`
+	BaseChannelLabel   string   `json:"base_channel_label"`
+	ChildChannelLabels []string `json:"child_channel_labels"`
+	Entitlements       []string `json:"entitlements"`
+	ServerGroupIDs     []int    `json:"server_group_ids"`
+	Packages           []struct {
+		Name string `json:"name"`
+	} `json:"packages"`
+	UniversalDefault bool   `json:"universal_default"`
+	UsageLimit       int    `json:"usage_limit"`
+	ContactMethod    string `json:"contact_method"`
+}
+
+func (d uyuniActivationKeyDetails) activationKey() UyuniActivationKey {
+	key := UyuniActivationKey{
+		Key:                d.Key,
+		Description:        d.Description,
+		BaseChannelLabel:   d.BaseChannelLabel,
+		ChildChannelLabels: d.ChildChannelLabels,
+		Entitlements:       d.Entitlements,
+		ServerGroupIDs:     d.ServerGroupIDs,
+		UniversalDefault:   d.UniversalDefault,
+		UsageLimit:         d.UsageLimit,
+		ContactMethod:      d.ContactMethod,
+	}
+	// "none" is how Uyuni reports a key following the organization's default base channel
+	if key.BaseChannelLabel == "none" {
+		key.BaseChannelLabel = ""
+	}
+	for _, pkg := range d.Packages {
+		key.PackageNames = append(key.PackageNames, pkg.Name)
+	}
+	return key
+}
+
+// GetActivationKey logs in and returns the activation key.
+func (c *UyuniClient) GetActivationKey(ctx context.Context, key string) (*UyuniActivationKey, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.getActivationKey(ctx, client, key)
+}
+
+func (c *UyuniClient) getActivationKey(ctx context.Context, client *http.Client, key string) (*UyuniActivationKey, error) {
+	var details uyuniActivationKeyDetails
+	if err := c.get(ctx, client, fmt.Sprintf("activationkey/getDetails?key=%s", url.QueryEscape(key)), &details); err != nil {
+		return nil, err
+	}
+
+	activationKey := details.activationKey()
+	return &activationKey, nil
+}
+
+// ListActivationKeys logs in and returns the activation keys of the organization.
+func (c *UyuniClient) ListActivationKeys(ctx context.Context) ([]UyuniActivationKey, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var details []uyuniActivationKeyDetails
+	if err := c.get(ctx, client, "activationkey/listActivationKeys", &details); err != nil {
+		return nil, err
+	}
+
+	keys := make([]UyuniActivationKey, 0, len(details))
+	for _, d := range details {
+		keys = append(keys, d.activationKey())
+	}
+	return keys, nil
+}
+
+// CreateActivationKey logs in, creates the activation key and returns its key as prefixed by
+// Uyuni with the organization ID.
+func (c *UyuniClient) CreateActivationKey(ctx context.Context, desired UyuniActivationKey) (string, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var key string
+	err = c.post(ctx, client, "activationkey/create", map[string]any{
+		"key":              desired.Key,
+		"description":      desired.Description,
+		"baseChannelLabel": desired.BaseChannelLabel,
+		"entitlements":     nonNil(desired.Entitlements),
+		"universalDefault": desired.UniversalDefault,
+	}, &key)
+	if err != nil {
+		return "", err
+	}
+
+	created := UyuniActivationKey{Key: key, Description: desired.Description, BaseChannelLabel: desired.BaseChannelLabel, Entitlements: desired.Entitlements, UniversalDefault: desired.UniversalDefault}
+	if err := c.changeActivationKey(ctx, client, created, desired); err != nil {
+		return key, fmt.Errorf("activation key %s was created but could not be completed: %w", key, err)
+	}
+	return key, nil
+}
+
+// UpdateActivationKey logs in and changes the activation key to the desired settings.
+func (c *UyuniClient) UpdateActivationKey(ctx context.Context, key string, desired UyuniActivationKey) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := c.getActivationKey(ctx, client, key)
+	if err != nil {
+		return err
+	}
+
+	baseChannel := desired.BaseChannelLabel
+	if baseChannel == "" {
+		baseChannel = "none"
+	}
+	err = c.post(ctx, client, "activationkey/setDetails", map[string]any{
+		"key": key,
+		"details": map[string]any{
+			"description":        desired.Description,
+			"base_channel_label": baseChannel,
+			"universal_default":  desired.UniversalDefault,
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	current.Key = key
+	return c.changeActivationKey(ctx, client, *current, desired)
+}
+
+// changeActivationKey adds and removes the entitlements, packages and server groups in which
+// current differs from desired.
+func (c *UyuniClient) changeActivationKey(ctx context.Context, client *http.Client, current UyuniActivationKey, desired UyuniActivationKey) error {
+	add, remove := diffValues(current.Entitlements, desired.Entitlements)
+	if len(add) > 0 {
+		if err := c.post(ctx, client, "activationkey/addEntitlements", map[string]any{"key": current.Key, "entitlements": add}, nil); err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		if err := c.post(ctx, client, "activationkey/removeEntitlements", map[string]any{"key": current.Key, "entitlements": remove}, nil); err != nil {
+			return err
+		}
+	}
+
+	packages := func(names []string) []map[string]string {
+		result := []map[string]string{}
+		for _, name := range names {
+			result = append(result, map[string]string{"name": name})
+		}
+		return result
+	}
+	add, remove = diffValues(current.PackageNames, desired.PackageNames)
+	if len(add) > 0 {
+		if err := c.post(ctx, client, "activationkey/addPackages", map[string]any{"key": current.Key, "packages": packages(add)}, nil); err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		if err := c.post(ctx, client, "activationkey/removePackages", map[string]any{"key": current.Key, "packages": packages(remove)}, nil); err != nil {
+			return err
+		}
+	}
+
+	addGroups, removeGroups := diffValues(current.ServerGroupIDs, desired.ServerGroupIDs)
+	if len(addGroups) > 0 {
+		if err := c.post(ctx, client, "activationkey/addServerGroups", map[string]any{"key": current.Key, "serverGroupIds": addGroups}, nil); err != nil {
+			return err
+		}
+	}
+	if len(removeGroups) > 0 {
+		if err := c.post(ctx, client, "activationkey/removeServerGroups", map[string]any{"key": current.Key, "serverGroupIds": removeGroups}, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteActivationKey logs in and deletes the activation key.
+func (c *UyuniClient) DeleteActivationKey(ctx context.Context, key string) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.post(ctx, client, "activationkey/delete", map[string]any{"key": key}, nil)
+}
+
+// diffValues returns the values of desired missing from current and the values of current
+// missing from desired.
+func diffValues[T comparable](current []T, desired []T) ([]T, []T) {
+	var add, remove []T
+	for _, value := range desired {
+		if !slices.Contains(current, value) {
+			add = append(add, value)
+		}
+	}
+	for _, value := range current {
+		if !slices.Contains(desired, value) {
+			remove = append(remove, value)
+		}
+	}
+	return add, remove
+}
+
+// nonNil returns values, or an empty slice so it is encoded as [] rather than null.
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
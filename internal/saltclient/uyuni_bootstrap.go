@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"time"
+)
+
+// UyuniBootstrapRequest are the parameters of system.bootstrap. Exactly one of SSHPassword and
+// SSHPrivateKey is set.
+type UyuniBootstrapRequest struct {
+	Host                    string
+	SSHPort                 int
+	SSHUser                 string
+	SSHPassword             string
+	SSHPrivateKey           string
+	SSHPrivateKeyPassphrase string
+	ActivationKey           string
+	SaltSSH                 bool
+}
+
+// BootstrapSystem logs in and has Uyuni install and register the Salt Minion on the host over
+// SSH. The call returns once the bootstrap finished, which may take up to timeout instead of
+// the usual HTTP timeout.
+func (c *UyuniClient) BootstrapSystem(ctx context.Context, bootstrap UyuniBootstrapRequest, timeout time.Duration) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+	client.Timeout = timeout
+
+	params := map[string]any{
+		"host":          bootstrap.Host,
+		"sshPort":       bootstrap.SSHPort,
+		"sshUser":       bootstrap.SSHUser,
+		"activationKey": bootstrap.ActivationKey,
+		"saltSSH":       bootstrap.SaltSSH,
+	}
+	if bootstrap.SSHPrivateKey != "" {
+		params["sshPrivKey"] = bootstrap.SSHPrivateKey
+		params["sshPrivKeyPass"] = bootstrap.SSHPrivateKeyPassphrase
+		return c.post(ctx, client, "system/bootstrapWithPrivateSshKey", params, nil)
+	}
+	params["sshPassword"] = bootstrap.SSHPassword
+	return c.post(ctx, client, "system/bootstrap", params, nil)
+}
+
+// DeleteSystem logs in and deletes the system profile from Uyuni, cleaning up the Salt Minion
+// as far as it is reachable.
+func (c *UyuniClient) DeleteSystem(ctx context.Context, systemName string) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return err
+	}
+
+	return c.post(ctx, client, "system/deleteSystem", map[string]any{"sid": systemID, "cleanupType": "FORCE_DELETE"}, nil)
+}
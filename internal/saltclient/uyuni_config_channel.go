@@ -0,0 +1,129 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// uyuniSystemEvent is an entry of the event history of a system, its ID is the one of the action.
+type uyuniSystemEvent struct {
+	ID int `json:"id"`
+}
+
+// GetConfigChannels logs in and returns the labels of the configuration channels assigned to
+// the system, highest priority first.
+func (c *UyuniClient) GetConfigChannels(ctx context.Context, systemName string) ([]string, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []struct {
+		Label string `json:"label"`
+	}
+	if err := c.get(ctx, client, fmt.Sprintf("system/config/listChannels?sid=%d", systemID), &channels); err != nil {
+		return nil, err
+	}
+
+	var labels []string
+	for _, channel := range channels {
+		labels = append(labels, channel.Label)
+	}
+	return labels, nil
+}
+
+// SetConfigChannelAssigned logs in and assigns the configuration channel to the system with
+// the lowest priority or, unless assigned, removes it.
+func (c *UyuniClient) SetConfigChannelAssigned(ctx context.Context, systemName string, label string, assigned bool) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return err
+	}
+
+	if assigned {
+		return c.post(ctx, client, "system/config/addChannels", map[string]any{"sids": []int{systemID}, "configChannelLabels": []string{label}, "addToTop": false}, nil)
+	}
+	return c.post(ctx, client, "system/config/removeChannels", map[string]any{"sids": []int{systemID}, "configChannelLabels": []string{label}}, nil)
+}
+
+// DeployConfigChannels logs in, schedules the deployment of all configuration files of the
+// system and waits until it finished or ctx is done.
+func (c *UyuniClient) DeployConfigChannels(ctx context.Context, systemName string) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return err
+	}
+
+	scheduled := time.Now().UTC()
+	err = c.post(ctx, client, "system/config/deployAll", map[string]any{"sids": []int{systemID}, "date": scheduled.Format(time.RFC3339)}, nil)
+	if err != nil {
+		return err
+	}
+
+	// deployAll does not return the action, find it among the events of the system; the minute
+	// of slack covers a clock skew between Uyuni and us
+	var events []uyuniSystemEvent
+	method := fmt.Sprintf("system/listSystemEvents?sid=%d&actionType=%s&earliestDate=%s", systemID, url.QueryEscape("configfiles.deploy"), url.QueryEscape(scheduled.Add(-time.Minute).Format(time.RFC3339)))
+	if err := c.get(ctx, client, method, &events); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("the scheduled deployment was not found among the events of system %s", systemName)
+	}
+	actionID := slices.MaxFunc(events, func(a, b uyuniSystemEvent) int { return a.ID - b.ID }).ID
+
+	return c.waitForAction(ctx, client, systemID, actionID, "deployment")
+}
+
+// waitForAction polls the outcome of the scheduled action on the system until it completed,
+// failed or ctx is done. What names the action in the errors.
+func (c *UyuniClient) waitForAction(ctx context.Context, client *http.Client, systemID int, actionID int, what string) error {
+	for {
+		for _, outcome := range []string{"listCompletedSystems", "listFailedSystems"} {
+			var systems []struct {
+				ServerID int    `json:"server_id"`
+				Message  string `json:"message"`
+			}
+			if err := c.get(ctx, client, fmt.Sprintf("schedule/%s?actionId=%d", outcome, actionID), &systems); err != nil {
+				return err
+			}
+			for _, system := range systems {
+				if system.ServerID != systemID {
+					continue
+				}
+				if outcome == "listFailedSystems" {
+					return fmt.Errorf("%s action %d failed: %s", what, actionID, system.Message)
+				}
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s action %d did not finish: %w", what, actionID, ctx.Err())
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// uyuniCustomInfoKey is a key of the custom system information defined in Uyuni.
+type uyuniCustomInfoKey struct {
+	Label string `json:"label"`
+}
+
+// GetCustomValues logs in and returns the custom system information of the system.
+func (c *UyuniClient) GetCustomValues(ctx context.Context, systemName string) (map[string]string, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	if err := c.get(ctx, client, fmt.Sprintf("system/getCustomValues?sid=%d", systemID), &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// SetCustomValues logs in and sets the custom system information of the system. Keys Uyuni
+// does not know yet are created first, values of other keys are left alone.
+func (c *UyuniClient) SetCustomValues(ctx context.Context, systemName string, values map[string]string) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return err
+	}
+
+	var keys []uyuniCustomInfoKey
+	if err := c.get(ctx, client, "system/custominfo/listAllKeys", &keys); err != nil {
+		return err
+	}
+
+	for label := range values {
+		if slices.ContainsFunc(keys, func(key uyuniCustomInfoKey) bool { return key.Label == label }) {
+			continue
+		}
+		if err := c.post(ctx, client, "system/custominfo/createKey", map[string]any{"keyLabel": label, "keyDescription": "Managed by Terraform"}, nil); err != nil {
+			return fmt.Errorf("cannot create the custom info key %s: %w", label, err)
+		}
+	}
+
+	return c.post(ctx, client, "system/setCustomValues", map[string]any{"sid": systemID, "values": values}, nil)
+}
+
+// DeleteCustomValues logs in and removes the values of keys from the system. The keys
+// themselves stay defined in Uyuni.
+func (c *UyuniClient) DeleteCustomValues(ctx context.Context, systemName string, keys []string) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return err
+	}
+
+	return c.post(ctx, client, "system/deleteCustomValues", map[string]any{"sid": systemID, "keys": keys}, nil)
+}
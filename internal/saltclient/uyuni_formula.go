@@ -0,0 +1,147 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// UyuniFormulaTarget is the system or, when GroupName is set, the system group formulas are
+// assigned to.
+type UyuniFormulaTarget struct {
+	SystemName string
+	GroupName  string
+}
+
+func (t UyuniFormulaTarget) String() string {
+	if t.GroupName != "" {
+		return fmt.Sprintf("system group %s", t.GroupName)
+	}
+	return fmt.Sprintf("system %s", t.SystemName)
+}
+
+// uyuniFormulaTargetID is the resolved target, either a system or a system group ID.
+type uyuniFormulaTargetID struct {
+	id    int
+	group bool
+}
+
+func (c *UyuniClient) resolveFormulaTarget(ctx context.Context, client *http.Client, target UyuniFormulaTarget) (uyuniFormulaTargetID, error) {
+	if target.GroupName == "" {
+		id, err := c.GetSystemID(ctx, client, target.SystemName)
+		return uyuniFormulaTargetID{id: id}, err
+	}
+
+	var group struct {
+		ID int `json:"id"`
+	}
+	if err := c.get(ctx, client, fmt.Sprintf("systemgroup/getDetails?systemGroupName=%s", url.QueryEscape(target.GroupName)), &group); err != nil {
+		return uyuniFormulaTargetID{}, err
+	}
+	return uyuniFormulaTargetID{id: group.ID, group: true}, nil
+}
+
+func (c *UyuniClient) formulas(ctx context.Context, client *http.Client, target uyuniFormulaTargetID) ([]string, error) {
+	var formulas []string
+	method := fmt.Sprintf("formula/getFormulasByServerId?sid=%d", target.id)
+	if target.group {
+		method = fmt.Sprintf("formula/getFormulasByGroupId?systemGroupId=%d", target.id)
+	}
+	if err := c.get(ctx, client, method, &formulas); err != nil {
+		return nil, err
+	}
+	return formulas, nil
+}
+
+// GetFormulas logs in and returns the formulas assigned to the target.
+func (c *UyuniClient) GetFormulas(ctx context.Context, target UyuniFormulaTarget) ([]string, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := c.resolveFormulaTarget(ctx, client, target)
+	if err != nil {
+		return nil, err
+	}
+	return c.formulas(ctx, client, id)
+}
+
+// SetFormulaEnabled logs in and assigns the formula to the target or, unless enabled, removes
+// it. The other formulas of the target are kept.
+func (c *UyuniClient) SetFormulaEnabled(ctx context.Context, target UyuniFormulaTarget, formula string, enabled bool) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := c.resolveFormulaTarget(ctx, client, target)
+	if err != nil {
+		return err
+	}
+
+	formulas, err := c.formulas(ctx, client, id)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(formulas, formula) == enabled {
+		return nil
+	}
+	if enabled {
+		formulas = append(formulas, formula)
+	} else {
+		formulas = slices.DeleteFunc(formulas, func(f string) bool { return f == formula })
+	}
+
+	if id.group {
+		return c.post(ctx, client, "formula/setFormulasOfGroup", map[string]any{"systemGroupId": id.id, "formulas": nonNil(formulas)}, nil)
+	}
+	return c.post(ctx, client, "formula/setFormulasOfServer", map[string]any{"sid": id.id, "formulas": nonNil(formulas)}, nil)
+}
+
+// GetFormulaData logs in and returns the form data of the formula on the target as JSON.
+func (c *UyuniClient) GetFormulaData(ctx context.Context, target UyuniFormulaTarget, formula string) (json.RawMessage, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := c.resolveFormulaTarget(ctx, client, target)
+	if err != nil {
+		return nil, err
+	}
+
+	var content json.RawMessage
+	method := fmt.Sprintf("formula/getSystemFormulaData?systemId=%d&formulaName=%s", id.id, url.QueryEscape(formula))
+	if id.group {
+		method = fmt.Sprintf("formula/getGroupFormulaData?systemGroupId=%d&formulaName=%s", id.id, url.QueryEscape(formula))
+	}
+	if err := c.get(ctx, client, method, &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// SetFormulaData logs in and replaces the form data of the formula on the target.
+func (c *UyuniClient) SetFormulaData(ctx context.Context, target UyuniFormulaTarget, formula string, content json.RawMessage) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := c.resolveFormulaTarget(ctx, client, target)
+	if err != nil {
+		return err
+	}
+
+	if id.group {
+		return c.post(ctx, client, "formula/setGroupFormulaData", map[string]any{"systemGroupId": id.id, "formulaName": formula, "content": content}, nil)
+	}
+	return c.post(ctx, client, "formula/setSystemFormulaData", map[string]any{"systemId": id.id, "formulaName": formula, "content": content}, nil)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// UyuniBaseEntitlements are the entitlements Uyuni gives a system on its registration. They come
+// with the contact method and cannot be managed as add-ons.
+var UyuniBaseEntitlements = []string{"enterprise_entitled", "salt_entitled", "foreign_entitled", "bootstrap_entitled"}
+
+// GetAddOnEntitlements logs in and returns the add-on entitlements of the system, sorted.
+func (c *UyuniClient) GetAddOnEntitlements(ctx context.Context, systemName string) ([]string, error) {
+	client, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entitlements []string
+	if err := c.get(ctx, client, fmt.Sprintf("system/getEntitlements?sid=%d", systemID), &entitlements); err != nil {
+		return nil, err
+	}
+
+	addOns := []string{}
+	for _, entitlement := range entitlements {
+		if !slices.Contains(UyuniBaseEntitlements, entitlement) {
+			addOns = append(addOns, entitlement)
+		}
+	}
+	slices.Sort(addOns)
+	return addOns, nil
+}
+
+// ChangeEntitlements logs in, adds the entitlements in add and removes the ones in remove from
+// the system. Either may be empty.
+func (c *UyuniClient) ChangeEntitlements(ctx context.Context, systemName string, add []string, remove []string) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return err
+	}
+
+	if len(add) > 0 {
+		if err := c.post(ctx, client, "system/addEntitlements", map[string]any{"sid": systemID, "entitlements": add}, nil); err != nil {
+			return fmt.Errorf("cannot add the entitlements %v: %w", add, err)
+		}
+	}
+	if len(remove) > 0 {
+		if err := c.post(ctx, client, "system/removeEntitlements", map[string]any{"sid": systemID, "entitlements": remove}, nil); err != nil {
+			return fmt.Errorf("cannot remove the entitlements %v: %w", remove, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"time"
+)
+
+// RebootSystem logs in, schedules a reboot of the system and waits until Uyuni saw it come
+// back, which completes the action, or ctx is done.
+func (c *UyuniClient) RebootSystem(ctx context.Context, systemName string) error {
+	client, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	systemID, err := c.GetSystemID(ctx, client, systemName)
+	if err != nil {
+		return err
+	}
+
+	var actionID int
+	err = c.post(ctx, client, "system/scheduleReboot", map[string]any{"sid": systemID, "earliestOccurrence": time.Now().UTC().Format(time.RFC3339)}, &actionID)
+	if err != nil {
+		return err
+	}
+
+	return c.waitForAction(ctx, client, systemID, actionID, "reboot")
+}
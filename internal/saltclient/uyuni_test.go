@@ -0,0 +1,207 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package saltclient
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeUyuni serves the Uyuni API methods in results, keyed by the request path, after a login.
+// A status of the same key fails the method with that HTTP status instead.
+type fakeUyuni struct {
+	mu       sync.Mutex
+	results  map[string]any
+	status   map[string]int
+	requests map[string]int
+}
+
+func (f *fakeUyuni) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[r.URL.Path]++
+
+	if r.URL.Path == "/auth/login" {
+		var login map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&login); err != nil || login["password"] != "secret" {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "pxt-session-cookie", Value: "session", Path: "/"})
+		return
+	}
+	if _, err := r.Cookie("pxt-session-cookie"); err != nil {
+		http.Error(w, "not logged in", http.StatusUnauthorized)
+		return
+	}
+	if status, ok := f.status[r.URL.Path]; ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	result, ok := f.results[r.URL.Path]
+	if !ok {
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": false, "message": "No such method"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": result})
+}
+
+func newFakeUyuni(t *testing.T, results map[string]any, status map[string]int) (*fakeUyuni, *UyuniClient) {
+	fake := &fakeUyuni{results: results, status: status, requests: map[string]int{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, &UyuniClient{BaseURL: server.URL, Username: "admin", Password: "secret", RetryAttempts: 2, HTTPTimeout: 10 * time.Second}
+}
+
+func TestUyuniClientCall(t *testing.T) {
+	_, client := newFakeUyuni(t, map[string]any{
+		"/saltkey/acceptedList": []string{"web1", "web2"},
+		"/system/getId":         []map[string]any{{"id": 1000010001, "name": "web1"}},
+	}, nil)
+	var called []string
+	client.OnCall = func(method string, elapsed time.Duration) {
+		called = append(called, method)
+	}
+
+	accepted, err := client.CheckServerAccepted(context.Background(), "web2")
+	if err != nil || !accepted {
+		t.Errorf("CheckServerAccepted(web2) = %t, %v, want true", accepted, err)
+	}
+	id, err := client.SystemID(context.Background(), "web1")
+	if err != nil || id != 1000010001 {
+		t.Errorf("SystemID(web1) = %d, %v, want 1000010001", id, err)
+	}
+	// the query of a method is left out of the measurements
+	if want := []string{"saltkey/acceptedList", "system/getId"}; len(called) != len(want) || called[0] != want[0] || called[1] != want[1] {
+		t.Errorf("OnCall got %q, want %q", called, want)
+	}
+}
+
+func TestUyuniClientErrors(t *testing.T) {
+	_, client := newFakeUyuni(t, map[string]any{
+		"/system/getId": []map[string]any{},
+	}, nil)
+
+	if _, err := client.SystemID(context.Background(), "web1"); !errors.Is(err, ErrUyuniSystemNotRegistered) {
+		t.Errorf("SystemID of an unknown system = %v, want ErrUyuniSystemNotRegistered", err)
+	}
+	if _, err := client.ListPendingKeys(context.Background()); !IsUyuniNotFound(err) {
+		t.Errorf("ListPendingKeys of a missing method = %v, want a not found error", err)
+	}
+
+	client.Password = "wrong"
+	if err := client.CheckLogin(context.Background()); err == nil {
+		t.Error("CheckLogin with a wrong password succeeded")
+	}
+}
+
+func TestUyuniClientRetry(t *testing.T) {
+	fake, client := newFakeUyuni(t, map[string]any{
+		"/system/getId": []map[string]any{{"id": 1000010001, "name": "web1"}},
+	}, map[string]int{
+		"/saltkey/pendingList": http.StatusBadGateway,
+		"/system/deleteSystem": http.StatusBadGateway,
+	})
+
+	if _, err := client.ListPendingKeys(context.Background()); err == nil {
+		t.Error("ListPendingKeys failing with 502 succeeded")
+	}
+	if err := client.DeleteSystem(context.Background(), "web1"); err == nil {
+		t.Error("DeleteSystem failing with 502 succeeded")
+	}
+
+	// a read is sent again after a gateway error, a change only when Uyuni never got it
+	want := map[string]int{"/saltkey/pendingList": 2, "/system/deleteSystem": 1}
+	for path, count := range want {
+		if fake.requests[path] != count {
+			t.Errorf("%s was requested %d times, want %d", path, fake.requests[path], count)
+		}
+	}
+}
+
+func TestUyuniClientTLS(t *testing.T) {
+	fake := &fakeUyuni{requests: map[string]int{}}
+	server := httptest.NewTLSServer(fake)
+	t.Cleanup(server.Close)
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	// the server does not ask for a client certificate, any will do
+	certificate := server.TLS.Certificates[0]
+
+	tests := map[string]struct {
+		client    UyuniClient
+		wantError bool
+	}{
+		"unverified without a client certificate": {client: UyuniClient{}},
+		"client certificate without a CA":         {client: UyuniClient{ClientCertificate: &certificate}, wantError: true},
+		"client certificate with the CA":          {client: UyuniClient{ClientCertificate: &certificate, RootCAs: roots}},
+		"CA of another server":                    {client: UyuniClient{RootCAs: x509.NewCertPool()}, wantError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := test.client
+			client.BaseURL, client.Username, client.Password, client.RetryAttempts, client.HTTPTimeout = server.URL, "admin", "secret", 1, 10*time.Second
+
+			err := client.CheckLogin(context.Background())
+			if test.wantError && err == nil {
+				t.Error("CheckLogin succeeded, want a certificate error")
+			}
+			if !test.wantError && err != nil {
+				t.Errorf("CheckLogin failed: %s", err)
+			}
+		})
+	}
+}
+
+func TestUyuniClientCancel(t *testing.T) {
+	_, client := newFakeUyuni(t, map[string]any{
+		"/saltkey/pendingList": []string{"web1"},
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ListPendingKeys(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListPendingKeys with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestUyuniClientTransportReuse(t *testing.T) {
+	fake := &fakeUyuni{requests: map[string]int{}}
+	server := httptest.NewUnstartedServer(fake)
+	var connections atomic.Int32
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	client := &UyuniClient{BaseURL: server.URL, Username: "admin", Password: "secret", RetryAttempts: 1, HTTPTimeout: 10 * time.Second}
+	// a copy for another user, as for the uyuni block of a resource
+	other := *client
+	other.Username = "other"
+
+	for _, c := range []*UyuniClient{client, client, &other} {
+		if err := c.CheckLogin(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// every login has its own session, but they all go over the idle connection of the first
+	if got := connections.Load(); got != 1 {
+		t.Errorf("%d connections were opened for 3 logins, want 1", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"flag"
 	"log"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"terraform-provider-salty/internal/provider"
@@ -39,6 +40,11 @@ func main() {
 
 	err := providerserver.Serve(context.Background(), provider.New(version), opts)
 
+	// Terraform waits a few seconds for the provider to exit after stopping it
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	provider.FlushMetrics(ctx)
+	cancel()
+
 	if err != nil {
 		log.Fatal(err.Error())
 	}